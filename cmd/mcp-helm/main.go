@@ -6,16 +6,22 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/auth"
 	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/config"
 	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/handler"
 	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/metrics"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/ratelimit"
 	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/server"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/tracing"
 )
 
 // Build information, set by goreleaser.
@@ -51,17 +57,118 @@ func run() error {
 	}
 	defer func() { _ = logger.Sync() }()
 
+	// Load chart policy
+	var policy *helm.Policy
+	if cfg.PolicyFile != "" {
+		policy, err = helm.LoadPolicy(cfg.PolicyFile)
+		if err != nil {
+			return fmt.Errorf("loading policy: %w", err)
+		}
+	}
+
+	allowedCIDRs, err := helm.ParseCIDRs(cfg.AllowedCIDRs)
+	if err != nil {
+		return fmt.Errorf("parsing allowed CIDRs: %w", err)
+	}
+	deniedCIDRs, err := helm.ParseCIDRs(cfg.DeniedCIDRs)
+	if err != nil {
+		return fmt.Errorf("parsing denied CIDRs: %w", err)
+	}
+
+	provenanceMode, err := helm.ParseVerificationMode(cfg.ProvenanceMode)
+	if err != nil {
+		return fmt.Errorf("parsing provenance mode: %w", err)
+	}
+	cosignMode, err := helm.ParseVerificationMode(cfg.CosignMode)
+	if err != nil {
+		return fmt.Errorf("parsing cosign mode: %w", err)
+	}
+
+	// Export traces if a collector is configured
+	if cfg.OTLPEndpoint != "" {
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+			Endpoint:       cfg.OTLPEndpoint,
+			SampleRatio:    cfg.TraceSampleRatio,
+			ServiceVersion: version,
+		})
+		if err != nil {
+			return fmt.Errorf("setting up tracing: %w", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				logger.Warn("failed to flush traces", zap.Error(err))
+			}
+		}()
+	}
+
+	// Load cache warm list
+	var warmList *helm.WarmList
+	if cfg.WarmFile != "" {
+		warmList, err = helm.LoadWarmList(cfg.WarmFile)
+		if err != nil {
+			return fmt.Errorf("loading warm list: %w", err)
+		}
+	}
+
+	// Metrics are only served over HTTP. Fetches are labeled by host only
+	// for the repositories the server is configured with.
+	var m *metrics.Metrics
+	if cfg.Transport == "http" && cfg.Metrics {
+		hosts := append(slices.Clone(cfg.AllowedHosts), cfg.ReadyRepositories...)
+		if warmList != nil {
+			for _, repo := range warmList.Repositories {
+				hosts = append(hosts, repo.URL)
+			}
+		}
+		m = metrics.New(metrics.WithHosts(hosts))
+	}
+
 	// Create Helm client
-	helmClient := helm.NewClient(
+	clientOpts := []helm.Option{
 		helm.WithTimeout(cfg.HelmTimeout),
 		helm.WithIndexTTL(cfg.IndexTTL),
+		helm.WithIndexMaxStale(cfg.IndexMaxStale),
+		helm.WithStaleWhileRevalidate(cfg.IndexStaleWhileRevalidate),
+		helm.WithFailureTTL(cfg.FailureTTL),
+		helm.WithMaxFetches(cfg.MaxFetches),
+		helm.WithMaxHostFetches(cfg.MaxHostFetches),
+		helm.WithFetchQueueTimeout(cfg.FetchQueueTimeout),
 		helm.WithChartCacheSize(cfg.CacheSize),
+		helm.WithChartCacheBytes(cfg.ChartCacheBytes),
+		helm.WithIndexCacheBytes(cfg.IndexCacheBytes),
+		helm.WithArchiveLimits(helm.ArchiveLimits{
+			MaxTotalBytes: cfg.MaxArchiveBytes,
+			MaxFiles:      cfg.MaxArchiveFiles,
+			MaxFileBytes:  cfg.MaxArchiveFileBytes,
+			MaxPathDepth:  cfg.MaxArchiveDepth,
+		}),
 		helm.WithMaxOutputBytes(cfg.MaxOutputBytes),
+		helm.WithCacheDir(cfg.CacheDir),
+		helm.WithDiskCacheSize(cfg.DiskCacheSize),
 		helm.WithAllowPrivateIPs(cfg.AllowPrivateIPs),
 		helm.WithAllowedHosts(cfg.AllowedHosts),
 		helm.WithDeniedHosts(cfg.DeniedHosts),
+		helm.WithAllowedCIDRs(allowedCIDRs),
+		helm.WithDeniedCIDRs(deniedCIDRs),
+		helm.WithRestrictChartHost(cfg.RestrictChartHost),
+		helm.WithPlainHTTP(cfg.OCIPlainHTTP),
+		helm.WithProvenanceMode(provenanceMode),
+		helm.WithProvenanceKeyring(cfg.ProvenanceKeyring),
+		helm.WithProvenanceHosts(cfg.ProvenanceHosts),
+		helm.WithProvenanceSkipHosts(cfg.ProvenanceSkipHosts),
+		helm.WithCosignMode(cosignMode),
+		helm.WithCosignKeys(cfg.CosignKeys),
+		helm.WithCosignHosts(cfg.CosignHosts),
+		helm.WithCosignSkipHosts(cfg.CosignSkipHosts),
+		helm.WithPolicy(policy),
 		helm.WithLogger(logger),
-	)
+	}
+	if m != nil {
+		clientOpts = append(clientOpts, helm.WithFetchObserver(m))
+	}
+	helmClient := helm.NewClient(clientOpts...)
 
 	// Create MCP server with capabilities
 	mcpServer := mcp.NewServer(
@@ -82,16 +189,77 @@ func run() error {
 		},
 	)
 
+	// Middleware added later runs first, so rate limited calls are still
+	// traced and counted
+	if cfg.RateLimitToolCalls > 0 || cfg.RateLimitDownloads > 0 {
+		limits := ratelimit.NewToolLimits(
+			ratelimit.Budget{PerMinute: cfg.RateLimitToolCalls, Burst: cfg.RateLimitToolCallsBurst},
+			ratelimit.Budget{PerMinute: cfg.RateLimitDownloads, Burst: cfg.RateLimitDownloadsBurst},
+			handler.DownloadTools,
+		)
+		mcpServer.AddReceivingMiddleware(limits.Middleware())
+	}
+
+	if cfg.OTLPEndpoint != "" {
+		mcpServer.AddReceivingMiddleware(tracing.Middleware())
+	}
+
 	// Register handlers
-	h := handler.New(helmClient, logger)
+	h := handler.New(helmClient, logger, handler.WithValuesCacheSize(cfg.CacheSize))
 	h.Register(mcpServer)
-
-	// Create and run server
-	srv := server.New(cfg, logger, mcpServer)
+	if cfg.AdminTools {
+		h.RegisterAdmin(mcpServer)
+	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	// Warm caches in the background
+	var serverOpts []server.Option
+	if warmList != nil {
+		warmer := helm.NewWarmer(helmClient, warmList, cfg.WarmVersions, cfg.WarmConcurrency, cfg.WarmInterval)
+		go warmer.Run(ctx)
+		serverOpts = append(serverOpts, server.WithWarmup(warmer))
+	}
+
+	serverOpts = append(serverOpts, server.WithReadiness(helmClient))
+	if cfg.AdminToken != "" {
+		serverOpts = append(serverOpts, server.WithCacheAdmin(helmClient))
+	}
+	if m != nil {
+		mcpServer.AddReceivingMiddleware(m.Middleware())
+		m.RegisterCaches(helmClient)
+		m.RegisterFetchQueue(helmClient)
+		m.RegisterSessions(mcpServer)
+		serverOpts = append(serverOpts, server.WithMetrics(m.Handler()))
+	}
+
+	if cfg.RateLimitRequests > 0 {
+		serverOpts = append(serverOpts, server.WithRateLimit(ratelimit.New(ratelimit.Budget{
+			PerMinute: cfg.RateLimitRequests,
+			Burst:     cfg.RateLimitRequestsBurst,
+		})))
+	}
+
+	// Authenticate HTTP clients
+	if cfg.Transport == "http" && (cfg.AuthTokensFile != "" || cfg.OAuthJWKS != "") {
+		authenticator, err := auth.New(ctx, auth.Options{
+			TokensFile: cfg.AuthTokensFile,
+			JWKS:       cfg.OAuthJWKS,
+			Issuer:     cfg.OAuthIssuer,
+			Audience:   cfg.OAuthAudience,
+			Resource:   cfg.OAuthResource,
+			Scopes:     cfg.OAuthScopes,
+		})
+		if err != nil {
+			return fmt.Errorf("setting up authentication: %w", err)
+		}
+		serverOpts = append(serverOpts, server.WithAuth(authenticator))
+	}
+
+	// Create and run server
+	srv := server.New(cfg, logger, mcpServer, serverOpts...)
+
 	return srv.Run(ctx)
 }
 
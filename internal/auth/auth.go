@@ -0,0 +1,236 @@
+// Package auth authenticates clients of the HTTP transport, with static
+// bearer tokens or as an OAuth 2.1 resource server validating JWTs.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	sdkauth "github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+)
+
+// MetadataPath is where the OAuth protected resource metadata is served
+// (RFC 9728).
+const MetadataPath = "/.well-known/oauth-protected-resource"
+
+// staticTokenLifetime is the expiration reported for static tokens, which
+// never expire; the MCP SDK requires every token to have one.
+const staticTokenLifetime = time.Hour
+
+// jwksTimeout bounds a request for the JWKS.
+const jwksTimeout = 10 * time.Second
+
+// signatureAlgorithms are the JWT signature algorithms accepted. Symmetric
+// algorithms are excluded, since the keys come from a public key set.
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// Options configures authentication. At least one of TokensFile and JWKS
+// must be set.
+type Options struct {
+	// TokensFile is a file of static bearer tokens, one per line.
+	TokensFile string
+
+	// JWKS is the path or http(s) URL of the key set JWTs are verified with.
+	JWKS string
+	// Issuer must match the "iss" claim of JWTs; it is also advertised as
+	// the authorization server.
+	Issuer string
+	// Audience must be in the "aud" claim of JWTs. Defaults to Resource.
+	Audience string
+	// Resource is the canonical URL of the MCP endpoint, e.g.
+	// https://mcp.example.com/mcp.
+	Resource string
+	// Scopes must all be granted to a JWT, in its "scope" or "scp" claim.
+	Scopes []string
+}
+
+// Authenticator verifies the bearer tokens of HTTP requests.
+type Authenticator struct {
+	tokens   *staticTokens // nil without a tokens file
+	keys     *keySet       // nil without a JWKS
+	issuer   string
+	audience string
+	resource string
+	scopes   []string
+}
+
+// New creates an Authenticator, loading the tokens file and the JWKS. Keys
+// fetched from a URL are refreshed with ctx, so it should live as long as the
+// server.
+func New(ctx context.Context, opts Options) (*Authenticator, error) {
+	if opts.TokensFile == "" && opts.JWKS == "" {
+		return nil, errors.New("no tokens file or JWKS configured")
+	}
+	a := &Authenticator{
+		issuer:   opts.Issuer,
+		audience: opts.Audience,
+		resource: opts.Resource,
+		scopes:   opts.Scopes,
+	}
+	if a.audience == "" {
+		a.audience = opts.Resource
+	}
+
+	if opts.TokensFile != "" {
+		tokens, err := loadTokens(opts.TokensFile)
+		if err != nil {
+			return nil, err
+		}
+		a.tokens = tokens
+	}
+	if opts.JWKS != "" {
+		if opts.Issuer == "" || opts.Resource == "" {
+			return nil, errors.New("JWT validation requires an issuer and a resource URL")
+		}
+		keys, err := newKeySet(ctx, opts.JWKS, &http.Client{Timeout: jwksTimeout})
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+	return a, nil
+}
+
+// OAuth reports whether JWTs are accepted, so that the protected resource
+// metadata should be served.
+func (a *Authenticator) OAuth() bool {
+	return a.keys != nil
+}
+
+// Middleware rejects requests without a valid bearer token with 401 and
+// passes the token's details to MCP tool handlers. With OAuth, the
+// WWW-Authenticate header of the rejection points clients at the protected
+// resource metadata.
+func (a *Authenticator) Middleware() func(http.Handler) http.Handler {
+	opts := &sdkauth.RequireBearerTokenOptions{}
+	if a.OAuth() {
+		opts.ResourceMetadataURL = a.metadataURL()
+	}
+	return sdkauth.RequireBearerToken(a.verify, opts)
+}
+
+// MetadataHandler serves the OAuth protected resource metadata.
+func (a *Authenticator) MetadataHandler() http.Handler {
+	return sdkauth.ProtectedResourceMetadataHandler(&oauthex.ProtectedResourceMetadata{
+		Resource:               a.resource,
+		AuthorizationServers:   []string{a.issuer},
+		ScopesSupported:        a.scopes,
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           "mcp-helm",
+	})
+}
+
+// MetadataPaths returns the paths the protected resource metadata is served
+// on: the well-known path, and the well-known path with the resource's path
+// appended, where RFC 9728 clients look first.
+func (a *Authenticator) MetadataPaths() []string {
+	paths := []string{MetadataPath}
+	if u, err := url.Parse(a.resource); err == nil && strings.Trim(u.Path, "/") != "" {
+		paths = append(paths, MetadataPath+"/"+strings.Trim(u.Path, "/"))
+	}
+	return paths
+}
+
+// metadataURL returns the absolute URL of the protected resource metadata
+// for the resource.
+func (a *Authenticator) metadataURL() string {
+	u, err := url.Parse(a.resource)
+	if err != nil {
+		return ""
+	}
+	paths := a.MetadataPaths()
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: paths[len(paths)-1]}).String()
+}
+
+// verify checks a bearer token: first against the static tokens, then as
+// a JWT.
+func (a *Authenticator) verify(ctx context.Context, token string, _ *http.Request) (*sdkauth.TokenInfo, error) {
+	if a.tokens != nil {
+		if client, ok := a.tokens.lookup(token); ok {
+			return &sdkauth.TokenInfo{
+				UserID:     client,
+				Expiration: time.Now().Add(staticTokenLifetime),
+			}, nil
+		}
+	}
+	if a.keys != nil && strings.Count(token, ".") == 2 {
+		return a.verifyJWT(ctx, token)
+	}
+	return nil, fmt.Errorf("%w: unknown token", sdkauth.ErrInvalidToken)
+}
+
+// jwtClaims are the claims read from a JWT.
+type jwtClaims struct {
+	jwt.Claims
+	Scope string   `json:"scope,omitempty"` // Space-separated (RFC 8693)
+	Scp   []string `json:"scp,omitempty"`   // List, as issued by some providers
+}
+
+// verifyJWT verifies a JWT's signature against the key set, and its issuer,
+// audience, lifetime, subject and scopes.
+func (a *Authenticator) verifyJWT(ctx context.Context, token string) (*sdkauth.TokenInfo, error) {
+	parsed, err := jwt.ParseSigned(token, signatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", sdkauth.ErrInvalidToken, err)
+	}
+	if len(parsed.Headers) != 1 {
+		return nil, fmt.Errorf("%w: token must have exactly one signature", sdkauth.ErrInvalidToken)
+	}
+
+	var claims jwtClaims
+	verified := false
+	for _, key := range a.keys.lookup(ctx, parsed.Headers[0].KeyID) {
+		if err := parsed.Claims(key.Key, &claims); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: signature does not match any key", sdkauth.ErrInvalidToken)
+	}
+
+	if claims.Expiry == nil {
+		return nil, fmt.Errorf("%w: token has no expiration", sdkauth.ErrInvalidToken)
+	}
+	// The subject identifies the client for sessions and rate limits
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", sdkauth.ErrInvalidToken)
+	}
+	if err := claims.Validate(jwt.Expected{
+		Issuer:      a.issuer,
+		AnyAudience: jwt.Audience{a.audience},
+		Time:        time.Now(),
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", sdkauth.ErrInvalidToken, err)
+	}
+
+	scopes := claims.Scp
+	if claims.Scope != "" {
+		scopes = strings.Fields(claims.Scope)
+	}
+	for _, s := range a.scopes {
+		if !slices.Contains(scopes, s) {
+			return nil, fmt.Errorf("%w: missing scope %q", sdkauth.ErrInvalidToken, s)
+		}
+	}
+
+	return &sdkauth.TokenInfo{
+		UserID:     claims.Subject,
+		Scopes:     scopes,
+		Expiration: claims.Expiry.Time(),
+	}, nil
+}
@@ -0,0 +1,389 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	sdkauth "github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testResource = "https://mcp.example.com/mcp"
+)
+
+// signingKey is an ECDSA key with its key ID.
+type signingKey struct {
+	kid string
+	key *ecdsa.PrivateKey
+}
+
+func newSigningKey(t *testing.T, kid string) signingKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return signingKey{kid: kid, key: key}
+}
+
+// jwks returns the JSON key set of the public halves of keys.
+func jwks(t *testing.T, keys ...signingKey) []byte {
+	t.Helper()
+	var set jose.JSONWebKeySet
+	for _, k := range keys {
+		set.Keys = append(set.Keys, jose.JSONWebKey{Key: &k.key.PublicKey, KeyID: k.kid, Algorithm: string(jose.ES256), Use: "sig"})
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	return data
+}
+
+// sign returns a JWT with the given claims signed by k.
+func (k signingKey) sign(t *testing.T, claims any) string {
+	t.Helper()
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.ES256, Key: k.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader(jose.HeaderKey("kid"), k.kid),
+	)
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	require.NoError(t, err)
+	return token
+}
+
+// validClaims returns claims that the test authenticator accepts.
+func validClaims() jwtClaims {
+	now := time.Now()
+	return jwtClaims{
+		Claims: jwt.Claims{
+			Subject:  "alice",
+			Issuer:   testIssuer,
+			Audience: jwt.Audience{testResource},
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Scope: "helm:read openid",
+	}
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// serve sends a request with the given bearer token through the middleware
+// and returns the response and the token info the handler saw.
+func serve(t *testing.T, a *Authenticator, token string) (*httptest.ResponseRecorder, *sdkauth.TokenInfo) {
+	t.Helper()
+	var info *sdkauth.TokenInfo
+	h := a.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info = sdkauth.TokenInfoFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr, info
+}
+
+func TestStaticTokens(t *testing.T) {
+	ctx := context.Background()
+	path := writeFile(t, "tokens", []byte("# CI pipelines\nci s3cret-ci\n\nanonymous-token\n"))
+	a, err := New(ctx, Options{TokensFile: path})
+	require.NoError(t, err)
+	assert.False(t, a.OAuth())
+
+	t.Run("named token", func(t *testing.T) {
+		rr, info := serve(t, a, "s3cret-ci")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		require.NotNil(t, info)
+		assert.Equal(t, "ci", info.UserID)
+	})
+
+	t.Run("unnamed token is identified by its line", func(t *testing.T) {
+		rr, info := serve(t, a, "anonymous-token")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		require.NotNil(t, info)
+		assert.Equal(t, "token-4", info.UserID)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		rr, info := serve(t, a, "guess")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Nil(t, info)
+		assert.Empty(t, rr.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		rr, _ := serve(t, a, "")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
+
+func TestLoadTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no tokens", "# nothing here\n\n", "contains no tokens"},
+		{"too many fields", "ci s3cret extra\n", "tokens:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTokens(writeFile(t, "tokens", []byte(tt.content)))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := loadTokens(filepath.Join(t.TempDir(), "missing"))
+		assert.Error(t, err)
+	})
+}
+
+func TestJWT(t *testing.T) {
+	ctx := context.Background()
+	key := newSigningKey(t, "k1")
+	a, err := New(ctx, Options{
+		JWKS:     writeFile(t, "jwks.json", jwks(t, key)),
+		Issuer:   testIssuer,
+		Resource: testResource,
+		Scopes:   []string{"helm:read"},
+	})
+	require.NoError(t, err)
+	assert.True(t, a.OAuth())
+
+	t.Run("valid token", func(t *testing.T) {
+		rr, info := serve(t, a, key.sign(t, validClaims()))
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.NotNil(t, info)
+		assert.Equal(t, "alice", info.UserID)
+		assert.ElementsMatch(t, []string{"helm:read", "openid"}, info.Scopes)
+	})
+
+	t.Run("scopes as a list", func(t *testing.T) {
+		claims := validClaims()
+		claims.Scope, claims.Scp = "", []string{"helm:read"}
+		rr, _ := serve(t, a, key.sign(t, claims))
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
+	rejected := []struct {
+		name   string
+		token  func() string
+		reason string
+	}{
+		{"wrong issuer", func() string {
+			c := validClaims()
+			c.Issuer = "https://evil.example.com"
+			return key.sign(t, c)
+		}, "issuer"},
+		{"wrong audience", func() string {
+			c := validClaims()
+			c.Audience = jwt.Audience{"https://other.example.com"}
+			return key.sign(t, c)
+		}, "audience"},
+		{"expired", func() string {
+			c := validClaims()
+			c.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			return key.sign(t, c)
+		}, "expired"},
+		{"no expiration", func() string {
+			c := validClaims()
+			c.Expiry = nil
+			return key.sign(t, c)
+		}, "no expiration"},
+		{"no subject", func() string {
+			c := validClaims()
+			c.Subject = ""
+			return key.sign(t, c)
+		}, "no subject"},
+		{"missing scope", func() string {
+			c := validClaims()
+			c.Scope = "openid"
+			return key.sign(t, c)
+		}, "helm:read"},
+		{"unknown key", func() string {
+			return newSigningKey(t, "k1").sign(t, validClaims())
+		}, "signature"},
+		{"not a JWT", func() string { return "opaque-token" }, "unknown token"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, info := serve(t, a, tt.token())
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.Nil(t, info)
+			assert.Contains(t, rr.Body.String(), tt.reason)
+			assert.Equal(t, "Bearer resource_metadata=https://mcp.example.com/.well-known/oauth-protected-resource/mcp",
+				rr.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestJWT_SymmetricAlgorithmRejected(t *testing.T) {
+	key := newSigningKey(t, "k1")
+	a, err := New(context.Background(), Options{
+		JWKS:     writeFile(t, "jwks.json", jwks(t, key)),
+		Issuer:   testIssuer,
+		Resource: testResource,
+	})
+	require.NoError(t, err)
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte("0123456789abcdef0123456789abcdef")}, nil)
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(validClaims()).Serialize()
+	require.NoError(t, err)
+
+	rr, _ := serve(t, a, token)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestJWKS_URL(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey := newSigningKey(t, "old"), newSigningKey(t, "new")
+	var served atomic.Pointer[[]byte]
+	initial := jwks(t, oldKey)
+	served.Store(&initial)
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(*served.Load())
+	}))
+	t.Cleanup(srv.Close)
+
+	a, err := New(ctx, Options{JWKS: srv.URL, Issuer: testIssuer, Resource: testResource})
+	require.NoError(t, err)
+
+	rr, _ := serve(t, a, oldKey.sign(t, validClaims()))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// A token signed with a rotated-in key triggers a refetch, but not more
+	// than once per minimum refresh interval
+	rotated := jwks(t, oldKey, newKey)
+	served.Store(&rotated)
+	rr, _ = serve(t, a, newKey.sign(t, validClaims()))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	a.keys.mu.Lock()
+	a.keys.fetchedAt = time.Now().Add(-jwksMinRefreshInterval)
+	a.keys.mu.Unlock()
+	rr, _ = serve(t, a, newKey.sign(t, validClaims()))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestJWKS_RefreshOutsideLock(t *testing.T) {
+	oldKey, newKey := newSigningKey(t, "old"), newSigningKey(t, "new")
+	initial, rotated := jwks(t, oldKey), jwks(t, oldKey, newKey)
+	var fetches atomic.Int32
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if fetches.Add(1) == 1 {
+			_, _ = w.Write(initial)
+			return
+		}
+		<-unblock
+		_, _ = w.Write(rotated)
+	}))
+	t.Cleanup(srv.Close)
+
+	a, err := New(context.Background(), Options{JWKS: srv.URL, Issuer: testIssuer, Resource: testResource})
+	require.NoError(t, err)
+	a.keys.mu.Lock()
+	a.keys.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+	a.keys.mu.Unlock()
+
+	// Keys due for refresh are served while the refresh runs
+	assert.Len(t, a.keys.lookup(context.Background(), "old"), 1)
+	require.Eventually(t, func() bool { return fetches.Load() == 2 }, time.Second, time.Millisecond)
+	assert.Len(t, a.keys.lookup(context.Background(), "old"), 1)
+
+	// Lookups of an unknown key wait for the refresh in progress instead of
+	// starting their own, and give up when their request is cancelled
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Empty(t, a.keys.lookup(cancelled, "new"))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Len(t, a.keys.lookup(context.Background(), "new"), 1)
+		}()
+	}
+	close(unblock)
+	wg.Wait()
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestNew_Errors(t *testing.T) {
+	ctx := context.Background()
+	key := newSigningKey(t, "k1")
+	private, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key.key, KeyID: "k1"}}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{"nothing configured", Options{}, "no tokens file or JWKS"},
+		{"JWKS without issuer", Options{JWKS: writeFile(t, "jwks.json", jwks(t, key)), Resource: testResource}, "requires an issuer"},
+		{"empty JWKS", Options{JWKS: writeFile(t, "jwks.json", []byte(`{"keys":[]}`)), Issuer: testIssuer, Resource: testResource}, "contains no keys"},
+		{"private key in JWKS", Options{JWKS: writeFile(t, "jwks.json", private), Issuer: testIssuer, Resource: testResource}, "private key"},
+		{"plain HTTP JWKS URL", Options{JWKS: "http://idp.example.com/jwks.json", Issuer: testIssuer, Resource: testResource}, "must use https"},
+		{"missing JWKS file", Options{JWKS: filepath.Join(t.TempDir(), "missing.json"), Issuer: testIssuer, Resource: testResource}, "reading JWKS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(ctx, tt.opts)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestMetadataHandler(t *testing.T) {
+	key := newSigningKey(t, "k1")
+	a, err := New(context.Background(), Options{
+		JWKS:     writeFile(t, "jwks.json", jwks(t, key)),
+		Issuer:   testIssuer,
+		Resource: testResource,
+		Scopes:   []string{"helm:read"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{MetadataPath, MetadataPath + "/mcp"}, a.MetadataPaths())
+
+	rr := httptest.NewRecorder()
+	a.MetadataHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, MetadataPath+"/mcp", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, testResource, body["resource"])
+	assert.Equal(t, []any{testIssuer}, body["authorization_servers"])
+	assert.Equal(t, []any{"helm:read"}, body["scopes_supported"])
+}
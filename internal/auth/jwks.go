@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksRefreshInterval is how often keys fetched from a URL are refreshed.
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefreshInterval limits refetches triggered by tokens signed with
+	// an unknown key, so that such tokens cannot flood the JWKS endpoint.
+	jwksMinRefreshInterval = time.Minute
+	// maxJWKSBytes bounds the size of a JWKS document.
+	maxJWKSBytes = 1 << 20
+)
+
+// keySet holds the keys tokens are verified with, loaded from a local file
+// or a URL. Keys from a URL are refreshed periodically, and when a token
+// names a key that is not in the set, so that key rotation needs no restart.
+type keySet struct {
+	source string // File path, https URL, or http URL on the local host
+	client *http.Client
+	ctx    context.Context // Server-owned; bounds refreshes, which outlive the requests that trigger them
+
+	refreshes singleflight.Group // Concurrent refreshes share one fetch
+
+	mu        sync.Mutex
+	keys      jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// newKeySet loads the keys from source. Later refreshes use ctx, which
+// should be cancelled when the server stops.
+func newKeySet(ctx context.Context, source string, client *http.Client) (*keySet, error) {
+	if strings.HasPrefix(source, "http://") && !isLoopbackURL(source) {
+		return nil, fmt.Errorf("JWKS URL %s must use https", source)
+	}
+	s := &keySet{source: source, client: client, ctx: ctx}
+	keys, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.keys, s.fetchedAt = keys, time.Now()
+	return s, nil
+}
+
+// isURL reports whether the keys are fetched over HTTP.
+func (s *keySet) isURL() bool {
+	return strings.HasPrefix(s.source, "https://") || strings.HasPrefix(s.source, "http://")
+}
+
+// isLoopbackURL reports whether rawURL points at the local host, the only
+// place keys may be fetched from over plain HTTP.
+func isLoopbackURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// lookup returns the keys with the given key ID, or all keys if the token
+// does not name one. Keys due for their periodic refresh are refreshed in
+// the background. A key ID that is not in the set waits for a refresh, for
+// as long as ctx allows, unless the keys were fetched too recently.
+func (s *keySet) lookup(ctx context.Context, kid string) []jose.JSONWebKey {
+	s.mu.Lock()
+	keys := s.find(kid)
+	age := time.Since(s.fetchedAt)
+	s.mu.Unlock()
+
+	if !s.isURL() {
+		return keys
+	}
+	switch {
+	case len(keys) == 0 && age >= jwksMinRefreshInterval:
+		select {
+		case <-s.refreshes.DoChan("", s.refresh):
+		case <-ctx.Done():
+			return nil
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.find(kid)
+	case age >= jwksRefreshInterval:
+		s.refreshes.DoChan("", s.refresh)
+	}
+	return keys
+}
+
+// refresh refetches the keys and swaps them in, keeping the previous keys
+// if the endpoint is unavailable. Refreshes are rate limited by the minimum
+// refresh interval either way.
+func (s *keySet) refresh() (any, error) {
+	s.mu.Lock()
+	recent := time.Since(s.fetchedAt) < jwksMinRefreshInterval
+	s.mu.Unlock()
+	if recent {
+		return nil, nil
+	}
+
+	fetched, err := s.load(s.ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.keys = fetched
+	}
+	s.fetchedAt = time.Now()
+	return nil, err
+}
+
+// find returns the keys matching kid. The caller must hold mu.
+func (s *keySet) find(kid string) []jose.JSONWebKey {
+	if kid == "" {
+		return s.keys.Keys
+	}
+	return s.keys.Key(kid)
+}
+
+// load reads and parses the key set from its source.
+func (s *keySet) load(ctx context.Context) (jose.JSONWebKeySet, error) {
+	var data []byte
+	if s.isURL() {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+		if err != nil {
+			return jose.JSONWebKeySet{}, fmt.Errorf("fetching JWKS: %w", err)
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return jose.JSONWebKeySet{}, fmt.Errorf("fetching JWKS: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			return jose.JSONWebKeySet{}, fmt.Errorf("fetching JWKS: unexpected status %s", resp.Status)
+		}
+		if data, err = io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes)); err != nil {
+			return jose.JSONWebKeySet{}, fmt.Errorf("fetching JWKS: %w", err)
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(s.source); err != nil {
+			return jose.JSONWebKeySet{}, fmt.Errorf("reading JWKS: %w", err)
+		}
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return jose.JSONWebKeySet{}, fmt.Errorf("parsing JWKS: %w", err)
+	}
+	if len(keys.Keys) == 0 {
+		return jose.JSONWebKeySet{}, fmt.Errorf("JWKS %s contains no keys", s.source)
+	}
+	for _, k := range keys.Keys {
+		if !k.IsPublic() {
+			return jose.JSONWebKeySet{}, fmt.Errorf("JWKS %s contains a private key", s.source)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// staticToken is a token from the tokens file, stored as its hash so that
+// comparisons take the same time whatever the token's length.
+type staticToken struct {
+	client string
+	hash   [sha256.Size]byte
+}
+
+// staticTokens authenticates clients by a fixed list of bearer tokens.
+type staticTokens struct {
+	tokens []staticToken
+}
+
+// loadTokens reads bearer tokens from a file with one token per line,
+// optionally preceded by a client name and whitespace:
+//
+//	# CI pipelines
+//	ci 3f9c0a...
+//	7b21d4...
+//
+// Blank lines and lines starting with # are ignored. Tokens without a name
+// are identified by their line number.
+func loadTokens(path string) (*staticTokens, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading tokens file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	s := &staticTokens{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		var client, token string
+		switch len(fields) {
+		case 1:
+			client, token = fmt.Sprintf("token-%d", line), fields[0]
+		case 2:
+			client, token = fields[0], fields[1]
+		default:
+			return nil, fmt.Errorf("tokens file %s:%d: want a token, optionally preceded by a client name", path, line)
+		}
+		s.tokens = append(s.tokens, staticToken{client: client, hash: sha256.Sum256([]byte(token))})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading tokens file: %w", err)
+	}
+	if len(s.tokens) == 0 {
+		return nil, fmt.Errorf("tokens file %s contains no tokens", path)
+	}
+	return s, nil
+}
+
+// lookup returns the client a token belongs to. Every token is compared, so
+// the time taken does not reveal which one matched.
+func (s *staticTokens) lookup(token string) (string, bool) {
+	hash := sha256.Sum256([]byte(token))
+	var client string
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare(hash[:], t.hash[:]) == 1 {
+			client = t.client
+		}
+	}
+	return client, client != ""
+}
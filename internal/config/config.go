@@ -4,6 +4,8 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -23,15 +25,112 @@ type Config struct {
 	CacheSize      int
 	IndexTTL       time.Duration
 	MaxOutputBytes int
+	CacheDir       string
+	DiskCacheSize  int64
+
+	// Memory budgets for the chart and index caches; zero means no byte limit.
+	ChartCacheBytes int64
+	IndexCacheBytes int64
+
+	// Limits enforced while decompressing chart archives
+	MaxArchiveBytes     int64
+	MaxArchiveFiles     int
+	MaxArchiveFileBytes int64
+	MaxArchiveDepth     int
+
+	// IndexMaxStale is the oldest an expired index may be and still be served
+	// when refreshing it fails (or, with IndexStaleWhileRevalidate, while it
+	// is refreshed in the background). Zero disables serving stale indexes.
+	IndexMaxStale             time.Duration
+	IndexStaleWhileRevalidate bool
+
+	// Concurrent download limits; zero disables a limit. Downloads over a
+	// limit wait up to FetchQueueTimeout for a free slot
+	MaxFetches        int
+	MaxHostFetches    int
+	FetchQueueTimeout time.Duration
+
+	// FailureTTL is how long chart-not-found results and repository failures
+	// are cached. Zero disables caching failures.
+	FailureTTL time.Duration
 
 	// Security settings
 	AllowPrivateIPs bool
 	AllowedHosts    []string
 	DeniedHosts     []string
+	AllowedCIDRs    []string
+	DeniedCIDRs     []string
+
+	// RestrictChartHost limits chart downloads (and their redirects) to the repository host.
+	RestrictChartHost bool
+
+	// OCIPlainHTTP reaches OCI registries over HTTP instead of HTTPS.
+	OCIPlainHTTP bool
+
+	// Provenance verification settings
+	ProvenanceMode      string
+	ProvenanceKeyring   string
+	ProvenanceHosts     []string
+	ProvenanceSkipHosts []string
+
+	// Cosign verification settings (OCI charts)
+	CosignMode      string
+	CosignKeys      []string
+	CosignHosts     []string
+	CosignSkipHosts []string
+
+	// Chart policy file (YAML)
+	PolicyFile string
+
+	// Cache warming settings; WarmFile lists the repositories and charts
+	WarmFile        string
+	WarmInterval    time.Duration
+	WarmVersions    int
+	WarmConcurrency int
+
+	// Cache administration; AdminTools registers the cache_status and
+	// refresh_repository tools, AdminToken enables the /admin HTTP endpoints
+	AdminTools bool
+	AdminToken string
+
+	// Authentication for the HTTP transport; disabled unless AuthTokensFile
+	// or OAuthJWKS is set
+	AuthTokensFile string
+	OAuthJWKS      string
+	OAuthIssuer    string
+	OAuthAudience  string
+	OAuthResource  string
+	OAuthScopes    []string
+
+	// Browser access to /mcp; requests with an Origin header must come from
+	// AllowedOrigins, and Host headers must be in AllowedHostHeaders if set
+	AllowedOrigins     []string
+	AllowedHostHeaders []string
 
 	// HTTP server settings
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	Metrics      bool // Serve Prometheus metrics on /metrics
+
+	// Rate limits per client, in calls per minute; zero disables a limit.
+	// RateLimitRequests limits HTTP requests to /mcp, RateLimitToolCalls
+	// calls to tools answered from cached indexes, and RateLimitDownloads
+	// calls to tools that download charts
+	RateLimitRequests       float64
+	RateLimitRequestsBurst  int
+	RateLimitToolCalls      float64
+	RateLimitToolCallsBurst int
+	RateLimitDownloads      float64
+	RateLimitDownloadsBurst int
+
+	// Readiness; /readyz fails while any of ReadyRepositories is unreachable,
+	// and for ShutdownDelay after a shutdown signal before the server stops
+	ReadyRepositories []string
+	ShutdownDelay     time.Duration
+
+	// Tracing; an empty OTLPEndpoint disables it
+	OTLPEndpoint     string
+	TraceSampleRatio float64
 
 	// Logging
 	LogLevel  string
@@ -64,23 +163,98 @@ func load(args []string, lookupEnv func(string) (string, bool)) (*Config, error)
 
 	// Transport flags
 	fs.StringVar(&cfg.Transport, "transport", "stdio", "Transport mode: stdio, http (env: MCP_HELM_TRANSPORT)")
-	fs.StringVar(&cfg.Listen, "listen", ":8012", "Listen address for HTTP mode (env: MCP_HELM_LISTEN)")
+	fs.StringVar(&cfg.Listen, "listen", "", "Listen address for HTTP mode; defaults to 127.0.0.1:8012, or :8012 when authentication is configured (env: MCP_HELM_LISTEN)")
 
 	// Helm flags
 	fs.DurationVar(&cfg.HelmTimeout, "helm-timeout", 30*time.Second, "Timeout for Helm operations (env: MCP_HELM_HELM_TIMEOUT)")
-	fs.IntVar(&cfg.CacheSize, "cache-size", 50, "Max charts to cache (env: MCP_HELM_CACHE_SIZE)")
+	fs.IntVar(&cfg.CacheSize, "cache-size", 50, "Max charts, and parsed values documents, to cache (env: MCP_HELM_CACHE_SIZE)")
+	fs.Int64Var(&cfg.ChartCacheBytes, "chart-cache-bytes", 256*1024*1024, "Max estimated memory for cached charts, 0 for no byte limit (env: MCP_HELM_CHART_CACHE_BYTES)")
+	fs.Int64Var(&cfg.IndexCacheBytes, "index-cache-bytes", 256*1024*1024, "Max estimated memory for cached repository indexes, 0 for no byte limit (env: MCP_HELM_INDEX_CACHE_BYTES)")
 	fs.DurationVar(&cfg.IndexTTL, "index-ttl", 5*time.Minute, "Repository index cache TTL (env: MCP_HELM_INDEX_TTL)")
+	fs.Int64Var(&cfg.MaxArchiveBytes, "max-archive-size", 100*1024*1024, "Max decompressed bytes of a chart archive, including subcharts (env: MCP_HELM_MAX_ARCHIVE_SIZE)")
+	fs.IntVar(&cfg.MaxArchiveFiles, "max-archive-files", 5000, "Max files in a chart archive, including subcharts (env: MCP_HELM_MAX_ARCHIVE_FILES)")
+	fs.Int64Var(&cfg.MaxArchiveFileBytes, "max-archive-file-size", 5*1024*1024, "Max decompressed bytes of a single file in a chart archive (env: MCP_HELM_MAX_ARCHIVE_FILE_SIZE)")
+	fs.IntVar(&cfg.MaxArchiveDepth, "max-archive-depth", 16, "Max directory depth of files in a chart archive (env: MCP_HELM_MAX_ARCHIVE_DEPTH)")
+	fs.DurationVar(&cfg.IndexMaxStale, "index-max-stale", 0, "Max age of an expired index served when refreshing it fails, 0 disables (env: MCP_HELM_INDEX_MAX_STALE)")
+	fs.BoolVar(&cfg.IndexStaleWhileRevalidate, "index-stale-while-revalidate", false, "Serve expired indexes immediately while refreshing them in the background; requires --index-max-stale (env: MCP_HELM_INDEX_STALE_WHILE_REVALIDATE)")
+	fs.DurationVar(&cfg.FailureTTL, "failure-ttl", 30*time.Second, "How long chart-not-found results and repository failures are cached, 0 disables (env: MCP_HELM_FAILURE_TTL)")
+	fs.IntVar(&cfg.MaxFetches, "max-fetches", 32, "Max index and chart downloads in progress at once, 0 for no limit (env: MCP_HELM_MAX_FETCHES)")
+	fs.IntVar(&cfg.MaxHostFetches, "max-host-fetches", 8, "Max downloads in progress at once from each repository host, 0 for no limit (env: MCP_HELM_MAX_HOST_FETCHES)")
+	fs.DurationVar(&cfg.FetchQueueTimeout, "fetch-queue-timeout", 30*time.Second, "How long a download over the concurrency limits waits for a free slot, 0 waits until the operation times out (env: MCP_HELM_FETCH_QUEUE_TIMEOUT)")
 	fs.IntVar(&cfg.MaxOutputBytes, "max-output-size", 2*1024*1024, "Max tool output bytes (env: MCP_HELM_MAX_OUTPUT_SIZE)")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", "", "Directory for the persistent chart and index cache; defaults to mcp-helm in the user cache directory (env: MCP_HELM_CACHE_DIR)")
+	fs.Int64Var(&cfg.DiskCacheSize, "disk-cache-size", 1024*1024*1024, "Max bytes of the persistent cache, 0 disables (env: MCP_HELM_DISK_CACHE_SIZE)")
 
 	// Security flags
 	fs.BoolVar(&cfg.AllowPrivateIPs, "allow-private-ips", false, "Allow URLs resolving to private IPs (env: MCP_HELM_ALLOW_PRIVATE_IPS)")
-	var allowedHosts, deniedHosts string
+	var allowedHosts, deniedHosts, allowedCIDRs, deniedCIDRs string
 	fs.StringVar(&allowedHosts, "allowed-hosts", "", "Comma-separated allowlist of hostnames (env: MCP_HELM_ALLOWED_HOSTS)")
 	fs.StringVar(&deniedHosts, "denied-hosts", "", "Comma-separated denylist of hostnames (env: MCP_HELM_DENIED_HOSTS)")
+	fs.StringVar(&allowedCIDRs, "allowed-cidrs", "", "Comma-separated networks permitted even if blocked by default, e.g. an internal repo subnet (env: MCP_HELM_ALLOWED_CIDRS)")
+	fs.StringVar(&deniedCIDRs, "denied-cidrs", "", "Comma-separated networks that are always refused (env: MCP_HELM_DENIED_CIDRS)")
+	fs.BoolVar(&cfg.RestrictChartHost, "restrict-chart-host", false, "Only download charts from the repository's own host, including redirects (env: MCP_HELM_RESTRICT_CHART_HOST)")
+	fs.BoolVar(&cfg.OCIPlainHTTP, "oci-plain-http", false, "Reach OCI registries over plain HTTP instead of HTTPS (env: MCP_HELM_OCI_PLAIN_HTTP)")
+
+	// Provenance flags
+	fs.StringVar(&cfg.ProvenanceMode, "provenance-mode", "off", "Chart provenance verification: off, annotate, enforce (env: MCP_HELM_PROVENANCE_MODE)")
+	fs.StringVar(&cfg.ProvenanceKeyring, "provenance-keyring", "", "PGP keyring used to verify chart provenance files (env: MCP_HELM_PROVENANCE_KEYRING)")
+	var provenanceHosts, provenanceSkipHosts string
+	fs.StringVar(&provenanceHosts, "provenance-hosts", "", "Comma-separated repository hosts to verify; empty verifies all (env: MCP_HELM_PROVENANCE_HOSTS)")
+	fs.StringVar(&provenanceSkipHosts, "provenance-skip-hosts", "", "Comma-separated repository hosts exempt from verification (env: MCP_HELM_PROVENANCE_SKIP_HOSTS)")
+
+	// Cosign flags
+	fs.StringVar(&cfg.CosignMode, "cosign-mode", "off", "OCI chart cosign verification: off, annotate, enforce (env: MCP_HELM_COSIGN_MODE)")
+	var cosignKeys, cosignHosts, cosignSkipHosts string
+	var readyRepositories string
+	var oauthScopes string
+	fs.StringVar(&cosignKeys, "cosign-keys", "", "Comma-separated PEM public key files trusted for cosign signatures (env: MCP_HELM_COSIGN_KEYS)")
+	fs.StringVar(&cosignHosts, "cosign-hosts", "", "Comma-separated registry hosts to verify; empty verifies all (env: MCP_HELM_COSIGN_HOSTS)")
+	fs.StringVar(&cosignSkipHosts, "cosign-skip-hosts", "", "Comma-separated registry hosts exempt from verification (env: MCP_HELM_COSIGN_SKIP_HOSTS)")
+
+	// Policy flags
+	fs.StringVar(&cfg.PolicyFile, "policy-file", "", "YAML chart policy allowing or denying charts by repository, name and version (env: MCP_HELM_POLICY_FILE)")
+
+	// Warming flags
+	fs.StringVar(&cfg.WarmFile, "warm-file", "", "YAML list of repositories and charts to load into the caches at startup (env: MCP_HELM_WARM_FILE)")
+	fs.DurationVar(&cfg.WarmInterval, "warm-interval", 0, "Interval between cache warming rounds, 0 warms once at startup (env: MCP_HELM_WARM_INTERVAL)")
+	fs.IntVar(&cfg.WarmVersions, "warm-versions", 1, "Latest versions of each listed chart to warm (env: MCP_HELM_WARM_VERSIONS)")
+	fs.IntVar(&cfg.WarmConcurrency, "warm-concurrency", 4, "Max repositories and charts warmed at once (env: MCP_HELM_WARM_CONCURRENCY)")
+
+	// Admin flags
+	fs.BoolVar(&cfg.AdminTools, "admin-tools", false, "Register the cache_status and refresh_repository MCP tools (env: MCP_HELM_ADMIN_TOOLS)")
+	fs.StringVar(&cfg.AdminToken, "admin-token", "", "Bearer token for the /admin HTTP endpoints, which are disabled when empty (env: MCP_HELM_ADMIN_TOKEN)")
+
+	// Auth flags
+	fs.StringVar(&cfg.AuthTokensFile, "auth-tokens-file", "", "File of bearer tokens accepted on /mcp, one per line, optionally preceded by a client name (env: MCP_HELM_AUTH_TOKENS_FILE)")
+	fs.StringVar(&cfg.OAuthJWKS, "oauth-jwks", "", "Path or https URL of the JWKS that JWT bearer tokens are verified with; enables OAuth resource server mode (env: MCP_HELM_OAUTH_JWKS)")
+	fs.StringVar(&cfg.OAuthIssuer, "oauth-issuer", "", "Required issuer of JWTs, advertised as the authorization server (env: MCP_HELM_OAUTH_ISSUER)")
+	fs.StringVar(&cfg.OAuthAudience, "oauth-audience", "", "Required audience of JWTs; defaults to --oauth-resource (env: MCP_HELM_OAUTH_AUDIENCE)")
+	fs.StringVar(&cfg.OAuthResource, "oauth-resource", "", "Public URL of the MCP endpoint, e.g. https://mcp.example.com/mcp (env: MCP_HELM_OAUTH_RESOURCE)")
+	fs.StringVar(&oauthScopes, "oauth-scopes", "", "Comma-separated scopes JWTs must grant (env: MCP_HELM_OAUTH_SCOPES)")
+
+	// Origin flags
+	var allowedOrigins, allowedHostHeaders string
+	fs.StringVar(&allowedOrigins, "allowed-origins", "", "Comma-separated origins of web clients allowed to call /mcp from a browser, e.g. https://app.example.com, or * for any (env: MCP_HELM_ALLOWED_ORIGINS)")
+	fs.StringVar(&allowedHostHeaders, "allowed-host-headers", "", "Comma-separated Host header values accepted on /mcp, with or without a port; defaults to loopback names for connections to a loopback address (env: MCP_HELM_ALLOWED_HOST_HEADERS)")
 
 	// Server flags
 	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", 30*time.Second, "HTTP read timeout (env: MCP_HELM_READ_TIMEOUT)")
 	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", 30*time.Second, "HTTP write timeout (env: MCP_HELM_WRITE_TIMEOUT)")
+	fs.BoolVar(&cfg.Metrics, "metrics", true, "Serve Prometheus metrics on /metrics in HTTP mode (env: MCP_HELM_METRICS)")
+	fs.StringVar(&readyRepositories, "ready-repositories", "", "Comma-separated repositories that must be reachable for /readyz to report ready (env: MCP_HELM_READY_REPOSITORIES)")
+	fs.DurationVar(&cfg.ShutdownDelay, "shutdown-delay", 0, "Time to keep serving after a shutdown signal with /readyz failing, so load balancers stop routing first (env: MCP_HELM_SHUTDOWN_DELAY)")
+
+	// Rate limit flags
+	fs.Float64Var(&cfg.RateLimitRequests, "rate-limit-requests", 0, "HTTP requests to /mcp per minute per client (authenticated user or IP), 0 for no limit (env: MCP_HELM_RATE_LIMIT_REQUESTS)")
+	fs.IntVar(&cfg.RateLimitRequestsBurst, "rate-limit-requests-burst", 60, "HTTP requests to /mcp a client may make at once (env: MCP_HELM_RATE_LIMIT_REQUESTS_BURST)")
+	fs.Float64Var(&cfg.RateLimitToolCalls, "rate-limit-tool-calls", 0, "Calls per minute per client (authenticated user or MCP session) to tools served from cached indexes, 0 for no limit (env: MCP_HELM_RATE_LIMIT_TOOL_CALLS)")
+	fs.IntVar(&cfg.RateLimitToolCallsBurst, "rate-limit-tool-calls-burst", 30, "Calls to tools served from cached indexes a client may make at once (env: MCP_HELM_RATE_LIMIT_TOOL_CALLS_BURST)")
+	fs.Float64Var(&cfg.RateLimitDownloads, "rate-limit-downloads", 0, "Calls per minute per client to tools that download charts, 0 for no limit (env: MCP_HELM_RATE_LIMIT_DOWNLOADS)")
+	fs.IntVar(&cfg.RateLimitDownloadsBurst, "rate-limit-downloads-burst", 10, "Calls to tools that download charts a client may make at once (env: MCP_HELM_RATE_LIMIT_DOWNLOADS_BURST)")
+
+	// Tracing flags
+	fs.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector URL to export traces to, e.g. http://localhost:4318; tracing is disabled when empty (env: MCP_HELM_OTLP_ENDPOINT)")
+	fs.Float64Var(&cfg.TraceSampleRatio, "trace-sample-ratio", 1, "Fraction of new traces to record, from 0 to 1 (env: MCP_HELM_TRACE_SAMPLE_RATIO)")
 
 	// Logging flags
 	fs.StringVar(&cfg.LogLevel, "log-level", "info", "Log level: debug, info, warn, error (env: MCP_HELM_LOG_LEVEL)")
@@ -100,8 +274,61 @@ func load(args []string, lookupEnv func(string) (string, bool)) (*Config, error)
 	if f := fs.Lookup("denied-hosts"); f != nil {
 		deniedHosts = f.Value.String()
 	}
+	if f := fs.Lookup("allowed-cidrs"); f != nil {
+		allowedCIDRs = f.Value.String()
+	}
+	if f := fs.Lookup("denied-cidrs"); f != nil {
+		deniedCIDRs = f.Value.String()
+	}
+	if f := fs.Lookup("provenance-hosts"); f != nil {
+		provenanceHosts = f.Value.String()
+	}
+	if f := fs.Lookup("provenance-skip-hosts"); f != nil {
+		provenanceSkipHosts = f.Value.String()
+	}
+	if f := fs.Lookup("cosign-keys"); f != nil {
+		cosignKeys = f.Value.String()
+	}
+	if f := fs.Lookup("cosign-hosts"); f != nil {
+		cosignHosts = f.Value.String()
+	}
+	if f := fs.Lookup("cosign-skip-hosts"); f != nil {
+		cosignSkipHosts = f.Value.String()
+	}
+	if f := fs.Lookup("ready-repositories"); f != nil {
+		readyRepositories = f.Value.String()
+	}
+	if f := fs.Lookup("oauth-scopes"); f != nil {
+		oauthScopes = f.Value.String()
+	}
+	if f := fs.Lookup("allowed-origins"); f != nil {
+		allowedOrigins = f.Value.String()
+	}
+	if f := fs.Lookup("allowed-host-headers"); f != nil {
+		allowedHostHeaders = f.Value.String()
+	}
 	cfg.AllowedHosts = parseCSV(allowedHosts)
 	cfg.DeniedHosts = parseCSV(deniedHosts)
+	cfg.AllowedCIDRs = parseCSV(allowedCIDRs)
+	cfg.DeniedCIDRs = parseCSV(deniedCIDRs)
+	cfg.ProvenanceHosts = parseCSV(provenanceHosts)
+	cfg.ProvenanceSkipHosts = parseCSV(provenanceSkipHosts)
+	cfg.CosignKeys = parseCSV(cosignKeys)
+	cfg.CosignHosts = parseCSV(cosignHosts)
+	cfg.CosignSkipHosts = parseCSV(cosignSkipHosts)
+	cfg.ReadyRepositories = parseCSV(readyRepositories)
+	cfg.OAuthScopes = parseCSV(oauthScopes)
+	cfg.AllowedOrigins = parseCSV(allowedOrigins)
+	cfg.AllowedHostHeaders = parseCSV(allowedHostHeaders)
+
+	// Without authentication, only local clients can reach the server unless
+	// a listen address is given
+	if !fs.Changed("listen") {
+		cfg.Listen = "127.0.0.1:8012"
+		if cfg.AuthTokensFile != "" || cfg.OAuthJWKS != "" {
+			cfg.Listen = ":8012"
+		}
+	}
 
 	if err := cfg.validate(); err != nil {
 		return nil, err
@@ -156,12 +383,27 @@ func (c *Config) validate() error {
 	if c.IndexTTL <= 0 {
 		errs = append(errs, errors.New("--index-ttl must be positive"))
 	}
+	if c.IndexMaxStale < 0 || (c.IndexMaxStale > 0 && c.IndexMaxStale <= c.IndexTTL) {
+		errs = append(errs, errors.New("--index-max-stale must be 0 or greater than --index-ttl"))
+	}
+	if c.IndexStaleWhileRevalidate && c.IndexMaxStale == 0 {
+		errs = append(errs, errors.New("--index-stale-while-revalidate requires --index-max-stale"))
+	}
+	if c.FailureTTL < 0 {
+		errs = append(errs, errors.New("--failure-ttl must not be negative"))
+	}
+	if c.FetchQueueTimeout < 0 {
+		errs = append(errs, errors.New("--fetch-queue-timeout must not be negative"))
+	}
 	if c.ReadTimeout <= 0 {
 		errs = append(errs, errors.New("--read-timeout must be positive"))
 	}
 	if c.WriteTimeout <= 0 {
 		errs = append(errs, errors.New("--write-timeout must be positive"))
 	}
+	if c.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("--shutdown-delay must not be negative"))
+	}
 
 	// Positive integers
 	if c.CacheSize <= 0 {
@@ -170,6 +412,116 @@ func (c *Config) validate() error {
 	if c.MaxOutputBytes <= 0 {
 		errs = append(errs, errors.New("--max-output-size must be positive"))
 	}
+	if c.MaxArchiveBytes <= 0 {
+		errs = append(errs, errors.New("--max-archive-size must be positive"))
+	}
+	if c.MaxArchiveFiles <= 0 {
+		errs = append(errs, errors.New("--max-archive-files must be positive"))
+	}
+	if c.MaxArchiveFileBytes <= 0 {
+		errs = append(errs, errors.New("--max-archive-file-size must be positive"))
+	}
+	if c.MaxArchiveDepth <= 0 {
+		errs = append(errs, errors.New("--max-archive-depth must be positive"))
+	}
+	if c.ChartCacheBytes < 0 {
+		errs = append(errs, errors.New("--chart-cache-bytes must not be negative"))
+	}
+	if c.IndexCacheBytes < 0 {
+		errs = append(errs, errors.New("--index-cache-bytes must not be negative"))
+	}
+	if c.DiskCacheSize < 0 {
+		errs = append(errs, errors.New("--disk-cache-size must not be negative"))
+	}
+	if c.MaxFetches < 0 {
+		errs = append(errs, errors.New("--max-fetches must not be negative"))
+	}
+	if c.MaxHostFetches < 0 {
+		errs = append(errs, errors.New("--max-host-fetches must not be negative"))
+	}
+	if c.WarmInterval < 0 {
+		errs = append(errs, errors.New("--warm-interval must not be negative"))
+	}
+	if c.WarmVersions <= 0 {
+		errs = append(errs, errors.New("--warm-versions must be positive"))
+	}
+	if c.WarmConcurrency <= 0 {
+		errs = append(errs, errors.New("--warm-concurrency must be positive"))
+	}
+
+	// Rate limits
+	for _, l := range []struct {
+		name      string
+		perMinute float64
+		burst     int
+	}{
+		{"rate-limit-requests", c.RateLimitRequests, c.RateLimitRequestsBurst},
+		{"rate-limit-tool-calls", c.RateLimitToolCalls, c.RateLimitToolCallsBurst},
+		{"rate-limit-downloads", c.RateLimitDownloads, c.RateLimitDownloadsBurst},
+	} {
+		if l.perMinute < 0 {
+			errs = append(errs, fmt.Errorf("--%s must not be negative", l.name))
+		}
+		if l.perMinute > 0 && l.burst <= 0 {
+			errs = append(errs, fmt.Errorf("--%s-burst must be positive", l.name))
+		}
+	}
+
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		errs = append(errs, errors.New("--trace-sample-ratio must be between 0 and 1"))
+	}
+	if c.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid otlp-endpoint %q: must be an http or https URL", c.OTLPEndpoint))
+		}
+	}
+
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			continue
+		}
+		if u, err := url.Parse(o); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("invalid allowed origin %q: must be an http or https origin such as https://app.example.com", o))
+		}
+	}
+
+	// OAuth validation
+	if c.OAuthJWKS != "" {
+		if c.OAuthIssuer == "" {
+			errs = append(errs, errors.New("--oauth-issuer required with --oauth-jwks"))
+		}
+		if u, err := url.Parse(c.OAuthResource); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, errors.New("--oauth-resource must be the http or https URL of the MCP endpoint when --oauth-jwks is set"))
+		}
+	} else if c.OAuthIssuer != "" || c.OAuthAudience != "" || c.OAuthResource != "" || len(c.OAuthScopes) > 0 {
+		errs = append(errs, errors.New("--oauth-issuer, --oauth-audience, --oauth-resource and --oauth-scopes require --oauth-jwks"))
+	}
+
+	// CIDR validation
+	for _, cidr := range c.AllowedCIDRs {
+		if !validCIDR(cidr) {
+			errs = append(errs, fmt.Errorf("invalid allowed-cidrs entry %q: must be a CIDR or IP address", cidr))
+		}
+	}
+	for _, cidr := range c.DeniedCIDRs {
+		if !validCIDR(cidr) {
+			errs = append(errs, fmt.Errorf("invalid denied-cidrs entry %q: must be a CIDR or IP address", cidr))
+		}
+	}
+
+	// Provenance validation
+	if mode, ok := verificationMode(c.ProvenanceMode); !ok {
+		errs = append(errs, fmt.Errorf("invalid provenance-mode %q: must be off, annotate, or enforce", c.ProvenanceMode))
+	} else if mode != "off" && c.ProvenanceKeyring == "" {
+		errs = append(errs, fmt.Errorf("--provenance-keyring required for provenance-mode %q", c.ProvenanceMode))
+	}
+
+	// Cosign validation
+	if mode, ok := verificationMode(c.CosignMode); !ok {
+		errs = append(errs, fmt.Errorf("invalid cosign-mode %q: must be off, annotate, or enforce", c.CosignMode))
+	} else if mode != "off" && len(c.CosignKeys) == 0 {
+		errs = append(errs, fmt.Errorf("--cosign-keys required for cosign-mode %q", c.CosignMode))
+	}
 
 	// Log level validation
 	switch c.LogLevel {
@@ -193,6 +545,30 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// validCIDR reports whether s is a CIDR prefix or a bare IP address.
+func validCIDR(s string) bool {
+	if strings.Contains(s, "/") {
+		_, err := netip.ParsePrefix(s)
+		return err == nil
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+// verificationMode normalizes a provenance or cosign mode the way the helm
+// client parses it, reporting whether it is one of off, annotate or enforce.
+// An empty mode is off.
+func verificationMode(s string) (string, bool) {
+	switch m := strings.ToLower(strings.TrimSpace(s)); m {
+	case "":
+		return "off", true
+	case "off", "annotate", "enforce":
+		return m, true
+	default:
+		return "", false
+	}
+}
+
 // parseCSV splits a comma-separated string into trimmed, non-empty parts.
 func parseCSV(s string) []string {
 	if s == "" {
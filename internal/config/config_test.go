@@ -62,16 +62,22 @@ func TestParseCSV(t *testing.T) {
 func TestConfig_Validate(t *testing.T) {
 	baseConfig := func() *Config {
 		return &Config{
-			Transport:      "stdio",
-			Listen:         ":8012",
-			HelmTimeout:    30 * time.Second,
-			CacheSize:      50,
-			IndexTTL:       5 * time.Minute,
-			MaxOutputBytes: 2 * 1024 * 1024,
-			ReadTimeout:    30 * time.Second,
-			WriteTimeout:   30 * time.Second,
-			LogLevel:       "info",
-			LogFormat:      "json",
+			Transport:           "stdio",
+			Listen:              ":8012",
+			HelmTimeout:         30 * time.Second,
+			CacheSize:           50,
+			IndexTTL:            5 * time.Minute,
+			MaxOutputBytes:      2 * 1024 * 1024,
+			WarmVersions:        1,
+			MaxArchiveBytes:     100 * 1024 * 1024,
+			MaxArchiveFiles:     5000,
+			MaxArchiveFileBytes: 5 * 1024 * 1024,
+			MaxArchiveDepth:     16,
+			WarmConcurrency:     4,
+			ReadTimeout:         30 * time.Second,
+			WriteTimeout:        30 * time.Second,
+			LogLevel:            "info",
+			LogFormat:           "json",
 		}
 	}
 
@@ -133,6 +139,79 @@ func TestConfig_Validate(t *testing.T) {
 			modify:  func(c *Config) { c.MaxOutputBytes = 0 },
 			wantErr: "--max-output-size must be positive",
 		},
+		{
+			name:    "index max stale not beyond TTL",
+			modify:  func(c *Config) { c.IndexMaxStale = c.IndexTTL },
+			wantErr: "--index-max-stale must be 0 or greater than --index-ttl",
+		},
+		{
+			name:    "stale-while-revalidate without max stale",
+			modify:  func(c *Config) { c.IndexStaleWhileRevalidate = true },
+			wantErr: "--index-stale-while-revalidate requires --index-max-stale",
+		},
+		{
+			name: "stale-while-revalidate with max stale",
+			modify: func(c *Config) {
+				c.IndexMaxStale = 24 * time.Hour
+				c.IndexStaleWhileRevalidate = true
+			},
+			wantErr: "",
+		},
+		{
+			name:    "negative chart cache bytes",
+			modify:  func(c *Config) { c.ChartCacheBytes = -1 },
+			wantErr: "--chart-cache-bytes must not be negative",
+		},
+		{
+			name:    "negative index cache bytes",
+			modify:  func(c *Config) { c.IndexCacheBytes = -1 },
+			wantErr: "--index-cache-bytes must not be negative",
+		},
+		{
+			name:    "negative disk cache size",
+			modify:  func(c *Config) { c.DiskCacheSize = -1 },
+			wantErr: "--disk-cache-size must not be negative",
+		},
+		{
+			name:    "negative warm interval",
+			modify:  func(c *Config) { c.WarmInterval = -time.Minute },
+			wantErr: "--warm-interval must not be negative",
+		},
+		{
+			name:    "zero warm versions",
+			modify:  func(c *Config) { c.WarmVersions = 0 },
+			wantErr: "--warm-versions must be positive",
+		},
+		{
+			name:    "zero warm concurrency",
+			modify:  func(c *Config) { c.WarmConcurrency = 0 },
+			wantErr: "--warm-concurrency must be positive",
+		},
+		{
+			name:    "negative failure TTL",
+			modify:  func(c *Config) { c.FailureTTL = -time.Second },
+			wantErr: "--failure-ttl must not be negative",
+		},
+		{
+			name:    "zero max archive size",
+			modify:  func(c *Config) { c.MaxArchiveBytes = 0 },
+			wantErr: "--max-archive-size must be positive",
+		},
+		{
+			name:    "zero max archive files",
+			modify:  func(c *Config) { c.MaxArchiveFiles = 0 },
+			wantErr: "--max-archive-files must be positive",
+		},
+		{
+			name:    "negative max archive file size",
+			modify:  func(c *Config) { c.MaxArchiveFileBytes = -1 },
+			wantErr: "--max-archive-file-size must be positive",
+		},
+		{
+			name:    "zero max archive depth",
+			modify:  func(c *Config) { c.MaxArchiveDepth = 0 },
+			wantErr: "--max-archive-depth must be positive",
+		},
 		{
 			name:    "zero read timeout",
 			modify:  func(c *Config) { c.ReadTimeout = 0 },
@@ -143,6 +222,68 @@ func TestConfig_Validate(t *testing.T) {
 			modify:  func(c *Config) { c.WriteTimeout = 0 },
 			wantErr: "--write-timeout must be positive",
 		},
+		{
+			name:    "invalid provenance mode",
+			modify:  func(c *Config) { c.ProvenanceMode = "strict" },
+			wantErr: "invalid provenance-mode",
+		},
+		{
+			name: "provenance mode is case-insensitive",
+			modify: func(c *Config) {
+				c.ProvenanceMode = "Enforce"
+				c.ProvenanceKeyring = "/etc/mcp-helm/pubring.gpg"
+			},
+			wantErr: "",
+		},
+		{
+			name:    "provenance enforce without keyring",
+			modify:  func(c *Config) { c.ProvenanceMode = "enforce" },
+			wantErr: "--provenance-keyring required",
+		},
+		{
+			name: "valid provenance annotate with keyring",
+			modify: func(c *Config) {
+				c.ProvenanceMode = "annotate"
+				c.ProvenanceKeyring = "/etc/mcp-helm/pubring.gpg"
+			},
+			wantErr: "",
+		},
+		{
+			name: "valid CIDRs",
+			modify: func(c *Config) {
+				c.AllowedCIDRs = []string{"10.20.0.0/16", "fd00::/8"}
+				c.DeniedCIDRs = []string{"169.254.169.254"}
+			},
+			wantErr: "",
+		},
+		{
+			name:    "invalid allowed CIDR",
+			modify:  func(c *Config) { c.AllowedCIDRs = []string{"10.20.0.0/33"} },
+			wantErr: "invalid allowed-cidrs entry",
+		},
+		{
+			name:    "invalid denied CIDR",
+			modify:  func(c *Config) { c.DeniedCIDRs = []string{"metadata"} },
+			wantErr: "invalid denied-cidrs entry",
+		},
+		{
+			name:    "invalid cosign mode",
+			modify:  func(c *Config) { c.CosignMode = "strict" },
+			wantErr: "invalid cosign-mode",
+		},
+		{
+			name:    "cosign enforce without keys",
+			modify:  func(c *Config) { c.CosignMode = "enforce" },
+			wantErr: "--cosign-keys required",
+		},
+		{
+			name: "valid cosign enforce with keys",
+			modify: func(c *Config) {
+				c.CosignMode = "enforce"
+				c.CosignKeys = []string{"/etc/mcp-helm/cosign.pub"}
+			},
+			wantErr: "",
+		},
 		{
 			name:    "invalid log level",
 			modify:  func(c *Config) { c.LogLevel = "trace" },
@@ -173,6 +314,111 @@ func TestConfig_Validate(t *testing.T) {
 			modify:  func(c *Config) { c.LogFormat = "console" },
 			wantErr: "",
 		},
+		{
+			name: "valid OAuth config",
+			modify: func(c *Config) {
+				c.OAuthJWKS = "https://idp.example.com/jwks.json"
+				c.OAuthIssuer = "https://idp.example.com"
+				c.OAuthResource = "https://mcp.example.com/mcp"
+			},
+			wantErr: "",
+		},
+		{
+			name: "OAuth without issuer",
+			modify: func(c *Config) {
+				c.OAuthJWKS = "/etc/mcp-helm/jwks.json"
+				c.OAuthResource = "https://mcp.example.com/mcp"
+			},
+			wantErr: "--oauth-issuer required",
+		},
+		{
+			name: "OAuth without resource URL",
+			modify: func(c *Config) {
+				c.OAuthJWKS = "/etc/mcp-helm/jwks.json"
+				c.OAuthIssuer = "https://idp.example.com"
+			},
+			wantErr: "--oauth-resource must be",
+		},
+		{
+			name:    "OAuth settings without JWKS",
+			modify:  func(c *Config) { c.OAuthIssuer = "https://idp.example.com" },
+			wantErr: "require --oauth-jwks",
+		},
+		{
+			name:    "valid allowed origins",
+			modify:  func(c *Config) { c.AllowedOrigins = []string{"https://app.example.com", "http://localhost:6274/", "*"} },
+			wantErr: "",
+		},
+		{
+			name:    "allowed origin with path",
+			modify:  func(c *Config) { c.AllowedOrigins = []string{"https://app.example.com/mcp"} },
+			wantErr: "invalid allowed origin",
+		},
+		{
+			name:    "allowed origin without scheme",
+			modify:  func(c *Config) { c.AllowedOrigins = []string{"app.example.com"} },
+			wantErr: "invalid allowed origin",
+		},
+		{
+			name:    "negative max fetches",
+			modify:  func(c *Config) { c.MaxFetches = -1 },
+			wantErr: "--max-fetches must not be negative",
+		},
+		{
+			name:    "negative max host fetches",
+			modify:  func(c *Config) { c.MaxHostFetches = -1 },
+			wantErr: "--max-host-fetches must not be negative",
+		},
+		{
+			name:    "negative fetch queue timeout",
+			modify:  func(c *Config) { c.FetchQueueTimeout = -time.Second },
+			wantErr: "--fetch-queue-timeout must not be negative",
+		},
+		{
+			name:    "negative shutdown delay",
+			modify:  func(c *Config) { c.ShutdownDelay = -time.Second },
+			wantErr: "--shutdown-delay must not be negative",
+		},
+		{
+			name: "valid rate limits",
+			modify: func(c *Config) {
+				c.RateLimitToolCalls = 120
+				c.RateLimitToolCallsBurst = 30
+				c.RateLimitDownloads = 0.5
+				c.RateLimitDownloadsBurst = 5
+			},
+			wantErr: "",
+		},
+		{
+			name:    "negative rate limit",
+			modify:  func(c *Config) { c.RateLimitRequests = -1 },
+			wantErr: "--rate-limit-requests must not be negative",
+		},
+		{
+			name:    "rate limit without burst",
+			modify:  func(c *Config) { c.RateLimitDownloads = 10 },
+			wantErr: "--rate-limit-downloads-burst must be positive",
+		},
+		{
+			name:    "trace sample ratio above 1",
+			modify:  func(c *Config) { c.TraceSampleRatio = 1.5 },
+			wantErr: "--trace-sample-ratio must be between 0 and 1",
+		},
+		{
+			name:    "negative trace sample ratio",
+			modify:  func(c *Config) { c.TraceSampleRatio = -0.1 },
+			wantErr: "--trace-sample-ratio must be between 0 and 1",
+		},
+		{
+			name:    "valid OTLP endpoint",
+			modify:  func(c *Config) { c.OTLPEndpoint = "http://localhost:4318" },
+			wantErr: "",
+		},
+		{
+			name:    "OTLP endpoint without scheme",
+			modify:  func(c *Config) { c.OTLPEndpoint = "localhost:4318" },
+			wantErr: "invalid otlp-endpoint",
+		},
 	}
 
 	for _, tt := range tests {
@@ -245,11 +491,26 @@ func TestFlagEnvName(t *testing.T) {
 		{"listen", "MCP_HELM_LISTEN"},
 		{"helm-timeout", "MCP_HELM_HELM_TIMEOUT"},
 		{"cache-size", "MCP_HELM_CACHE_SIZE"},
+		{"chart-cache-bytes", "MCP_HELM_CHART_CACHE_BYTES"},
+		{"index-cache-bytes", "MCP_HELM_INDEX_CACHE_BYTES"},
 		{"index-ttl", "MCP_HELM_INDEX_TTL"},
+		{"index-max-stale", "MCP_HELM_INDEX_MAX_STALE"},
+		{"index-stale-while-revalidate", "MCP_HELM_INDEX_STALE_WHILE_REVALIDATE"},
 		{"max-output-size", "MCP_HELM_MAX_OUTPUT_SIZE"},
+		{"max-archive-file-size", "MCP_HELM_MAX_ARCHIVE_FILE_SIZE"},
+		{"cache-dir", "MCP_HELM_CACHE_DIR"},
+		{"disk-cache-size", "MCP_HELM_DISK_CACHE_SIZE"},
 		{"allow-private-ips", "MCP_HELM_ALLOW_PRIVATE_IPS"},
 		{"allowed-hosts", "MCP_HELM_ALLOWED_HOSTS"},
 		{"denied-hosts", "MCP_HELM_DENIED_HOSTS"},
+		{"allowed-cidrs", "MCP_HELM_ALLOWED_CIDRS"},
+		{"denied-cidrs", "MCP_HELM_DENIED_CIDRS"},
+		{"restrict-chart-host", "MCP_HELM_RESTRICT_CHART_HOST"},
+		{"oci-plain-http", "MCP_HELM_OCI_PLAIN_HTTP"},
+		{"provenance-mode", "MCP_HELM_PROVENANCE_MODE"},
+		{"provenance-skip-hosts", "MCP_HELM_PROVENANCE_SKIP_HOSTS"},
+		{"cosign-keys", "MCP_HELM_COSIGN_KEYS"},
+		{"policy-file", "MCP_HELM_POLICY_FILE"},
 		{"read-timeout", "MCP_HELM_READ_TIMEOUT"},
 		{"write-timeout", "MCP_HELM_WRITE_TIMEOUT"},
 		{"log-level", "MCP_HELM_LOG_LEVEL"},
@@ -287,6 +548,141 @@ func TestLoad_EnvVarFallback(t *testing.T) {
 		if cfg.CacheSize != 50 {
 			t.Errorf("CacheSize = %d, want %d", cfg.CacheSize, 50)
 		}
+		if cfg.ChartCacheBytes != 256*1024*1024 {
+			t.Errorf("ChartCacheBytes = %d, want %d", cfg.ChartCacheBytes, 256*1024*1024)
+		}
+		if !cfg.Metrics {
+			t.Error("Metrics = false, want true")
+		}
+		if cfg.MaxFetches != 32 || cfg.MaxHostFetches != 8 || cfg.FetchQueueTimeout != 30*time.Second {
+			t.Errorf("fetch limits = %d, %d per host, %v queue timeout; want 32, 8, 30s", cfg.MaxFetches, cfg.MaxHostFetches, cfg.FetchQueueTimeout)
+		}
+		if cfg.MaxArchiveBytes != 100*1024*1024 || cfg.MaxArchiveFiles != 5000 || cfg.MaxArchiveFileBytes != 5*1024*1024 || cfg.MaxArchiveDepth != 16 {
+			t.Errorf("archive limits = %d bytes, %d files, %d bytes per file, depth %d; want 104857600, 5000, 5242880, 16",
+				cfg.MaxArchiveBytes, cfg.MaxArchiveFiles, cfg.MaxArchiveFileBytes, cfg.MaxArchiveDepth)
+		}
+		if cfg.OTLPEndpoint != "" {
+			t.Errorf("OTLPEndpoint = %q, want tracing disabled", cfg.OTLPEndpoint)
+		}
+		if cfg.TraceSampleRatio != 1 {
+			t.Errorf("TraceSampleRatio = %v, want 1", cfg.TraceSampleRatio)
+		}
+	})
+
+	t.Run("env var disables metrics", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_METRICS": "false",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.Metrics {
+			t.Error("Metrics = true, want false")
+		}
+	})
+
+	t.Run("env vars configure tracing", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_OTLP_ENDPOINT":      "http://collector:4318",
+			"MCP_HELM_TRACE_SAMPLE_RATIO": "0.25",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.OTLPEndpoint != "http://collector:4318" {
+			t.Errorf("OTLPEndpoint = %q, want %q", cfg.OTLPEndpoint, "http://collector:4318")
+		}
+		if cfg.TraceSampleRatio != 0.25 {
+			t.Errorf("TraceSampleRatio = %v, want 0.25", cfg.TraceSampleRatio)
+		}
+	})
+
+	t.Run("env vars configure readiness", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_READY_REPOSITORIES": "https://charts.example.com, oci://ghcr.io/example",
+			"MCP_HELM_SHUTDOWN_DELAY":     "5s",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		want := []string{"https://charts.example.com", "oci://ghcr.io/example"}
+		if strings.Join(cfg.ReadyRepositories, ",") != strings.Join(want, ",") {
+			t.Errorf("ReadyRepositories = %v, want %v", cfg.ReadyRepositories, want)
+		}
+		if cfg.ShutdownDelay != 5*time.Second {
+			t.Errorf("ShutdownDelay = %v, want 5s", cfg.ShutdownDelay)
+		}
+	})
+
+	t.Run("env vars configure rate limits", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_RATE_LIMIT_TOOL_CALLS":      "300",
+			"MCP_HELM_RATE_LIMIT_DOWNLOADS":       "20",
+			"MCP_HELM_RATE_LIMIT_DOWNLOADS_BURST": "3",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.RateLimitRequests != 0 {
+			t.Errorf("RateLimitRequests = %v, want 0", cfg.RateLimitRequests)
+		}
+		if cfg.RateLimitToolCalls != 300 || cfg.RateLimitToolCallsBurst != 30 {
+			t.Errorf("tool calls limit = %v burst %d, want 300 burst 30", cfg.RateLimitToolCalls, cfg.RateLimitToolCallsBurst)
+		}
+		if cfg.RateLimitDownloads != 20 || cfg.RateLimitDownloadsBurst != 3 {
+			t.Errorf("downloads limit = %v burst %d, want 20 burst 3", cfg.RateLimitDownloads, cfg.RateLimitDownloadsBurst)
+		}
+	})
+
+	t.Run("listens on loopback without authentication", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(nil))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.Listen != "127.0.0.1:8012" {
+			t.Errorf("Listen = %q, want %q", cfg.Listen, "127.0.0.1:8012")
+		}
+	})
+
+	t.Run("listens on all interfaces with authentication", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_AUTH_TOKENS_FILE": "/etc/mcp-helm/tokens",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.Listen != ":8012" {
+			t.Errorf("Listen = %q, want %q", cfg.Listen, ":8012")
+		}
+	})
+
+	t.Run("explicit listen address is kept", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_LISTEN": ":9000",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.Listen != ":9000" {
+			t.Errorf("Listen = %q, want %q", cfg.Listen, ":9000")
+		}
+	})
+
+	t.Run("env vars configure browser access", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_ALLOWED_ORIGINS":      "https://app.example.com, http://localhost:6274",
+			"MCP_HELM_ALLOWED_HOST_HEADERS": "mcp.example.com",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		want := []string{"https://app.example.com", "http://localhost:6274"}
+		if strings.Join(cfg.AllowedOrigins, ",") != strings.Join(want, ",") {
+			t.Errorf("AllowedOrigins = %v, want %v", cfg.AllowedOrigins, want)
+		}
+		if len(cfg.AllowedHostHeaders) != 1 || cfg.AllowedHostHeaders[0] != "mcp.example.com" {
+			t.Errorf("AllowedHostHeaders = %v, want [mcp.example.com]", cfg.AllowedHostHeaders)
+		}
 	})
 
 	t.Run("env var sets transport", func(t *testing.T) {
@@ -340,6 +736,69 @@ func TestLoad_EnvVarFallback(t *testing.T) {
 		}
 	})
 
+	t.Run("env var sets disk cache", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_CACHE_DIR":       "/var/cache/mcp-helm",
+			"MCP_HELM_DISK_CACHE_SIZE": "0",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.CacheDir != "/var/cache/mcp-helm" {
+			t.Errorf("CacheDir = %q, want %q", cfg.CacheDir, "/var/cache/mcp-helm")
+		}
+		if cfg.DiskCacheSize != 0 {
+			t.Errorf("DiskCacheSize = %d, want 0", cfg.DiskCacheSize)
+		}
+	})
+
+	t.Run("env var sets failure TTL", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_FAILURE_TTL": "0",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.FailureTTL != 0 {
+			t.Errorf("FailureTTL = %v, want 0", cfg.FailureTTL)
+		}
+	})
+
+	t.Run("env var sets cache warming", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_WARM_FILE":     "/etc/mcp-helm/warm.yaml",
+			"MCP_HELM_WARM_INTERVAL": "1h",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.WarmFile != "/etc/mcp-helm/warm.yaml" {
+			t.Errorf("WarmFile = %q, want %q", cfg.WarmFile, "/etc/mcp-helm/warm.yaml")
+		}
+		if cfg.WarmInterval != time.Hour {
+			t.Errorf("WarmInterval = %v, want %v", cfg.WarmInterval, time.Hour)
+		}
+		if cfg.WarmVersions != 1 || cfg.WarmConcurrency != 4 {
+			t.Errorf("WarmVersions, WarmConcurrency = %d, %d, want defaults 1, 4", cfg.WarmVersions, cfg.WarmConcurrency)
+		}
+	})
+
+	t.Run("env var sets admin settings", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_ADMIN_TOOLS": "true",
+			"MCP_HELM_ADMIN_TOKEN": "s3cret",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if !cfg.AdminTools {
+			t.Error("AdminTools = false, want true")
+		}
+		if cfg.AdminToken != "s3cret" {
+			t.Errorf("AdminToken = %q, want %q", cfg.AdminToken, "s3cret")
+		}
+	})
+
 	t.Run("env var sets boolean", func(t *testing.T) {
 		cfg, err := load(nil, envFrom(map[string]string{
 			"MCP_HELM_ALLOW_PRIVATE_IPS": "true",
@@ -352,6 +811,30 @@ func TestLoad_EnvVarFallback(t *testing.T) {
 		}
 	})
 
+	t.Run("env var restricts chart host", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_RESTRICT_CHART_HOST": "true",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if !cfg.RestrictChartHost {
+			t.Error("RestrictChartHost = false, want true")
+		}
+	})
+
+	t.Run("env var enables plain HTTP registries", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_OCI_PLAIN_HTTP": "true",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if !cfg.OCIPlainHTTP {
+			t.Error("OCIPlainHTTP = false, want true")
+		}
+	})
+
 	t.Run("env var sets CSV hosts", func(t *testing.T) {
 		cfg, err := load(nil, envFrom(map[string]string{
 			"MCP_HELM_ALLOWED_HOSTS": "a.com,b.com",
@@ -364,6 +847,66 @@ func TestLoad_EnvVarFallback(t *testing.T) {
 		}
 	})
 
+	t.Run("env var sets CSV CIDRs", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_ALLOWED_CIDRS": "10.20.0.0/16, 10.30.0.0/16",
+			"MCP_HELM_DENIED_CIDRS":  "169.254.169.254",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if !slicesEqual(cfg.AllowedCIDRs, []string{"10.20.0.0/16", "10.30.0.0/16"}) {
+			t.Errorf("AllowedCIDRs = %v, want [10.20.0.0/16 10.30.0.0/16]", cfg.AllowedCIDRs)
+		}
+		if !slicesEqual(cfg.DeniedCIDRs, []string{"169.254.169.254"}) {
+			t.Errorf("DeniedCIDRs = %v, want [169.254.169.254]", cfg.DeniedCIDRs)
+		}
+	})
+
+	t.Run("env var sets provenance policy", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_PROVENANCE_MODE":       "enforce",
+			"MCP_HELM_PROVENANCE_KEYRING":    "/keys/pubring.gpg",
+			"MCP_HELM_PROVENANCE_HOSTS":      "charts.example.com",
+			"MCP_HELM_PROVENANCE_SKIP_HOSTS": "a.com,b.com",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.ProvenanceMode != "enforce" {
+			t.Errorf("ProvenanceMode = %q, want %q", cfg.ProvenanceMode, "enforce")
+		}
+		if cfg.ProvenanceKeyring != "/keys/pubring.gpg" {
+			t.Errorf("ProvenanceKeyring = %q, want %q", cfg.ProvenanceKeyring, "/keys/pubring.gpg")
+		}
+		if !slicesEqual(cfg.ProvenanceHosts, []string{"charts.example.com"}) {
+			t.Errorf("ProvenanceHosts = %v, want [charts.example.com]", cfg.ProvenanceHosts)
+		}
+		if !slicesEqual(cfg.ProvenanceSkipHosts, []string{"a.com", "b.com"}) {
+			t.Errorf("ProvenanceSkipHosts = %v, want [a.com b.com]", cfg.ProvenanceSkipHosts)
+		}
+	})
+
+	t.Run("env var sets cosign policy", func(t *testing.T) {
+		cfg, err := load(nil, envFrom(map[string]string{
+			"MCP_HELM_COSIGN_MODE":       "annotate",
+			"MCP_HELM_COSIGN_KEYS":       "/keys/a.pub,/keys/b.pub",
+			"MCP_HELM_COSIGN_SKIP_HOSTS": "localhost",
+		}))
+		if err != nil {
+			t.Fatalf("load() error: %v", err)
+		}
+		if cfg.CosignMode != "annotate" {
+			t.Errorf("CosignMode = %q, want %q", cfg.CosignMode, "annotate")
+		}
+		if !slicesEqual(cfg.CosignKeys, []string{"/keys/a.pub", "/keys/b.pub"}) {
+			t.Errorf("CosignKeys = %v, want [/keys/a.pub /keys/b.pub]", cfg.CosignKeys)
+		}
+		if !slicesEqual(cfg.CosignSkipHosts, []string{"localhost"}) {
+			t.Errorf("CosignSkipHosts = %v, want [localhost]", cfg.CosignSkipHosts)
+		}
+	})
+
 	t.Run("env var sets log level", func(t *testing.T) {
 		cfg, err := load(nil, envFrom(map[string]string{
 			"MCP_HELM_LOG_LEVEL": "debug",
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/mcputil"
+)
+
+// Input/output types for cache administration tools
+
+type cacheStatusInput struct{}
+
+type cacheStatsInfo struct {
+	Entries  int     `json:"entries" jsonschema:"Number of cached entries"`
+	Bytes    int64   `json:"bytes" jsonschema:"Estimated memory held by the entries"`
+	Hits     uint64  `json:"hits" jsonschema:"Cache hits"`
+	Misses   uint64  `json:"misses" jsonschema:"Cache misses"`
+	HitRatio float64 `json:"hit_ratio" jsonschema:"Fraction of lookups that were hits"`
+}
+
+type cachedIndexInfo struct {
+	Repository string `json:"repository" jsonschema:"Repository URL"`
+	FetchedAt  string `json:"fetched_at" jsonschema:"When the index was last fetched (RFC3339)"`
+	AgeSeconds int64  `json:"age_seconds" jsonschema:"Age of the index in seconds"`
+	Expired    bool   `json:"expired,omitempty" jsonschema:"True if the index is past its cache TTL"`
+	Charts     int    `json:"charts" jsonschema:"Number of charts in the index"`
+	Bytes      int64  `json:"bytes" jsonschema:"Estimated memory held by the index"`
+}
+
+type cachedChartInfo struct {
+	Repository string `json:"repository" jsonschema:"Repository URL"`
+	Chart      string `json:"chart" jsonschema:"Chart name"`
+	Version    string `json:"version" jsonschema:"Chart version"`
+	Bytes      int64  `json:"bytes" jsonschema:"Estimated memory held by the chart"`
+}
+
+type cacheStatusOutput struct {
+	Indexes  cacheStatsInfo `json:"indexes" jsonschema:"Repository index cache statistics"`
+	Charts   cacheStatsInfo `json:"charts" jsonschema:"Chart cache statistics"`
+	Failures cacheStatsInfo `json:"failures" jsonschema:"Cached chart-not-found results and repository failures"`
+
+	Repositories []cachedIndexInfo `json:"repositories" jsonschema:"Cached repository indexes (most recently used first)"`
+	ChartEntries []cachedChartInfo `json:"chart_entries" jsonschema:"Cached chart versions (most recently used first)"`
+}
+
+type refreshRepositoryInput struct {
+	RepositoryURL string `json:"repository_url" jsonschema:"Helm repository URL (e.g. https://charts.bitnami.com/bitnami) or OCI registry (e.g. oci://ghcr.io/traefik/helm)"`
+}
+
+type refreshRepositoryOutput struct {
+	Repository string `json:"repository" jsonschema:"Repository URL"`
+	FetchedAt  string `json:"fetched_at,omitempty" jsonschema:"When the index was fetched (RFC3339); absent for OCI registries, which have no index"`
+	Charts     int    `json:"charts" jsonschema:"Number of charts in the refreshed index"`
+}
+
+// RegisterAdmin registers the cache administration tools with the MCP server.
+// It registers nothing if the service does not expose its caches.
+func (h *Handler) RegisterAdmin(s *mcp.Server) {
+	if h.cacheAdmin == nil {
+		return
+	}
+
+	// Inspect caches
+	mcputil.RegisterTool(s, mcputil.ToolDef{
+		Name:        "cache_status",
+		Description: "Show the server's cache statistics: hit ratios, cached repository indexes with their age, and cached chart versions.",
+		ReadOnly:    true,
+	}, h.cacheStatus())
+
+	// Force-refresh a repository
+	mcputil.RegisterTool(s, mcputil.ToolDef{
+		Name:        "refresh_repository",
+		Description: "Refetch a repository's index and forget its cached failures, e.g. after a chart was just published. For OCI registries (oci://) only cached failures are dropped.",
+		OpenWorld:   true,
+	}, h.refreshRepository())
+}
+
+// Handler implementations
+
+func (h *Handler) cacheStatus() mcp.ToolHandlerFor[cacheStatusInput, cacheStatusOutput] {
+	return func(_ context.Context, _ *mcp.CallToolRequest, _ cacheStatusInput) (*mcp.CallToolResult, cacheStatusOutput, error) {
+		status := h.cacheAdmin.CacheStatus()
+		now := time.Now()
+
+		out := cacheStatusOutput{
+			Indexes:      newCacheStatsInfo(status.Indexes),
+			Charts:       newCacheStatsInfo(status.Charts),
+			Failures:     newCacheStatsInfo(status.Failures),
+			Repositories: make([]cachedIndexInfo, 0, len(status.IndexEntries)),
+			ChartEntries: make([]cachedChartInfo, 0, len(status.ChartEntries)),
+		}
+		// Entries are listed least recently used first
+		for i := len(status.IndexEntries) - 1; i >= 0; i-- {
+			e := status.IndexEntries[i]
+			out.Repositories = append(out.Repositories, cachedIndexInfo{
+				Repository: e.Repository,
+				FetchedAt:  e.FetchedAt.UTC().Format(time.RFC3339),
+				AgeSeconds: int64(now.Sub(e.FetchedAt) / time.Second),
+				Expired:    e.Expired,
+				Charts:     e.Charts,
+				Bytes:      e.Bytes,
+			})
+		}
+		for i := len(status.ChartEntries) - 1; i >= 0; i-- {
+			e := status.ChartEntries[i]
+			out.ChartEntries = append(out.ChartEntries, cachedChartInfo{
+				Repository: e.Repository,
+				Chart:      e.Chart,
+				Version:    e.Version,
+				Bytes:      e.Bytes,
+			})
+		}
+
+		return nil, out, nil
+	}
+}
+
+func (h *Handler) refreshRepository() mcp.ToolHandlerFor[refreshRepositoryInput, refreshRepositoryOutput] {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, in refreshRepositoryInput) (*mcp.CallToolResult, refreshRepositoryOutput, error) {
+		if err := validateRequired(map[string]string{
+			"repository_url": in.RepositoryURL,
+		}); err != nil {
+			return mcputil.TextError(err.Error()), refreshRepositoryOutput{}, nil
+		}
+
+		repo := strings.TrimSpace(in.RepositoryURL)
+
+		index, err := h.cacheAdmin.RefreshRepository(ctx, repo)
+		if err != nil {
+			return mcputil.HandleOpError("refresh_repository", repo, "", "", err), refreshRepositoryOutput{}, nil
+		}
+
+		out := refreshRepositoryOutput{Repository: repo}
+		if index != nil {
+			out.FetchedAt = index.FetchedAt.UTC().Format(time.RFC3339)
+			out.Charts = index.Charts
+		}
+		return nil, out, nil
+	}
+}
+
+// newCacheStatsInfo converts helm.CacheStats to its output form.
+func newCacheStatsInfo(s helm.CacheStats) cacheStatsInfo {
+	return cacheStatsInfo{
+		Entries:  s.Size,
+		Bytes:    s.Bytes,
+		Hits:     s.Hits,
+		Misses:   s.Misses,
+		HitRatio: s.HitRatio(),
+	}
+}
@@ -7,8 +7,10 @@ import (
 
 	"github.com/goccy/go-yaml"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/mcputil"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/traceutil"
 )
 
 // Default pagination limits
@@ -36,6 +38,8 @@ type searchChartsInput struct {
 type searchChartsOutput struct {
 	Charts []string `json:"charts" jsonschema:"Chart names"`
 	Total  int      `json:"total" jsonschema:"Total matching charts (may exceed returned results if limit applied)"`
+
+	StaleIndex *staleIndexInfo `json:"stale_index,omitempty" jsonschema:"Present when results come from a repository index past its cache TTL"`
 }
 
 type getValuesInput struct {
@@ -56,6 +60,24 @@ type getValuesOutput struct {
 	Path      string `json:"path,omitempty" jsonschema:"Extracted path, if specified"`
 	Collapsed bool   `json:"collapsed,omitempty" jsonschema:"True if deep values were summarized — use a higher depth to expand"`
 	Schema    string `json:"schema,omitempty" jsonschema:"JSON Schema for values (if include_schema=true and schema exists)"`
+
+	Verification *verificationInfo `json:"verification,omitempty" jsonschema:"Chart signature verification result (if verification is configured)"`
+	StaleIndex   *staleIndexInfo   `json:"stale_index,omitempty" jsonschema:"Present when the latest version was resolved from a repository index past its cache TTL"`
+}
+
+type staleIndexInfo struct {
+	FetchedAt    string `json:"fetched_at" jsonschema:"When the repository index was last fetched (RFC3339)"`
+	AgeSeconds   int64  `json:"age_seconds" jsonschema:"Age of the repository index in seconds"`
+	Revalidating bool   `json:"revalidating,omitempty" jsonschema:"True if the index is being refreshed in the background"`
+	RefreshError string `json:"refresh_error,omitempty" jsonschema:"Why refreshing the index failed, if it did"`
+}
+
+type verificationInfo struct {
+	Method  string `json:"method" jsonschema:"Verification method: provenance or cosign"`
+	Status  string `json:"status" jsonschema:"Verification status: verified, unsigned, or invalid"`
+	Signer  string `json:"signer,omitempty" jsonschema:"Signer identity (if verified)"`
+	Digest  string `json:"digest,omitempty" jsonschema:"Verified chart archive or manifest digest"`
+	Message string `json:"message,omitempty" jsonschema:"Reason the chart is unsigned or invalid"`
 }
 
 type getDependenciesInput struct {
@@ -67,6 +89,9 @@ type getDependenciesInput struct {
 type getDependenciesOutput struct {
 	Version      string           `json:"version" jsonschema:"Resolved chart version (especially useful when chart_version was omitted and latest was used)"`
 	Dependencies []dependencyInfo `json:"dependencies" jsonschema:"Chart dependencies"`
+
+	Verification *verificationInfo `json:"verification,omitempty" jsonschema:"Chart signature verification result (if verification is configured)"`
+	StaleIndex   *staleIndexInfo   `json:"stale_index,omitempty" jsonschema:"Present when the latest version was resolved from a repository index past its cache TTL"`
 }
 
 type dependencyInfo struct {
@@ -86,6 +111,9 @@ type getNotesInput struct {
 type getNotesOutput struct {
 	Version string `json:"version" jsonschema:"Resolved chart version (especially useful when chart_version was omitted and latest was used)"`
 	Notes   string `json:"notes" jsonschema:"Contents of NOTES.txt"`
+
+	Verification *verificationInfo `json:"verification,omitempty" jsonschema:"Chart signature verification result (if verification is configured)"`
+	StaleIndex   *staleIndexInfo   `json:"stale_index,omitempty" jsonschema:"Present when the latest version was resolved from a repository index past its cache TTL"`
 }
 
 // Handler implementations
@@ -148,8 +176,9 @@ func (h *Handler) searchCharts() mcp.ToolHandlerFor[searchChartsInput, searchCha
 		}
 
 		return nil, searchChartsOutput{
-			Charts: charts,
-			Total:  total,
+			Charts:     charts,
+			Total:      total,
+			StaleIndex: h.staleIndex(ctx, strings.TrimSpace(in.RepositoryURL)),
 		}, nil
 	}
 }
@@ -218,18 +247,38 @@ func (h *Handler) getValues() mcp.ToolHandlerFor[getValuesInput, getValuesOutput
 			opts.ShowDefaults = *in.ShowDefaults
 		}
 
+		// Collapsing works on a parsed document, which is cached per chart
+		// version and path; unlimited depth uses the YAML text instead
+		var doc *parsedYAML
+		valuesKey := makeValuesKey(repo, chart, version, path)
+		if opts.MaxDepth > 0 {
+			doc, _ = h.values.Get(valuesKey, valuesBytes)
+		}
+
 		// If path is specified, extract that portion first
-		dataToProcess := valuesBytes
-		if path != "" {
-			extracted, extractErr := extractYAMLPath(valuesBytes, path)
-			if extractErr != nil {
-				// Provide actionable error message with chart context
-				if strings.Contains(extractErr.Error(), "path not found") {
-					return mcputil.TextError(fmt.Sprintf("path %q not found in %s/%s@%s values.yaml (try depth=1 to see available keys)", path, repo, chart, version)), getValuesOutput{}, nil
+		var dataToProcess []byte
+		if doc == nil {
+			dataToProcess = valuesBytes
+			if path != "" {
+				extracted, extractErr := extractYAMLPath(valuesBytes, path)
+				if extractErr != nil {
+					// Provide actionable error message with chart context
+					if strings.Contains(extractErr.Error(), "path not found") {
+						return mcputil.TextError(fmt.Sprintf("path %q not found in %s/%s@%s values.yaml (try depth=1 to see available keys)", path, repo, chart, version)), getValuesOutput{}, nil
+					}
+					return mcputil.TextError(fmt.Sprintf("invalid path syntax %q in %s/%s@%s: %v", path, repo, chart, version, extractErr)), getValuesOutput{}, nil
+				}
+				dataToProcess = []byte(extracted)
+			}
+			if opts.MaxDepth > 0 {
+				_, span := traceutil.StartSpan(ctx, tracerName, "handler.parseYAML", attribute.Int("mcp_helm.values.bytes", len(dataToProcess)))
+				doc, err = parseYAML(dataToProcess)
+				traceutil.EndSpan(span, err)
+				if err != nil {
+					return mcputil.TextError(fmt.Sprintf("processing values: %v", err)), getValuesOutput{}, nil
 				}
-				return mcputil.TextError(fmt.Sprintf("invalid path syntax %q in %s/%s@%s: %v", path, repo, chart, version, extractErr)), getValuesOutput{}, nil
+				h.values.Put(valuesKey, valuesBytes, doc)
 			}
-			dataToProcess = []byte(extracted)
 		}
 
 		// Fetch schema early so we can account for its size
@@ -249,19 +298,31 @@ func (h *Handler) getValues() mcp.ToolHandlerFor[getValuesInput, getValuesOutput
 			}
 		}
 
-		// Apply collapse transformation, auto-reducing depth if output exceeds limit
-		result, collapsed, err := CollapseYAML(dataToProcess, opts)
-		if err != nil {
-			return mcputil.TextError(fmt.Sprintf("processing values: %v", err)), getValuesOutput{}, nil
-		}
-
-		for len(result)+len(schemaStr) > MaxResponseBytes && opts.MaxDepth > 1 {
-			opts.MaxDepth--
+		// Apply collapse transformation, reducing depth to the largest that
+		// fits if output exceeds limit
+		var (
+			result    string
+			collapsed bool
+		)
+		_, span := traceutil.StartSpan(ctx, tracerName, "handler.CollapseYAML", attribute.Int("mcp_helm.values.depth", opts.MaxDepth))
+		if doc == nil {
 			result, collapsed, err = CollapseYAML(dataToProcess, opts)
 			if err != nil {
+				traceutil.EndSpan(span, err)
 				return mcputil.TextError(fmt.Sprintf("processing values: %v", err)), getValuesOutput{}, nil
 			}
+		} else {
+			result, collapsed = doc.collapse(opts)
+			if len(result)+len(schemaStr) > MaxResponseBytes && opts.MaxDepth > 1 {
+				opts.MaxDepth = doc.fitDepth(opts, MaxResponseBytes-len(schemaStr))
+				result, collapsed = doc.collapse(opts)
+			}
 		}
+		span.SetAttributes(
+			attribute.Int("mcp_helm.values.fitted_depth", opts.MaxDepth),
+			attribute.Int("mcp_helm.values.output_bytes", len(result)),
+		)
+		traceutil.EndSpan(span, nil)
 
 		// If output still exceeds limit at minimum depth, return an actionable error
 		if len(result)+len(schemaStr) > MaxResponseBytes {
@@ -272,11 +333,13 @@ func (h *Handler) getValues() mcp.ToolHandlerFor[getValuesInput, getValuesOutput
 		}
 
 		output := getValuesOutput{
-			Version:   version,
-			Values:    result,
-			Path:      path,
-			Collapsed: collapsed,
-			Schema:    schemaStr,
+			Version:      version,
+			Values:       result,
+			Path:         path,
+			Collapsed:    collapsed,
+			Schema:       schemaStr,
+			Verification: h.verification(ctx, repo, chart, version),
+			StaleIndex:   h.resolvedStaleIndex(ctx, repo, in.ChartVersion),
 		}
 
 		return nil, output, nil
@@ -319,7 +382,12 @@ func (h *Handler) getDependencies() mcp.ToolHandlerFor[getDependenciesInput, get
 			})
 		}
 
-		return nil, getDependenciesOutput{Version: version, Dependencies: result}, nil
+		return nil, getDependenciesOutput{
+			Version:      version,
+			Dependencies: result,
+			Verification: h.verification(ctx, repo, chart, version),
+			StaleIndex:   h.resolvedStaleIndex(ctx, repo, in.ChartVersion),
+		}, nil
 	}
 }
 
@@ -361,8 +429,10 @@ func (h *Handler) getNotes() mcp.ToolHandlerFor[getNotesInput, getNotesOutput] {
 		}
 
 		return nil, getNotesOutput{
-			Version: version,
-			Notes:   string(notes),
+			Version:      version,
+			Notes:        string(notes),
+			Verification: h.verification(ctx, repo, chart, version),
+			StaleIndex:   h.resolvedStaleIndex(ctx, repo, in.ChartVersion),
 		}, nil
 	}
 }
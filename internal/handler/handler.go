@@ -7,6 +7,7 @@ import (
 	"maps"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
@@ -15,23 +16,57 @@ import (
 	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/mcputil"
 )
 
+// tracerName names the tracer of the handler's spans.
+const tracerName = "github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/handler"
+
 // Handler provides MCP tool handlers backed by a Helm service.
 type Handler struct {
-	svc    helm.ChartService
-	logger *zap.Logger
+	svc         helm.ChartService
+	verifier    helm.ChartVerifier       // nil if svc does not verify chart signatures
+	indexStatus helm.IndexStatusReporter // nil if svc does not report stale indexes
+	cacheAdmin  helm.CacheAdmin          // nil if svc does not expose its caches
+	values      *valuesCache             // nil if parsed values are not cached
+	logger      *zap.Logger
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithValuesCacheSize sets how many parsed values documents get_values keeps,
+// keyed by chart version and path. Zero disables the cache. Default is 50.
+func WithValuesCacheSize(n int) Option {
+	return func(h *Handler) {
+		h.values = newValuesCache(n)
+	}
 }
 
 // New creates a new Handler.
-func New(svc helm.ChartService, logger *zap.Logger) *Handler {
+func New(svc helm.ChartService, logger *zap.Logger, opts ...Option) *Handler {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
-	return &Handler{
-		svc:    svc,
-		logger: logger,
+	verifier, _ := svc.(helm.ChartVerifier)
+	indexStatus, _ := svc.(helm.IndexStatusReporter)
+	cacheAdmin, _ := svc.(helm.CacheAdmin)
+	h := &Handler{
+		svc:         svc,
+		verifier:    verifier,
+		indexStatus: indexStatus,
+		cacheAdmin:  cacheAdmin,
+		values:      newValuesCache(defaultValuesCacheSize),
+		logger:      logger,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
+// DownloadTools are the tools that may download a chart archive, or with
+// refresh_repository a repository index, on every call. The others are
+// usually answered from cached indexes.
+var DownloadTools = []string{"get_values", "get_dependencies", "get_notes", "refresh_repository"}
+
 // Register registers all Helm tools with the MCP server.
 func (h *Handler) Register(s *mcp.Server) {
 	// Search for charts in a repository
@@ -95,3 +130,96 @@ func validateRequired(fields map[string]string) error {
 	}
 	return nil
 }
+
+// verification returns the signature verification result for a chart version,
+// or nil if the service does not verify charts or no policy applies.
+// Failures are logged rather than returned so they never block the tool result.
+func (h *Handler) verification(ctx context.Context, repo, chart, version string) *verificationInfo {
+	if h.verifier == nil {
+		return nil
+	}
+	v, err := h.verifier.VerifyChart(ctx, repo, chart, version)
+	if err != nil {
+		h.logger.Warn("failed to get chart verification",
+			zap.String("repository", repo),
+			zap.String("chart", chart),
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return nil
+	}
+	return newVerificationInfo(v)
+}
+
+// versionVerifications returns verification results for the listed versions,
+// keyed by version. Like verification, failures are logged and yield nil.
+func (h *Handler) versionVerifications(ctx context.Context, repo, chart string, versions []string) map[string]*verificationInfo {
+	if h.verifier == nil || len(versions) == 0 {
+		return nil
+	}
+	results, err := h.verifier.VerifyVersions(ctx, repo, chart, versions)
+	if err != nil {
+		h.logger.Warn("failed to get chart version verifications",
+			zap.String("repository", repo),
+			zap.String("chart", chart),
+			zap.Error(err),
+		)
+		return nil
+	}
+	infos := make(map[string]*verificationInfo, len(results))
+	for version, v := range results {
+		if info := newVerificationInfo(v); info != nil {
+			infos[version] = info
+		}
+	}
+	return infos
+}
+
+// newVerificationInfo converts a helm.Verification to its output form.
+func newVerificationInfo(v *helm.Verification) *verificationInfo {
+	if v == nil {
+		return nil
+	}
+	return &verificationInfo{
+		Method:  v.Method,
+		Status:  string(v.Status),
+		Signer:  v.Signer,
+		Digest:  v.Digest,
+		Message: v.Message,
+	}
+}
+
+// staleIndex reports whether results for the repository came from an index
+// past its cache TTL, or returns nil if the index is fresh. Like verification,
+// failures are logged rather than returned.
+func (h *Handler) staleIndex(ctx context.Context, repo string) *staleIndexInfo {
+	if h.indexStatus == nil {
+		return nil
+	}
+	status, err := h.indexStatus.IndexStatus(ctx, repo)
+	if err != nil {
+		h.logger.Warn("failed to get repository index status",
+			zap.String("repository", repo),
+			zap.Error(err),
+		)
+		return nil
+	}
+	if status == nil {
+		return nil
+	}
+	return &staleIndexInfo{
+		FetchedAt:    status.FetchedAt.UTC().Format(time.RFC3339),
+		AgeSeconds:   int64(status.Age / time.Second),
+		Revalidating: status.Revalidating,
+		RefreshError: status.RefreshError,
+	}
+}
+
+// resolvedStaleIndex is staleIndex for tools that take an optional version:
+// the index only matters when the latest version was resolved from it.
+func (h *Handler) resolvedStaleIndex(ctx context.Context, repo, requestedVersion string) *staleIndexInfo {
+	if strings.TrimSpace(requestedVersion) != "" {
+		return nil
+	}
+	return h.staleIndex(ctx, repo)
+}
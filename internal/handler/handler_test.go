@@ -9,6 +9,10 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap"
 
 	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
@@ -784,6 +788,151 @@ value: 123`
 		// Verify the error message suggests using path parameter
 		assert.Contains(t, fmt.Sprintf("%v", result.Content[0]), "path")
 	})
+
+	t.Run("auto-reduces to the largest depth that fits", func(t *testing.T) {
+		var sb strings.Builder
+		for i := 0; i < 150; i++ {
+			fmt.Fprintf(&sb, "section%d:\n", i)
+			for j := 0; j < 4; j++ {
+				fmt.Fprintf(&sb, "  key%d:\n", j)
+				for k := 0; k < 4; k++ {
+					fmt.Fprintf(&sb, "    sub%d:\n      leaf: value%d\n", k, k)
+				}
+			}
+		}
+		bigYAML := []byte(sb.String())
+
+		// Find the expected depth by rendering every depth
+		want := ""
+		for depth := 4; depth >= 1; depth-- {
+			opts := DefaultCollapseOptions()
+			opts.MaxDepth = depth
+			out, _, err := CollapseYAML(bigYAML, opts)
+			assert.NoError(t, err)
+			if len(out) <= MaxResponseBytes {
+				want = out
+				break
+			}
+		}
+		assert.NotEmpty(t, want)
+
+		mockSvc := new(mocks.ChartService)
+		mockSvc.On("GetValues", ctx, "https://repo.com", "big", "1.0.0").Return(bigYAML, nil)
+
+		depth := 4
+		result, output, err := New(mockSvc, zap.NewNop()).getValues()(ctx, nil, getValuesInput{
+			RepositoryURL: "https://repo.com",
+			ChartName:     "big",
+			ChartVersion:  "1.0.0",
+			Depth:         &depth,
+		})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, want, output.Values)
+	})
+}
+
+func TestGetValues_ValuesCache(t *testing.T) {
+	ctx := context.Background()
+	values := []byte("# -- Replicas\nreplicaCount: 1\nimage:\n  repository: nginx\n  tag: latest\n")
+	input := getValuesInput{RepositoryURL: "https://repo.com", ChartName: "nginx", ChartVersion: "1.0.0"}
+
+	t.Run("parsed values are reused across calls and options", func(t *testing.T) {
+		mockSvc := new(mocks.ChartService)
+		mockSvc.On("GetValues", ctx, "https://repo.com", "nginx", "1.0.0").Return(values, nil)
+		h := New(mockSvc, zap.NewNop())
+
+		_, first, err := h.getValues()(ctx, nil, input)
+		assert.NoError(t, err)
+		key := makeValuesKey("https://repo.com", "nginx", "1.0.0", "")
+		doc, ok := h.values.Get(key, values)
+		assert.True(t, ok)
+
+		withComments := input
+		showComments := true
+		withComments.ShowComments = &showComments
+		_, second, err := h.getValues()(ctx, nil, withComments)
+		assert.NoError(t, err)
+		assert.NotContains(t, first.Values, "# Replicas")
+		assert.Contains(t, second.Values, "# Replicas")
+
+		cached, _ := h.values.Get(key, values)
+		assert.Same(t, doc, cached)
+		assert.Equal(t, 1, h.values.Len())
+	})
+
+	t.Run("paths are cached separately", func(t *testing.T) {
+		mockSvc := new(mocks.ChartService)
+		mockSvc.On("GetValues", ctx, "https://repo.com", "nginx", "1.0.0").Return(values, nil)
+		h := New(mockSvc, zap.NewNop())
+
+		_, whole, err := h.getValues()(ctx, nil, input)
+		assert.NoError(t, err)
+
+		withPath := input
+		withPath.Path = ".image"
+		for range 2 {
+			_, output, err := h.getValues()(ctx, nil, withPath)
+			assert.NoError(t, err)
+			assert.Equal(t, "repository: nginx\ntag: latest", output.Values)
+		}
+		assert.Contains(t, whole.Values, "replicaCount: 1")
+		assert.Equal(t, 2, h.values.Len())
+	})
+
+	t.Run("changed values are parsed again", func(t *testing.T) {
+		mockSvc := new(mocks.ChartService)
+		mockSvc.On("GetValues", ctx, "https://repo.com", "nginx", "1.0.0").Return(values, nil).Once()
+		mockSvc.On("GetValues", ctx, "https://repo.com", "nginx", "1.0.0").Return([]byte("replicaCount: 2\n"), nil).Once()
+		h := New(mockSvc, zap.NewNop())
+
+		_, _, err := h.getValues()(ctx, nil, input)
+		assert.NoError(t, err)
+		_, output, err := h.getValues()(ctx, nil, input)
+		assert.NoError(t, err)
+		assert.Equal(t, "replicaCount: 2", output.Values)
+	})
+
+	t.Run("zero size disables the cache", func(t *testing.T) {
+		mockSvc := new(mocks.ChartService)
+		mockSvc.On("GetValues", ctx, "https://repo.com", "nginx", "1.0.0").Return(values, nil)
+		h := New(mockSvc, zap.NewNop(), WithValuesCacheSize(0))
+
+		_, output, err := h.getValues()(ctx, nil, input)
+		assert.NoError(t, err)
+		assert.Contains(t, output.Values, "replicaCount: 1")
+		assert.Zero(t, h.values.Len())
+	})
+}
+
+func TestGetValues_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	values := []byte("replicaCount: 1\nimage:\n  repository: nginx\n")
+	mockSvc := new(mocks.ChartService)
+	mockSvc.On("GetValues", ctx, "https://repo.com", "nginx", "1.0.0").Return(values, nil)
+	h := New(mockSvc, zap.NewNop())
+
+	_, output, err := h.getValues()(ctx, nil, getValuesInput{RepositoryURL: "https://repo.com", ChartName: "nginx", ChartVersion: "1.0.0"})
+	assert.NoError(t, err)
+	root.End()
+
+	names := make(map[string]tracetest.SpanStub)
+	for _, s := range exporter.GetSpans() {
+		names[s.Name] = s
+	}
+	for _, name := range []string{"handler.parseYAML", "handler.CollapseYAML"} {
+		span, ok := names[name]
+		if assert.True(t, ok, "span %q not recorded", name) {
+			assert.Equal(t, root.SpanContext().SpanID(), span.Parent.SpanID())
+		}
+	}
+	assert.Contains(t, names["handler.CollapseYAML"].Attributes, attribute.Int("mcp_helm.values.output_bytes", len(output.Values)))
 }
 
 func TestGetDependencies(t *testing.T) {
@@ -956,3 +1105,323 @@ func TestGetNotes(t *testing.T) {
 		assert.True(t, result.IsError)
 	})
 }
+
+// verifyingService combines the ChartService and ChartVerifier mocks.
+type verifyingService struct {
+	*mocks.ChartService
+	*mocks.ChartVerifier
+}
+
+func TestVerificationAnnotation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("service without verifier omits verification", func(t *testing.T) {
+		mockSvc := new(mocks.ChartService)
+		mockSvc.On("GetValues", ctx, "https://repo.com", "nginx", "1.0.0").
+			Return([]byte("replicaCount: 1"), nil)
+
+		h := New(mockSvc, zap.NewNop())
+		result, output, err := h.getValues()(ctx, nil, getValuesInput{
+			RepositoryURL: "https://repo.com",
+			ChartName:     "nginx",
+			ChartVersion:  "1.0.0",
+		})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		assert.Nil(t, h.verifier)
+		assert.Nil(t, output.Verification)
+	})
+
+	t.Run("get_values includes verified signer", func(t *testing.T) {
+		svc := verifyingService{new(mocks.ChartService), new(mocks.ChartVerifier)}
+		svc.ChartService.On("GetValues", ctx, "https://repo.com", "nginx", "1.0.0").
+			Return([]byte("replicaCount: 1"), nil)
+		svc.ChartVerifier.On("VerifyChart", ctx, "https://repo.com", "nginx", "1.0.0").
+			Return(&helm.Verification{
+				Method: helm.MethodProvenance,
+				Status: helm.StatusVerified,
+				Signer: "Chart Publisher <publisher@example.com>",
+				Digest: "sha256:abc",
+			}, nil)
+
+		h := New(svc, zap.NewNop())
+		result, output, err := h.getValues()(ctx, nil, getValuesInput{
+			RepositoryURL: "https://repo.com",
+			ChartName:     "nginx",
+			ChartVersion:  "1.0.0",
+		})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		if assert.NotNil(t, output.Verification) {
+			assert.Equal(t, "verified", output.Verification.Status)
+			assert.Equal(t, "provenance", output.Verification.Method)
+			assert.Equal(t, "Chart Publisher <publisher@example.com>", output.Verification.Signer)
+			assert.Equal(t, "sha256:abc", output.Verification.Digest)
+		}
+	})
+
+	t.Run("get_notes includes unsigned status", func(t *testing.T) {
+		svc := verifyingService{new(mocks.ChartService), new(mocks.ChartVerifier)}
+		svc.ChartService.On("GetNotes", ctx, "https://repo.com", "nginx", "1.0.0").
+			Return([]byte("Thanks!"), true, nil)
+		svc.ChartVerifier.On("VerifyChart", ctx, "https://repo.com", "nginx", "1.0.0").
+			Return(&helm.Verification{
+				Method:  helm.MethodProvenance,
+				Status:  helm.StatusUnsigned,
+				Message: "no provenance file published for chart",
+			}, nil)
+
+		h := New(svc, zap.NewNop())
+		result, output, err := h.getNotes()(ctx, nil, getNotesInput{
+			RepositoryURL: "https://repo.com",
+			ChartName:     "nginx",
+			ChartVersion:  "1.0.0",
+		})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		if assert.NotNil(t, output.Verification) {
+			assert.Equal(t, "unsigned", output.Verification.Status)
+			assert.Contains(t, output.Verification.Message, "no provenance file")
+		}
+	})
+
+	t.Run("verifier error does not fail get_dependencies", func(t *testing.T) {
+		svc := verifyingService{new(mocks.ChartService), new(mocks.ChartVerifier)}
+		svc.ChartService.On("GetDependencies", ctx, "https://repo.com", "app", "1.0.0").
+			Return([]helm.Dependency{{Name: "redis", Version: "17.x"}}, nil)
+		svc.ChartVerifier.On("VerifyChart", ctx, "https://repo.com", "app", "1.0.0").
+			Return(nil, errors.New("network error"))
+
+		h := New(svc, zap.NewNop())
+		result, output, err := h.getDependencies()(ctx, nil, getDependenciesInput{
+			RepositoryURL: "https://repo.com",
+			ChartName:     "app",
+			ChartVersion:  "1.0.0",
+		})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		assert.Len(t, output.Dependencies, 1)
+		assert.Nil(t, output.Verification)
+	})
+	t.Run("get_versions annotates returned versions", func(t *testing.T) {
+		svc := verifyingService{new(mocks.ChartService), new(mocks.ChartVerifier)}
+		svc.ChartService.On("ListVersions", ctx, "oci://ghcr.io/org/charts", "app").
+			Return([]helm.ChartVersion{{Version: "2.0.0"}, {Version: "1.0.0"}, {Version: "0.9.0"}}, nil)
+		svc.ChartVerifier.On("VerifyVersions", ctx, "oci://ghcr.io/org/charts", "app", []string{"2.0.0", "1.0.0"}).
+			Return(map[string]*helm.Verification{
+				"2.0.0": {Method: helm.MethodCosign, Status: helm.StatusVerified, Signer: "cosign.pub", Digest: "sha256:abc"},
+				"1.0.0": {Method: helm.MethodCosign, Status: helm.StatusUnsigned},
+			}, nil)
+
+		h := New(svc, zap.NewNop())
+		result, output, err := h.getVersions()(ctx, nil, getVersionsInput{
+			RepositoryURL: "oci://ghcr.io/org/charts",
+			ChartName:     "app",
+			Limit:         2,
+		})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		if !assert.Len(t, output.Versions, 2) {
+			return
+		}
+		if assert.NotNil(t, output.Versions[0].Verification) {
+			assert.Equal(t, "cosign", output.Versions[0].Verification.Method)
+			assert.Equal(t, "verified", output.Versions[0].Verification.Status)
+			assert.Equal(t, "cosign.pub", output.Versions[0].Verification.Signer)
+		}
+		if assert.NotNil(t, output.Versions[1].Verification) {
+			assert.Equal(t, "unsigned", output.Versions[1].Verification.Status)
+		}
+		svc.ChartVerifier.AssertExpectations(t)
+	})
+}
+
+// indexStatusService combines the ChartService and IndexStatusReporter mocks.
+type indexStatusService struct {
+	*mocks.ChartService
+	*mocks.IndexStatusReporter
+}
+
+func TestStaleIndexAnnotation(t *testing.T) {
+	ctx := context.Background()
+	fetchedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	stale := &helm.IndexStatus{
+		FetchedAt:    fetchedAt,
+		Age:          90 * time.Minute,
+		Revalidating: true,
+		RefreshError: "repository error: connection refused",
+	}
+
+	t.Run("search_charts reports stale index", func(t *testing.T) {
+		svc := indexStatusService{new(mocks.ChartService), new(mocks.IndexStatusReporter)}
+		svc.ChartService.On("ListCharts", ctx, "https://repo.com").Return([]string{"nginx"}, nil)
+		svc.IndexStatusReporter.On("IndexStatus", ctx, "https://repo.com").Return(stale, nil)
+
+		h := New(svc, zap.NewNop())
+		result, output, err := h.searchCharts()(ctx, nil, searchChartsInput{RepositoryURL: "https://repo.com"})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		if assert.NotNil(t, output.StaleIndex) {
+			assert.Equal(t, "2026-01-01T12:00:00Z", output.StaleIndex.FetchedAt)
+			assert.Equal(t, int64(5400), output.StaleIndex.AgeSeconds)
+			assert.True(t, output.StaleIndex.Revalidating)
+			assert.Contains(t, output.StaleIndex.RefreshError, "connection refused")
+		}
+	})
+
+	t.Run("fresh index is omitted", func(t *testing.T) {
+		svc := indexStatusService{new(mocks.ChartService), new(mocks.IndexStatusReporter)}
+		svc.ChartService.On("ListVersions", ctx, "https://repo.com", "nginx").
+			Return([]helm.ChartVersion{{Version: "1.0.0"}}, nil)
+		svc.IndexStatusReporter.On("IndexStatus", ctx, "https://repo.com").Return(nil, nil)
+
+		h := New(svc, zap.NewNop())
+		_, output, err := h.getVersions()(ctx, nil, getVersionsInput{RepositoryURL: "https://repo.com", ChartName: "nginx"})
+
+		assert.NoError(t, err)
+		assert.Nil(t, output.StaleIndex)
+	})
+
+	t.Run("get_values reports stale index only for resolved versions", func(t *testing.T) {
+		svc := indexStatusService{new(mocks.ChartService), new(mocks.IndexStatusReporter)}
+		svc.ChartService.On("GetLatestVersion", ctx, "https://repo.com", "nginx").Return("1.0.0", nil)
+		svc.ChartService.On("GetValues", ctx, "https://repo.com", "nginx", "1.0.0").
+			Return([]byte("replicaCount: 1"), nil)
+		svc.IndexStatusReporter.On("IndexStatus", ctx, "https://repo.com").Return(stale, nil).Once()
+
+		h := New(svc, zap.NewNop())
+		_, output, err := h.getValues()(ctx, nil, getValuesInput{RepositoryURL: "https://repo.com", ChartName: "nginx"})
+		assert.NoError(t, err)
+		assert.NotNil(t, output.StaleIndex)
+
+		_, output, err = h.getValues()(ctx, nil, getValuesInput{RepositoryURL: "https://repo.com", ChartName: "nginx", ChartVersion: "1.0.0"})
+		assert.NoError(t, err)
+		assert.Nil(t, output.StaleIndex)
+		svc.IndexStatusReporter.AssertExpectations(t)
+	})
+
+	t.Run("status error does not fail the tool", func(t *testing.T) {
+		svc := indexStatusService{new(mocks.ChartService), new(mocks.IndexStatusReporter)}
+		svc.ChartService.On("ListCharts", ctx, "https://repo.com").Return([]string{"nginx"}, nil)
+		svc.IndexStatusReporter.On("IndexStatus", ctx, "https://repo.com").Return(nil, errors.New("dns failure"))
+
+		h := New(svc, zap.NewNop())
+		result, output, err := h.searchCharts()(ctx, nil, searchChartsInput{RepositoryURL: "https://repo.com"})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		assert.Len(t, output.Charts, 1)
+		assert.Nil(t, output.StaleIndex)
+	})
+}
+
+// cacheAdminService combines the ChartService and CacheAdmin mocks.
+type cacheAdminService struct {
+	*mocks.ChartService
+	*mocks.CacheAdmin
+}
+
+func TestCacheStatus(t *testing.T) {
+	ctx := context.Background()
+	fetchedAt := time.Now().Add(-90 * time.Second)
+
+	svc := cacheAdminService{new(mocks.ChartService), new(mocks.CacheAdmin)}
+	svc.CacheAdmin.On("CacheStatus").Return(&helm.CacheStatus{
+		Indexes: helm.CacheStats{Size: 2, Bytes: 2048, Hits: 3, Misses: 1},
+		Charts:  helm.CacheStats{Size: 1, Bytes: 512},
+		IndexEntries: []helm.CachedIndex{
+			{Repository: "https://old.com", FetchedAt: fetchedAt, Expired: true, Charts: 1, Bytes: 1024},
+			{Repository: "https://new.com", FetchedAt: fetchedAt, Charts: 2, Bytes: 1024},
+		},
+		ChartEntries: []helm.CachedChart{
+			{Repository: "https://new.com", Chart: "nginx", Version: "1.0.0", Bytes: 512},
+		},
+	})
+
+	h := New(svc, zap.NewNop())
+	result, output, err := h.cacheStatus()(ctx, nil, cacheStatusInput{})
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Equal(t, 2, output.Indexes.Entries)
+	assert.InDelta(t, 0.75, output.Indexes.HitRatio, 1e-9)
+	assert.Zero(t, output.Charts.HitRatio)
+	if assert.Len(t, output.Repositories, 2) {
+		// Most recently used first
+		assert.Equal(t, "https://new.com", output.Repositories[0].Repository)
+		assert.Equal(t, "https://old.com", output.Repositories[1].Repository)
+		assert.True(t, output.Repositories[1].Expired)
+		assert.InDelta(t, 90, output.Repositories[0].AgeSeconds, 2)
+	}
+	if assert.Len(t, output.ChartEntries, 1) {
+		assert.Equal(t, "nginx", output.ChartEntries[0].Chart)
+	}
+}
+
+func TestRefreshRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns refreshed index", func(t *testing.T) {
+		svc := cacheAdminService{new(mocks.ChartService), new(mocks.CacheAdmin)}
+		fetchedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		svc.CacheAdmin.On("RefreshRepository", ctx, "https://repo.com").
+			Return(&helm.CachedIndex{Repository: "https://repo.com", FetchedAt: fetchedAt, Charts: 3}, nil)
+
+		h := New(svc, zap.NewNop())
+		result, output, err := h.refreshRepository()(ctx, nil, refreshRepositoryInput{RepositoryURL: " https://repo.com "})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "https://repo.com", output.Repository)
+		assert.Equal(t, "2026-01-01T12:00:00Z", output.FetchedAt)
+		assert.Equal(t, 3, output.Charts)
+	})
+
+	t.Run("OCI registry has no index", func(t *testing.T) {
+		svc := cacheAdminService{new(mocks.ChartService), new(mocks.CacheAdmin)}
+		svc.CacheAdmin.On("RefreshRepository", ctx, "oci://ghcr.io/org").Return(nil, nil)
+
+		h := New(svc, zap.NewNop())
+		result, output, err := h.refreshRepository()(ctx, nil, refreshRepositoryInput{RepositoryURL: "oci://ghcr.io/org"})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		assert.Empty(t, output.FetchedAt)
+	})
+
+	t.Run("missing repository", func(t *testing.T) {
+		svc := cacheAdminService{new(mocks.ChartService), new(mocks.CacheAdmin)}
+
+		h := New(svc, zap.NewNop())
+		result, _, err := h.refreshRepository()(ctx, nil, refreshRepositoryInput{})
+
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+		svc.CacheAdmin.AssertNotCalled(t, "RefreshRepository")
+	})
+
+	t.Run("refresh error", func(t *testing.T) {
+		svc := cacheAdminService{new(mocks.ChartService), new(mocks.CacheAdmin)}
+		svc.CacheAdmin.On("RefreshRepository", ctx, "https://repo.com").
+			Return(nil, &helm.RepositoryError{URL: "https://repo.com", Err: errors.New("connection refused")})
+
+		h := New(svc, zap.NewNop())
+		result, _, err := h.refreshRepository()(ctx, nil, refreshRepositoryInput{RepositoryURL: "https://repo.com"})
+
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
+
+func TestRegisterAdmin_WithoutCacheAdmin(t *testing.T) {
+	h := New(new(mocks.ChartService), zap.NewNop())
+	assert.Nil(t, h.cacheAdmin)
+	// Registers nothing; must not panic on a nil server
+	h.RegisterAdmin(nil)
+}
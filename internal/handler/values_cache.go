@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"bytes"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// defaultValuesCacheSize matches the default size of the chart cache.
+const defaultValuesCacheSize = 50
+
+// valuesCache caches parsed values documents per chart version and path, so
+// that repeated get_values calls neither re-parse values.yaml nor re-extract
+// its comments. Thread-safe.
+//
+// Entries remember the values.yaml they were parsed from and are only served
+// for identical content, so a chart republished under the same version is
+// parsed again.
+type valuesCache struct {
+	cache *lru.Cache[string, *valuesEntry]
+}
+
+// valuesEntry is a parsed values document and the values.yaml it came from.
+type valuesEntry struct {
+	source []byte
+	doc    *parsedYAML
+}
+
+// newValuesCache creates a values cache holding at most size documents.
+// A non-positive size disables caching.
+func newValuesCache(size int) *valuesCache {
+	if size <= 0 {
+		return nil
+	}
+	cache, _ := lru.New[string, *valuesEntry](size)
+	return &valuesCache{cache: cache}
+}
+
+// Get returns the document parsed for a chart version and path if it was
+// parsed from source. Safe to call on a nil cache.
+func (c *valuesCache) Get(key string, source []byte) (*parsedYAML, bool) {
+	if c == nil {
+		return nil, false
+	}
+	e, ok := c.cache.Get(key)
+	if !ok || !bytes.Equal(e.source, source) {
+		return nil, false
+	}
+	return e.doc, true
+}
+
+// Put stores the document parsed for a chart version and path from source.
+// Safe to call on a nil cache.
+func (c *valuesCache) Put(key string, source []byte, doc *parsedYAML) {
+	if c == nil {
+		return
+	}
+	c.cache.Add(key, &valuesEntry{source: source, doc: doc})
+}
+
+// Len returns the number of cached documents.
+func (c *valuesCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	return c.cache.Len()
+}
+
+// makeValuesKey builds a cache key from a chart version and values path.
+// NUL cannot occur in URLs, chart names or versions, so keys do not collide.
+func makeValuesKey(repo, chart, version, path string) string {
+	return strings.Join([]string{repo, chart, version, path}, "\x00")
+}
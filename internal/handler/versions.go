@@ -25,11 +25,15 @@ type versionInfo struct {
 	AppVersion string `json:"app_version,omitempty" jsonschema:"Application version"`
 	Created    string `json:"created,omitempty" jsonschema:"Creation timestamp (RFC3339)"`
 	Deprecated bool   `json:"deprecated" jsonschema:"Whether the version is deprecated"`
+
+	Verification *verificationInfo `json:"verification,omitempty" jsonschema:"Chart signature verification result (if verification is configured)"`
 }
 
 type getVersionsOutput struct {
 	Versions []versionInfo `json:"versions" jsonschema:"Chart versions (newest first)"`
 	Total    int           `json:"total" jsonschema:"Total versions available (may exceed returned results if limit applied)"`
+
+	StaleIndex *staleIndexInfo `json:"stale_index,omitempty" jsonschema:"Present when results come from a repository index past its cache TTL"`
 }
 
 // Handler implementations
@@ -72,6 +76,12 @@ func (h *Handler) getVersions() mcp.ToolHandlerFor[getVersionsInput, getVersions
 			versions = versions[:limit]
 		}
 
+		names := make([]string, 0, len(versions))
+		for _, v := range versions {
+			names = append(names, v.Version)
+		}
+		verifications := h.versionVerifications(ctx, repo, chart, names)
+
 		// Convert to output format
 		result := make([]versionInfo, 0, len(versions))
 		for _, v := range versions {
@@ -84,12 +94,15 @@ func (h *Handler) getVersions() mcp.ToolHandlerFor[getVersionsInput, getVersions
 				AppVersion: v.AppVersion,
 				Created:    created,
 				Deprecated: v.Deprecated,
+
+				Verification: verifications[v.Version],
 			})
 		}
 
 		return nil, getVersionsOutput{
-			Versions: result,
-			Total:    total,
+			Versions:   result,
+			Total:      total,
+			StaleIndex: h.staleIndex(ctx, repo),
 		}, nil
 	}
 }
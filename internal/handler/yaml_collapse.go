@@ -61,6 +61,30 @@ type orderedMap struct {
 	entries []orderedEntry
 }
 
+// parsedYAML is a values document parsed once into an ordered tree, so that
+// it can be collapsed repeatedly with different options.
+type parsedYAML struct {
+	root     interface{}       // nil for an empty document
+	comments map[string]string // First comment line keyed by full dotted path
+	size     int               // Length of the source YAML
+}
+
+// parseYAML parses YAML into an ordered tree and extracts its comments.
+func parseYAML(data []byte) (*parsedYAML, error) {
+	// Parse YAML AST to preserve key order and extract comments
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing YAML: %w", err)
+	}
+
+	// Build ordered tree from AST (use first document only; Helm values.yaml is single-document)
+	p := &parsedYAML{comments: extractComments(file), size: len(data)}
+	if len(file.Docs) > 0 {
+		p.root = astToOrdered(file.Docs[0].Body)
+	}
+	return p, nil
+}
+
 // CollapseYAML transforms YAML content with depth limiting for progressive disclosure.
 // When depth is limited, nested structures are summarized (e.g., "object (5 keys)").
 // Returns the original YAML unchanged if MaxDepth is 0 (unlimited).
@@ -76,34 +100,238 @@ func CollapseYAML(data []byte, opts CollapseOptions) (string, bool, error) {
 		return string(data), false, nil
 	}
 
-	// Parse YAML AST to preserve key order and extract comments
-	file, err := parser.ParseBytes(data, parser.ParseComments)
+	p, err := parseYAML(data)
 	if err != nil {
-		return "", false, fmt.Errorf("parsing YAML: %w", err)
+		return "", false, err
+	}
+	result, collapsed := p.collapse(opts)
+	return result, collapsed, nil
+}
+
+// collapse renders the document with depth limiting. opts.MaxDepth must be
+// positive; unlimited output is produced from the source YAML instead.
+func (p *parsedYAML) collapse(opts CollapseOptions) (string, bool) {
+	if p.root == nil {
+		return "", true
 	}
 
-	// Extract comments keyed by full dotted path
 	var comments map[string]string
 	if opts.ShowComments {
-		comments = extractComments(file)
+		comments = p.comments
 	}
 
-	// Build ordered tree from AST (use first document only; Helm values.yaml is single-document)
-	var root interface{}
-	if len(file.Docs) > 0 {
-		root = astToOrdered(file.Docs[0].Body)
+	// Build collapsed output
+	var sb strings.Builder
+	sb.Grow(p.size / 2)
+
+	renderNode(&sb, p.root, "", "", 0, opts, comments)
+
+	return strings.TrimSuffix(sb.String(), "\n"), true
+}
+
+// fitDepth returns the largest depth up to opts.MaxDepth at which the
+// collapsed output is at most budget bytes, or 1 if none is. Output sizes for
+// all depths are measured in a single pass over the tree rather than by
+// rendering once per depth.
+func (p *parsedYAML) fitDepth(opts CollapseOptions, budget int) int {
+	if opts.MaxDepth <= 1 {
+		return opts.MaxDepth
+	}
+	sizes := p.measure(opts)
+	for depth := opts.MaxDepth; depth > 1; depth-- {
+		if sizes[depth] <= budget {
+			return depth
+		}
 	}
-	if root == nil {
-		return "", true, nil
+	return 1
+}
+
+// measure returns the length of the collapsed output for every depth from 1
+// to opts.MaxDepth, indexed by depth. It mirrors the render functions: each
+// piece of output is produced for a contiguous range of depths, which is
+// recorded in a difference array.
+func (p *parsedYAML) measure(opts CollapseOptions) []int {
+	m := &sizeMeasure{opts: opts, diff: make([]int, opts.MaxDepth+2)}
+	if opts.ShowComments {
+		m.comments = p.comments
+	}
+	if p.root != nil {
+		m.node(p.root, "", 0, 0)
 	}
 
-	// Build collapsed output
-	var sb strings.Builder
-	sb.Grow(len(data) / 2)
+	sizes := make([]int, opts.MaxDepth+1)
+	total := 0
+	for depth := 1; depth <= opts.MaxDepth; depth++ {
+		total += m.diff[depth]
+		sizes[depth] = total
+		// Rendered container output ends with a newline, which is trimmed
+		if total > 0 && m.trailingNewline {
+			sizes[depth]--
+		}
+	}
+	return sizes
+}
+
+// sizeMeasure accumulates output sizes per depth for parsedYAML.measure.
+type sizeMeasure struct {
+	opts            CollapseOptions
+	comments        map[string]string
+	diff            []int // diff[d] - diff[d-1] sizes at depth d
+	trailingNewline bool
+}
+
+// add records n bytes of output produced at depths lo through hi.
+func (m *sizeMeasure) add(lo, hi, n int) {
+	lo = max(lo, 1)
+	hi = min(hi, m.opts.MaxDepth)
+	if lo > hi {
+		return
+	}
+	m.diff[lo] += n
+	m.diff[hi+1] -= n
+}
+
+// from records n bytes of output produced at depths lo and above.
+func (m *sizeMeasure) from(lo, n int) {
+	m.add(lo, m.opts.MaxDepth, n)
+}
+
+// The methods below mirror renderNode, renderMap, renderArray,
+// renderArrayItem, renderInlineMap and renderValue. Output appears at depths
+// lo and above; a container at depth d is summarized at depths up to d and
+// expanded above.
 
-	renderNode(&sb, root, "", "", 0, opts, comments)
+func (m *sizeMeasure) node(node interface{}, path string, indent, depth int) {
+	switch v := node.(type) {
+	case *orderedMap:
+		m.trailingNewline = true
+		m.mapEntries(v, path, indent, depth, 1)
+	case []interface{}:
+		m.trailingNewline = true
+		m.array(v, path, indent, depth, 1)
+	default:
+		m.from(1, len(m.scalar(v)))
+	}
+}
 
-	return strings.TrimSuffix(sb.String(), "\n"), true, nil
+func (m *sizeMeasure) mapEntries(om *orderedMap, path string, indent, depth, lo int) {
+	for _, entry := range om.entries {
+		childPath := entry.key
+		if path != "" {
+			childPath = path + "." + entry.key
+		}
+
+		if comment, ok := m.comments[childPath]; ok {
+			// Comments are skipped on entries that are immediately collapsed
+			commentLo := lo
+			if isNonEmptyContainer(entry.value) {
+				commentLo = max(lo, depth+2)
+			}
+			m.from(commentLo, indent+len("# ")+len(comment)+len("\n"))
+		}
+
+		m.from(lo, indent+len(entry.key)+len(": "))
+		m.value(entry.value, childPath, indent+2, depth+1, lo)
+	}
+}
+
+func (m *sizeMeasure) array(arr []interface{}, path string, indent, depth, lo int) {
+	maxItems := m.opts.MaxArrayItems
+	if maxItems == 0 {
+		maxItems = len(arr) // unlimited
+	}
+
+	for i, item := range arr {
+		if i >= maxItems {
+			m.from(lo, indent+len(fmt.Sprintf("... and %d more items\n", len(arr)-maxItems)))
+			break
+		}
+		m.from(lo, indent+len("- "))
+		m.arrayItem(item, path, indent, depth+1, lo)
+	}
+}
+
+func (m *sizeMeasure) arrayItem(item interface{}, path string, indent, depth, lo int) {
+	switch v := item.(type) {
+	case *orderedMap:
+		if len(v.entries) == 0 {
+			m.from(lo, len("object (empty)\n"))
+			return
+		}
+		m.add(lo, depth, len(summarizeOrderedMap(v))+1)
+		m.inlineMap(v, path, indent+2, depth, max(lo, depth+1))
+
+	case []interface{}:
+		if len(v) == 0 {
+			m.from(lo, len("array (empty)\n"))
+			return
+		}
+		m.add(lo, depth, len(summarizeArray(v))+1)
+		m.from(max(lo, depth+1), len("\n"))
+		m.array(v, path, indent+2, depth, max(lo, depth+1))
+
+	default:
+		m.from(lo, len(m.scalar(v))+1)
+	}
+}
+
+func (m *sizeMeasure) inlineMap(om *orderedMap, path string, indent, depth, lo int) {
+	for i, entry := range om.entries {
+		childPath := entry.key
+		if path != "" {
+			childPath = path + "." + entry.key
+		}
+		if i > 0 {
+			m.from(lo, indent)
+		}
+		m.from(lo, len(entry.key)+len(": "))
+		m.value(entry.value, childPath, indent, depth+1, lo)
+	}
+}
+
+func (m *sizeMeasure) value(value interface{}, path string, indent, depth, lo int) {
+	switch v := value.(type) {
+	case *orderedMap:
+		if len(v.entries) == 0 {
+			m.from(lo, len("object (empty)\n"))
+			return
+		}
+		m.add(lo, depth, len(summarizeOrderedMap(v))+1)
+		m.from(max(lo, depth+1), len("\n"))
+		m.mapEntries(v, path, indent, depth, max(lo, depth+1))
+
+	case []interface{}:
+		if len(v) == 0 {
+			m.from(lo, len("array (empty)\n"))
+			return
+		}
+		m.add(lo, depth, len(summarizeArray(v))+1)
+		m.from(max(lo, depth+1), len("\n"))
+		m.array(v, path, indent, depth, max(lo, depth+1))
+
+	default:
+		m.from(lo, len(m.scalar(v))+1)
+	}
+}
+
+// scalar returns the rendered form of a scalar, as renderScalar writes it.
+func (m *sizeMeasure) scalar(v interface{}) string {
+	if m.opts.ShowDefaults {
+		return formatScalar(v)
+	}
+	return inferType(v)
+}
+
+// isNonEmptyContainer reports whether a value is a map or array with entries.
+func isNonEmptyContainer(value interface{}) bool {
+	switch v := value.(type) {
+	case *orderedMap:
+		return len(v.entries) > 0
+	case []interface{}:
+		return len(v) > 0
+	default:
+		return false
+	}
 }
 
 // unquoteKey strips surrounding double or single quotes from a YAML key string.
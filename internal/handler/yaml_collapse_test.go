@@ -943,3 +943,96 @@ func TestRenderScalar(t *testing.T) {
 		})
 	}
 }
+
+func TestParsedYAML_MeasureMatchesRender(t *testing.T) {
+	inputs := map[string]string{
+		"nested maps": `# -- Replicas
+replicaCount: 3
+# -- Image settings
+image:
+  # -- Registry
+  repository: nginx
+  tag: "1.25"
+  pullSecrets: []
+resources: {}
+ingress:
+  enabled: false
+  # -- Hosts to route
+  hosts:
+    - host: example.com
+      paths:
+        - path: /
+          pathType: Prefix
+    - host: other.com
+      paths: []
+`,
+		"arrays": `items:
+  - 1
+  - [a, b, c, d, e]
+  - - nested: true
+      other: {deep: {deeper: 1}}
+  - {}
+  - []
+  - "quoted: string"
+matrix:
+  - [1, 2]
+  - [3, 4]
+`,
+		"root array": `- name: a
+  value: 1
+- name: b
+  nested:
+    key: value
+- plain
+`,
+		"root scalar": `just a string`,
+		"empty":       ``,
+		"anchors": `base: &base
+  a: 1
+  b: {c: 2}
+derived: *base
+`,
+	}
+
+	optionSets := []CollapseOptions{
+		{MaxDepth: 6, MaxArrayItems: 3, ShowDefaults: true},
+		{MaxDepth: 6, MaxArrayItems: 0, ShowDefaults: true, ShowComments: true},
+		{MaxDepth: 6, MaxArrayItems: 1, ShowDefaults: false, ShowComments: true},
+	}
+
+	for name, input := range inputs {
+		p, err := parseYAML([]byte(input))
+		require.NoError(t, err, name)
+		for _, opts := range optionSets {
+			sizes := p.measure(opts)
+			for depth := 1; depth <= opts.MaxDepth; depth++ {
+				o := opts
+				o.MaxDepth = depth
+				result, _ := p.collapse(o)
+				assert.Equal(t, len(result), sizes[depth], "%s at depth %d with %+v", name, depth, opts)
+			}
+		}
+	}
+}
+
+func TestParsedYAML_FitDepth(t *testing.T) {
+	input := `a:
+  b:
+    c:
+      d: 1
+      e: 2
+      f: 3
+`
+	p, err := parseYAML([]byte(input))
+	require.NoError(t, err)
+
+	opts := CollapseOptions{MaxDepth: 4, ShowDefaults: true}
+	full, _ := p.collapse(opts)
+
+	assert.Equal(t, 4, p.fitDepth(opts, len(full)))
+	assert.Equal(t, 3, p.fitDepth(opts, len(full)-1))
+	assert.Equal(t, 1, p.fitDepth(opts, 0), "falls back to depth 1")
+
+	opts.MaxDepth = 1
+	assert.Equal(t, 1, p.fitDepth(opts, 0))
+}
@@ -0,0 +1,70 @@
+package helm
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"helm.sh/helm/v4/pkg/registry"
+)
+
+// Ensure Client implements CacheAdmin.
+var _ CacheAdmin = (*Client)(nil)
+
+// CacheStatus returns statistics and contents of the index, chart and
+// failure caches.
+func (c *Client) CacheStatus() *CacheStatus {
+	return &CacheStatus{
+		Indexes:      c.indexCache.Stats(),
+		Charts:       c.chartCache.Stats(),
+		Failures:     c.failureCache.Stats(),
+		IndexEntries: c.indexCache.Entries(),
+		ChartEntries: c.chartCache.Entries(),
+	}
+}
+
+// RefreshRepository drops the cached failures of a repository and its charts
+// and refetches its index, e.g. after a chart was just published. Unlike a
+// tool call, a failed refresh is reported even if a stale index could be
+// served. OCI registries have no index, so only their failures are dropped.
+// Repositories refused by the policy are not refreshed.
+func (c *Client) RefreshRepository(ctx context.Context, repoURL string) (*CachedIndex, error) {
+	if registry.IsOCI(repoURL) {
+		validatedURL, err := ValidateOCIURL(ctx, repoURL, c.validationOpts())
+		if err != nil {
+			return nil, err
+		}
+		if err := c.checkPolicy(policyTarget{repository: validatedURL}); err != nil {
+			return nil, err
+		}
+		c.failureCache.InvalidateRepository(validatedURL)
+		return nil, nil
+	}
+
+	validatedURL, err := ValidateRepoURL(ctx, repoURL, c.validationOpts())
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkPolicy(policyTarget{repository: validatedURL}); err != nil {
+		return nil, err
+	}
+	c.failureCache.InvalidateRepository(validatedURL)
+
+	unlock := c.indexCache.LockRepo(validatedURL)
+	defer unlock()
+
+	c.logger.Info("refreshing repository index", zap.String("url", validatedURL))
+	e, err := c.loadIndex(ctx, validatedURL, true)
+	if err != nil {
+		c.recordFailure(ctx, makeChartKey(validatedURL, "", ""), err)
+		return nil, err
+	}
+	c.indexCache.put(validatedURL, e)
+	c.setRefreshError(validatedURL, nil)
+
+	return &CachedIndex{
+		Repository: validatedURL,
+		FetchedAt:  e.fetchedAt,
+		Charts:     len(e.index.names),
+		Bytes:      estimateIndexBytes(e.index),
+	}, nil
+}
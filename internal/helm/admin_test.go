@@ -0,0 +1,119 @@
+package helm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClient_CacheStatus(t *testing.T) {
+	ctx := context.Background()
+	repo := newChartRepo(t, "app", "1.0.0", buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n"))
+	client := NewClient(
+		WithAllowPrivateIPs(true),
+		WithCacheDir(t.TempDir()),
+		WithLogger(zap.NewNop()),
+	)
+
+	_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+	require.NoError(t, err)
+
+	status := client.CacheStatus()
+	if assert.Len(t, status.IndexEntries, 1) {
+		e := status.IndexEntries[0]
+		assert.Equal(t, repo.URL, e.Repository)
+		assert.Equal(t, 1, e.Charts)
+		assert.Positive(t, e.Bytes)
+		assert.False(t, e.Expired)
+		assert.WithinDuration(t, time.Now(), e.FetchedAt, time.Minute)
+	}
+	if assert.Len(t, status.ChartEntries, 1) {
+		assert.Equal(t, CachedChart{
+			Repository: repo.URL,
+			Chart:      "app",
+			Version:    "1.0.0",
+			Bytes:      status.ChartEntries[0].Bytes,
+		}, status.ChartEntries[0])
+	}
+	assert.Equal(t, 1, status.Indexes.Size)
+	assert.Equal(t, 1, status.Charts.Size)
+}
+
+func TestClient_RefreshRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("refetches the index and drops cached failures", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		server.down.Store(true)
+		client := NewClient(
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+			WithFailureTTL(time.Minute),
+		)
+
+		_, err := client.ListCharts(ctx, server.URL)
+		require.Error(t, err)
+		_, err = client.ListCharts(ctx, server.URL)
+		require.True(t, IsCachedFailure(err))
+
+		server.down.Store(false)
+		index, err := client.RefreshRepository(ctx, server.URL)
+		require.NoError(t, err)
+		require.NotNil(t, index)
+		assert.Equal(t, 1, index.Charts)
+		assert.Zero(t, client.failureCache.Len())
+
+		requests := server.requests.Load()
+		charts, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app"}, charts)
+		assert.Equal(t, requests, server.requests.Load(), "refreshed index should be served from cache")
+	})
+
+	t.Run("reports refresh failures", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		client := NewClient(
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+		)
+
+		_, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+
+		server.down.Store(true)
+		_, err = client.RefreshRepository(ctx, server.URL)
+		assert.True(t, IsRepositoryError(err), "expected RepositoryError, got %T: %v", err, err)
+	})
+
+	t.Run("refuses repositories denied by policy", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		policy, err := ParsePolicy([]byte("rules:\n  - effect: deny\n    repository: " + server.URL + "\n  - effect: deny\n    repository: oci://127.0.0.1/*\n"))
+		require.NoError(t, err)
+		client := NewClient(
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+			WithPolicy(policy),
+		)
+
+		_, err = client.RefreshRepository(ctx, server.URL)
+		assert.True(t, IsPolicyError(err), "expected PolicyError, got %T: %v", err, err)
+		assert.Zero(t, server.requests.Load())
+
+		_, err = client.RefreshRepository(ctx, "oci://127.0.0.1/org")
+		assert.True(t, IsPolicyError(err), "expected PolicyError, got %T: %v", err, err)
+	})
+
+	t.Run("rejects invalid URLs", func(t *testing.T) {
+		client := NewClient(WithLogger(zap.NewNop()))
+
+		_, err := client.RefreshRepository(ctx, "ftp://example.com")
+		assert.True(t, IsURLValidationError(err), "expected URLValidationError, got %T: %v", err, err)
+	})
+}
@@ -0,0 +1,178 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"helm.sh/helm/v4/pkg/chart/loader"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/traceutil"
+)
+
+// Archive limit names reported in ArchiveLimitError.
+const (
+	limitTotalSize = "total size"
+	limitFileCount = "file count"
+	limitFileSize  = "file size"
+	limitPathDepth = "path depth"
+)
+
+// ArchiveLimits bounds how much a chart archive may expand when it is loaded.
+// The compressed size is bounded separately by WithMaxChartBytes.
+type ArchiveLimits struct {
+	MaxTotalBytes int64 // Total decompressed size, including subchart archives
+	MaxFiles      int   // Number of files, including files in subchart archives
+	MaxFileBytes  int64 // Decompressed size of a single file
+	MaxPathDepth  int   // Directory depth below the chart root
+}
+
+// defaultArchiveLimits returns limits that accommodate large real-world charts.
+func defaultArchiveLimits() ArchiveLimits {
+	return ArchiveLimits{
+		MaxTotalBytes: 100 * 1024 * 1024, // 100 MB, matching Helm's own limit
+		MaxFiles:      5000,
+		MaxFileBytes:  5 * 1024 * 1024, // 5 MB, matching Helm's own limit
+		MaxPathDepth:  16,
+	}
+}
+
+// loadArchive loads a downloaded chart archive within the client's archive
+// limits, tracing it in a span.
+func (c *Client) loadArchive(ctx context.Context, data []byte) (_ *chartv2.Chart, err error) {
+	_, span := traceutil.StartSpan(ctx, tracerName, "helm.loader.Load", attribute.Int("mcp_helm.chart.archive_bytes", len(data)))
+	defer func() { traceutil.EndSpan(span, err) }()
+	return loadChartArchive(data, c.opts.archiveLimits)
+}
+
+// loadChartArchive loads a packaged chart after checking it against limits.
+//
+// Helm's loader buffers every file of the archive in memory, so the archive
+// is first streamed through a counting pass that stops as soon as a limit is
+// exceeded. Subchart archives under charts/ are checked recursively, since
+// Helm expands them as well.
+func loadChartArchive(data []byte, limits ArchiveLimits) (*chartv2.Chart, error) {
+	s := &archiveScanner{limits: limits}
+	if err := s.scan(bytes.NewReader(data), 0); err != nil {
+		var limitErr *ArchiveLimitError
+		if errors.As(err, &limitErr) {
+			return nil, limitErr
+		}
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	loaded, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+	chart, ok := loaded.(*chartv2.Chart)
+	if !ok {
+		return nil, fmt.Errorf("unsupported chart format")
+	}
+	return chart, nil
+}
+
+// archiveScanner accumulates totals across a chart and its subchart archives.
+type archiveScanner struct {
+	limits ArchiveLimits
+	total  int64
+	files  int
+}
+
+// scan reads a gzipped tar stream, enforcing limits. baseDepth is the depth
+// of the directory the archive was found in, so that nesting subcharts counts
+// towards the path depth limit.
+func (s *archiveScanner) scan(r io.Reader, baseDepth int) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = zr.Close() }()
+
+	tr := tar.NewReader(zr)
+	for {
+		hd, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hd.FileInfo().IsDir() || hd.Typeflag == tar.TypeXGlobalHeader || hd.Typeflag == tar.TypeXHeader {
+			continue
+		}
+
+		// Like Helm, drop the leading chart directory and normalize separators.
+		name := strings.ReplaceAll(hd.Name, "\\", "/")
+		if _, rest, ok := strings.Cut(name, "/"); ok {
+			name = rest
+		}
+		name = path.Clean(name)
+
+		depth := baseDepth + strings.Count(name, "/")
+		if s.limits.MaxPathDepth > 0 && depth > s.limits.MaxPathDepth {
+			return &ArchiveLimitError{Limit: limitPathDepth, Value: int64(depth), Max: int64(s.limits.MaxPathDepth), Path: hd.Name}
+		}
+
+		s.files++
+		if s.limits.MaxFiles > 0 && s.files > s.limits.MaxFiles {
+			return &ArchiveLimitError{Limit: limitFileCount, Value: int64(s.files), Max: int64(s.limits.MaxFiles)}
+		}
+
+		// Subchart archives are buffered (bounded by the file size limit) so
+		// their contents can be checked too.
+		nested := path.Base(path.Dir(name)) == "charts" && strings.HasSuffix(name, ".tgz")
+		var buf *bytes.Buffer
+		dst := io.Discard
+		if nested {
+			buf = &bytes.Buffer{}
+			dst = buf
+		}
+
+		// Read one byte past the limit so that an oversized file is detected
+		// without trusting the size recorded in the header.
+		n, err := io.Copy(dst, io.LimitReader(tr, s.readLimit()))
+		if err != nil {
+			return err
+		}
+		if s.limits.MaxFileBytes > 0 && n > s.limits.MaxFileBytes {
+			return &ArchiveLimitError{Limit: limitFileSize, Value: n, Max: s.limits.MaxFileBytes, Path: hd.Name}
+		}
+		s.total += n
+		if s.limits.MaxTotalBytes > 0 && s.total > s.limits.MaxTotalBytes {
+			return &ArchiveLimitError{Limit: limitTotalSize, Value: s.total, Max: s.limits.MaxTotalBytes}
+		}
+
+		if nested {
+			if err := s.scan(buf, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readLimit returns how many bytes of the next file to read: one more than the
+// smaller of the per-file limit and the remaining total budget.
+func (s *archiveScanner) readLimit() int64 {
+	limit := int64(-1)
+	if s.limits.MaxFileBytes > 0 {
+		limit = s.limits.MaxFileBytes
+	}
+	if s.limits.MaxTotalBytes > 0 {
+		if remaining := s.limits.MaxTotalBytes - s.total; limit < 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+	if limit < 0 {
+		return 1<<63 - 1
+	}
+	return limit + 1
+}
@@ -0,0 +1,151 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// tarFile is an entry in an archive built by buildTarGz.
+type tarFile struct {
+	name string
+	data []byte
+}
+
+// buildTarGz returns a gzipped tar archive containing files in order.
+func buildTarGz(t *testing.T, files ...tarFile) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, f := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data))}))
+		_, err := tw.Write(f.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestLoadChartArchive(t *testing.T) {
+	chartYAML := tarFile{"app/Chart.yaml", []byte("apiVersion: v2\nname: app\nversion: 1.0.0\n")}
+	limits := ArchiveLimits{MaxTotalBytes: 256 * 1024, MaxFiles: 10, MaxFileBytes: 64 * 1024, MaxPathDepth: 3}
+
+	t.Run("chart within limits loads", func(t *testing.T) {
+		data := buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n")
+
+		chart, err := loadChartArchive(data, limits)
+		require.NoError(t, err)
+		assert.Equal(t, "app", chart.Name())
+	})
+
+	tests := []struct {
+		name      string
+		files     []tarFile
+		wantLimit string
+	}{
+		{
+			name:      "highly compressible file exceeds file size",
+			files:     []tarFile{chartYAML, {"app/values.yaml", make([]byte, 65*1024)}},
+			wantLimit: limitFileSize,
+		},
+		{
+			name: "many files exceed total size",
+			files: func() []tarFile {
+				files := []tarFile{chartYAML}
+				for i := range 8 {
+					files = append(files, tarFile{fmt.Sprintf("app/files/f%d", i), make([]byte, 60*1024)})
+				}
+				return files
+			}(),
+			wantLimit: limitTotalSize,
+		},
+		{
+			name: "too many files",
+			files: func() []tarFile {
+				files := []tarFile{chartYAML}
+				for i := range 10 {
+					files = append(files, tarFile{fmt.Sprintf("app/templates/t%d.yaml", i), nil})
+				}
+				return files
+			}(),
+			wantLimit: limitFileCount,
+		},
+		{
+			name:      "deeply nested path",
+			files:     []tarFile{chartYAML, {"app/a/b/c/d/e.yaml", nil}},
+			wantLimit: limitPathDepth,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildTarGz(t, tt.files...)
+
+			_, err := loadChartArchive(data, limits)
+			require.Error(t, err)
+			var limitErr *ArchiveLimitError
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, tt.wantLimit, limitErr.Limit)
+		})
+	}
+
+	t.Run("subchart archives count towards limits", func(t *testing.T) {
+		sub := buildTarGz(t,
+			tarFile{"sub/Chart.yaml", []byte("apiVersion: v2\nname: sub\nversion: 1.0.0\n")},
+			tarFile{"sub/values.yaml", make([]byte, 65*1024)},
+		)
+		data := buildTarGz(t, chartYAML, tarFile{"app/charts/sub-1.0.0.tgz", sub})
+
+		_, err := loadChartArchive(data, limits)
+		var limitErr *ArchiveLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, limitFileSize, limitErr.Limit)
+		assert.Equal(t, "sub/values.yaml", limitErr.Path)
+	})
+
+	t.Run("subchart nesting counts towards path depth", func(t *testing.T) {
+		sub := buildTarGz(t, tarFile{"sub/templates/x/y.yaml", nil})
+		data := buildTarGz(t, chartYAML, tarFile{"app/charts/sub-1.0.0.tgz", sub})
+
+		_, err := loadChartArchive(data, limits)
+		var limitErr *ArchiveLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, limitPathDepth, limitErr.Limit)
+	})
+
+	t.Run("invalid archive is a load error", func(t *testing.T) {
+		_, err := loadChartArchive([]byte("not a chart"), limits)
+		require.Error(t, err)
+		assert.False(t, IsArchiveLimitError(err))
+		assert.True(t, strings.HasPrefix(err.Error(), "failed to load chart"))
+	})
+}
+
+func TestClient_ArchiveLimits(t *testing.T) {
+	ctx := context.Background()
+	repo := newChartRepo(t, "app", "1.0.0", buildChartArchive(t, "app", "1.0.0", strings.Repeat("a: b\n", 10000)))
+
+	client := NewClient(
+		WithTimeout(5*time.Second),
+		WithAllowPrivateIPs(true),
+		WithCacheDir(t.TempDir()),
+		WithLogger(zap.NewNop()),
+		WithArchiveLimits(ArchiveLimits{MaxFileBytes: 1024}),
+	)
+
+	_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+	require.Error(t, err)
+	assert.True(t, IsArchiveLimitError(err), "expected ArchiveLimitError, got %T: %v", err, err)
+}
@@ -2,6 +2,7 @@ package helm
 
 import (
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -9,49 +10,126 @@ import (
 	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/hashicorp/golang-lru/v2/expirable"
 	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
-	repo "helm.sh/helm/v4/pkg/repo/v1"
 )
 
 // CacheStats holds cache performance metrics.
 type CacheStats struct {
-	Hits   uint64 // Number of cache hits
-	Misses uint64 // Number of cache misses
-	Size   int    // Current number of entries
+	Hits   uint64 `json:"hits"`   // Number of cache hits
+	Misses uint64 `json:"misses"` // Number of cache misses
+	Size   int    `json:"size"`   // Current number of entries
+	Bytes  int64  `json:"bytes"`  // Estimated memory held by the entries
+
+	// Index cache only: conditional refreshes answered with 304 Not Modified,
+	// and the index bytes those responses saved downloading.
+	NotModified uint64 `json:"not_modified,omitempty"`
+	BytesSaved  uint64 `json:"bytes_saved,omitempty"`
+}
+
+// HitRatio returns the fraction of lookups that were hits, or zero if there
+// were none.
+func (s CacheStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// CachedIndex describes a repository index held in the index cache.
+type CachedIndex struct {
+	Repository string    `json:"repository"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	Expired    bool      `json:"expired"` // Past the TTL; only served stale
+	Charts     int       `json:"charts"`  // Number of charts in the index
+	Bytes      int64     `json:"bytes"`   // Estimated memory held
+}
+
+// CachedChart describes a chart version held in the chart cache.
+type CachedChart struct {
+	Repository string `json:"repository"`
+	Chart      string `json:"chart"`
+	Version    string `json:"version"`
+	Bytes      int64  `json:"bytes"` // Estimated memory held
 }
 
 // IndexCache caches repository indexes with bounded size and TTL expiration.
-// Thread-safe. Uses LRU eviction when capacity is reached.
+// Thread-safe. Uses LRU eviction when either the entry capacity or the byte
+// budget is reached; sizes are estimated from the parsed index.
+//
+// Expiry is measured from when an index was fetched, which may be earlier than
+// when it was cached (e.g. an index restored from disk). Expired indexes can be
+// retained for up to a max stale age so that they can still be served while a
+// refresh is pending or failing.
 type IndexCache struct {
-	cache    *expirable.LRU[string, *repo.IndexFile]
-	repoLock *repoLockManager
-	hits     atomic.Uint64
-	misses   atomic.Uint64
+	cache       *expirable.LRU[string, indexEntry]
+	ttl         time.Duration
+	budget      byteBudget
+	repoLock    *repoLockManager
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	notModified atomic.Uint64
+	bytesSaved  atomic.Uint64
+}
+
+// indexEntry is a cached index together with when it was fetched.
+type indexEntry struct {
+	index      *chartIndex
+	fetchedAt  time.Time
+	validators indexValidators // For a conditional refresh
+	size       int64           // Estimated bytes
 }
 
 // NewIndexCache creates a bounded index cache.
 // Entries expire after ttl and are evicted LRU when size exceeds capacity.
 func NewIndexCache(capacity int, ttl time.Duration) *IndexCache {
+	return newIndexCache(capacity, 0, ttl, 0)
+}
+
+// newIndexCache creates a bounded index cache. maxBytes limits the estimated
+// size of all entries (zero or negative means no limit). Entries expire after
+// ttl but are retained until they are maxStale old, for GetStale.
+func newIndexCache(capacity int, maxBytes int64, ttl, maxStale time.Duration) *IndexCache {
 	if capacity <= 0 {
 		capacity = 100
 	}
 	if ttl <= 0 {
 		ttl = 5 * time.Minute
 	}
-	return &IndexCache{
-		cache:    expirable.NewLRU[string, *repo.IndexFile](capacity, nil, ttl),
+	c := &IndexCache{
+		ttl:      ttl,
+		budget:   byteBudget{max: maxBytes},
 		repoLock: newRepoLockManager(),
 	}
+	c.cache = expirable.NewLRU(capacity, func(_ string, e indexEntry) {
+		c.budget.release(e.size)
+	}, max(ttl, maxStale))
+	return c
 }
 
 // Get retrieves a cached index if present and not expired.
-func (c *IndexCache) Get(repoURL string) (*repo.IndexFile, bool) {
-	index, ok := c.cache.Get(repoURL)
+func (c *IndexCache) Get(repoURL string) (*chartIndex, bool) {
+	entry, ok := c.cache.Get(repoURL)
+	if ok && time.Since(entry.fetchedAt) >= c.ttl {
+		ok = false
+	}
 	if ok {
 		c.hits.Add(1)
 	} else {
 		c.misses.Add(1)
 	}
-	return index, ok
+	return entry.index, ok
+}
+
+// GetStale retrieves a cached index whether or not it has expired, together
+// with when it was fetched. It does not affect hit/miss statistics.
+func (c *IndexCache) GetStale(repoURL string) (*chartIndex, time.Time, bool) {
+	entry, ok := c.cache.Peek(repoURL)
+	return entry.index, entry.fetchedAt, ok
+}
+
+// peek returns the entry for repoURL whether or not it has expired, without
+// affecting hit/miss statistics or LRU order.
+func (c *IndexCache) peek(repoURL string) (indexEntry, bool) {
+	return c.cache.Peek(repoURL)
 }
 
 // Stats returns cache performance metrics.
@@ -61,16 +139,85 @@ func (c *IndexCache) Stats() CacheStats {
 	hits := c.hits.Load()
 	misses := c.misses.Load()
 	size := c.cache.Len()
+	bytes := c.budget.used.Load()
+	notModified := c.notModified.Load()
+	bytesSaved := c.bytesSaved.Load()
 	return CacheStats{
-		Hits:   hits,
-		Misses: misses,
-		Size:   size,
+		Hits:        hits,
+		Misses:      misses,
+		Size:        size,
+		Bytes:       bytes,
+		NotModified: notModified,
+		BytesSaved:  bytesSaved,
+	}
+}
+
+// RecordNotModified records a conditional refresh that was answered with
+// 304 Not Modified, saving a download of size bytes.
+func (c *IndexCache) RecordNotModified(size int64) {
+	c.notModified.Add(1)
+	if size > 0 {
+		c.bytesSaved.Add(uint64(size))
+	}
+}
+
+// Put stores an index fetched just now in the cache.
+func (c *IndexCache) Put(repoURL string, index *chartIndex) {
+	c.PutFetched(repoURL, index, time.Now())
+}
+
+// PutFetched stores an index in the cache, recording when it was fetched.
+// An index larger than the whole byte budget is not cached.
+func (c *IndexCache) PutFetched(repoURL string, index *chartIndex, fetchedAt time.Time) {
+	c.put(repoURL, indexEntry{index: index, fetchedAt: fetchedAt})
+}
+
+// put stores an entry in the cache, as PutFetched does, keeping its
+// validators.
+func (c *IndexCache) put(repoURL string, e indexEntry) {
+	e.size = estimateIndexBytes(e.index)
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+
+	// Remove any previous entry first: replacing a key in place would skip
+	// the eviction callback that keeps the byte count right.
+	c.cache.Remove(repoURL)
+	if !c.budget.fits(e.size) {
+		return
+	}
+	c.cache.Add(repoURL, e)
+	c.budget.reserve(e.size, func() bool {
+		_, _, ok := c.cache.RemoveOldest()
+		return ok
+	})
+}
+
+// Entries lists the cached indexes, least recently used first. It does not
+// affect hit/miss statistics or LRU order.
+func (c *IndexCache) Entries() []CachedIndex {
+	var entries []CachedIndex
+	for _, repoURL := range c.cache.Keys() {
+		if e, ok := c.Entry(repoURL); ok {
+			entries = append(entries, e)
+		}
 	}
+	return entries
 }
 
-// Put stores an index in the cache.
-func (c *IndexCache) Put(repoURL string, index *repo.IndexFile) {
-	c.cache.Add(repoURL, index)
+// Entry describes the cached index of a repository, whether or not it has
+// expired.
+func (c *IndexCache) Entry(repoURL string) (CachedIndex, bool) {
+	e, ok := c.cache.Peek(repoURL)
+	if !ok {
+		return CachedIndex{}, false
+	}
+	return CachedIndex{
+		Repository: repoURL,
+		FetchedAt:  e.fetchedAt,
+		Expired:    time.Since(e.fetchedAt) >= c.ttl,
+		Charts:     len(e.index.names),
+		Bytes:      e.size,
+	}, true
 }
 
 // Invalidate removes a specific repo from the cache.
@@ -95,39 +242,72 @@ func (c *IndexCache) LockRepo(repoURL string) func() {
 }
 
 // ChartCache caches loaded Helm charts with bounded size.
-// Thread-safe. Uses LRU eviction when capacity is reached.
+// Thread-safe. Uses LRU eviction when either the entry capacity or the byte
+// budget is reached; sizes are estimated from the chart's files.
 // No TTL since chart versions are immutable.
 type ChartCache struct {
-	cache  *lru.Cache[string, *chartv2.Chart]
+	cache  *lru.Cache[string, chartEntry]
+	budget byteBudget
 	hits   atomic.Uint64
 	misses atomic.Uint64
 }
 
+// chartEntry is a cached chart together with its signature verification result.
+type chartEntry struct {
+	chart        *chartv2.Chart
+	verification *Verification
+	size         int64       // Estimated bytes
+	info         CachedChart // Describes the entry for Entries
+}
+
 // NewChartCache creates a bounded chart cache.
 // Charts don't expire (versions are immutable) but are evicted LRU when size exceeds capacity.
 func NewChartCache(capacity int) *ChartCache {
+	return newChartCache(capacity, 0)
+}
+
+// newChartCache creates a bounded chart cache whose entries are also limited
+// to maxBytes in total (zero or negative means no limit).
+func newChartCache(capacity int, maxBytes int64) *ChartCache {
 	if capacity <= 0 {
 		capacity = 50
 	}
-	cache, err := lru.New[string, *chartv2.Chart](capacity)
+	c := &ChartCache{budget: byteBudget{max: maxBytes}}
+	cache, err := lru.NewWithEvict(capacity, func(_ string, e chartEntry) {
+		c.budget.release(e.size)
+	})
 	if err != nil {
 		// lru.New only fails if size <= 0, which we guard above.
 		panic("helm: chart cache: " + err.Error())
 	}
-	return &ChartCache{
-		cache: cache,
-	}
+	c.cache = cache
+	return c
 }
 
 // Get retrieves a chart from the cache.
 func (c *ChartCache) Get(repoURL, chartName, version string) (*chartv2.Chart, bool) {
-	chart, ok := c.cache.Get(makeChartKey(repoURL, chartName, version))
+	entry, ok := c.cache.Get(makeChartKey(repoURL, chartName, version))
 	if ok {
 		c.hits.Add(1)
 	} else {
 		c.misses.Add(1)
 	}
-	return chart, ok
+	return entry.chart, ok
+}
+
+// Peek retrieves a chart from the cache without affecting hit/miss
+// statistics or LRU order.
+func (c *ChartCache) Peek(repoURL, chartName, version string) (*chartv2.Chart, bool) {
+	entry, ok := c.cache.Peek(makeChartKey(repoURL, chartName, version))
+	return entry.chart, ok
+}
+
+// Verification returns the signature verification result recorded for a
+// cached chart. It does not affect hit/miss statistics or LRU order.
+// The boolean is false if the chart is not cached.
+func (c *ChartCache) Verification(repoURL, chartName, version string) (*Verification, bool) {
+	entry, ok := c.cache.Peek(makeChartKey(repoURL, chartName, version))
+	return entry.verification, ok
 }
 
 // Stats returns cache performance metrics.
@@ -137,16 +317,50 @@ func (c *ChartCache) Stats() CacheStats {
 	hits := c.hits.Load()
 	misses := c.misses.Load()
 	size := c.cache.Len()
+	bytes := c.budget.used.Load()
 	return CacheStats{
 		Hits:   hits,
 		Misses: misses,
 		Size:   size,
+		Bytes:  bytes,
 	}
 }
 
 // Put stores a chart in the cache.
 func (c *ChartCache) Put(repoURL, chartName, version string, chart *chartv2.Chart) {
-	c.cache.Add(makeChartKey(repoURL, chartName, version), chart)
+	c.PutVerified(repoURL, chartName, version, chart, nil)
+}
+
+// PutVerified stores a chart in the cache along with its signature verification result.
+// A chart larger than the whole byte budget is not cached.
+func (c *ChartCache) PutVerified(repoURL, chartName, version string, chart *chartv2.Chart, v *Verification) {
+	key := makeChartKey(repoURL, chartName, version)
+	size := estimateChartBytes(chart)
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+
+	// Remove any previous entry first, as IndexCache.PutFetched does.
+	c.cache.Remove(key)
+	if !c.budget.fits(size) {
+		return
+	}
+	info := CachedChart{Repository: repoURL, Chart: chartName, Version: version, Bytes: size}
+	c.cache.Add(key, chartEntry{chart: chart, verification: v, size: size, info: info})
+	c.budget.reserve(size, func() bool {
+		_, _, ok := c.cache.RemoveOldest()
+		return ok
+	})
+}
+
+// Entries lists the cached charts, least recently used first. It does not
+// affect hit/miss statistics or LRU order.
+func (c *ChartCache) Entries() []CachedChart {
+	values := c.cache.Values()
+	entries := make([]CachedChart, 0, len(values))
+	for _, e := range values {
+		entries = append(entries, e.info)
+	}
+	return entries
 }
 
 // Clear removes all entries from the cache.
@@ -159,6 +373,135 @@ func (c *ChartCache) Len() int {
 	return c.cache.Len()
 }
 
+// FailureCache caches chart-not-found results and repository failures for a
+// short TTL, so that retries of a missing chart or a failing repository fail
+// fast. Thread-safe. Keys are built with makeChartKey; a nil FailureCache
+// caches nothing.
+type FailureCache struct {
+	cache  *expirable.LRU[string, failureEntry]
+	ttl    time.Duration
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// failureEntry is a cached failure together with when it expires.
+type failureEntry struct {
+	err       error
+	expiresAt time.Time
+}
+
+// newFailureCache creates a bounded failure cache, or returns nil if ttl is
+// not positive.
+func newFailureCache(capacity int, ttl time.Duration) *FailureCache {
+	if ttl <= 0 {
+		return nil
+	}
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &FailureCache{cache: expirable.NewLRU[string, failureEntry](capacity, nil, ttl), ttl: ttl}
+}
+
+// Get returns the failure cached under key.
+func (c *FailureCache) Get(key string) (*CachedFailureError, bool) {
+	if c == nil {
+		return nil, false
+	}
+	entry, ok := c.cache.Get(key)
+	if ok && time.Now().After(entry.expiresAt) {
+		ok = false
+	}
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return &CachedFailureError{Err: entry.err, ExpiresAt: entry.expiresAt}, true
+}
+
+// Put caches a failure under key. Failures that were themselves served from
+// the cache are not cached again, so that they expire on time.
+func (c *FailureCache) Put(key string, err error) {
+	if c == nil || IsCachedFailure(err) {
+		return
+	}
+	c.cache.Add(key, failureEntry{err: err, expiresAt: time.Now().Add(c.ttl)})
+}
+
+// InvalidateRepository removes the cached failures of a repository and of
+// its charts.
+func (c *FailureCache) InvalidateRepository(repoURL string) {
+	if c == nil {
+		return
+	}
+	// makeChartKey starts every key with the length-prefixed repository URL
+	prefix := strconv.Itoa(len(repoURL)) + ":" + repoURL
+	for _, key := range c.cache.Keys() {
+		if strings.HasPrefix(key, prefix) {
+			c.cache.Remove(key)
+		}
+	}
+}
+
+// Stats returns cache performance metrics.
+func (c *FailureCache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	hits := c.hits.Load()
+	misses := c.misses.Load()
+	size := c.cache.Len()
+	return CacheStats{
+		Hits:   hits,
+		Misses: misses,
+		Size:   size,
+	}
+}
+
+// Clear removes all entries from the cache.
+func (c *FailureCache) Clear() {
+	if c != nil {
+		c.cache.Purge()
+	}
+}
+
+// Len returns the current number of cached failures.
+func (c *FailureCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	return c.cache.Len()
+}
+
+// byteBudget tracks the estimated size of a cache's entries against a limit.
+// Eviction callbacks release entry sizes, so used is updated atomically.
+type byteBudget struct {
+	max  int64        // Zero or negative means no limit
+	used atomic.Int64 // Estimated bytes currently cached
+	mu   sync.Mutex   // Serializes puts so that concurrent evictions don't overshoot
+}
+
+// fits reports whether an entry of size bytes can be cached at all.
+func (b *byteBudget) fits(size int64) bool {
+	return b.max <= 0 || size <= b.max
+}
+
+// reserve accounts for a newly added entry of size bytes, calling
+// removeOldest until the cache is back within budget. The caller must hold mu.
+func (b *byteBudget) reserve(size int64, removeOldest func() bool) {
+	b.used.Add(size)
+	for b.max > 0 && b.used.Load() > b.max {
+		if !removeOldest() {
+			return
+		}
+	}
+}
+
+// release accounts for an entry of size bytes leaving the cache.
+func (b *byteBudget) release(size int64) {
+	b.used.Add(-size)
+}
+
 // makeChartKey builds an unambiguous cache key using length-prefixed encoding.
 // This prevents collisions when values contain the separator character.
 func makeChartKey(repoURL, chartName, version string) string {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v4/pkg/chart/common"
 	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
 	repo "helm.sh/helm/v4/pkg/repo/v1"
 )
@@ -21,11 +22,11 @@ func TestIndexCache(t *testing.T) {
 
 	t.Run("put and get", func(t *testing.T) {
 		cache := NewIndexCache(10, time.Minute)
-		index := &repo.IndexFile{
+		index := newChartIndex(&repo.IndexFile{
 			Entries: map[string]repo.ChartVersions{
 				"nginx": {{Metadata: &chartv2.Metadata{Name: "nginx"}}},
 			},
-		}
+		})
 
 		cache.Put("https://example.com", index)
 
@@ -36,7 +37,7 @@ func TestIndexCache(t *testing.T) {
 
 	t.Run("expired entry returns false", func(t *testing.T) {
 		cache := NewIndexCache(10, 10*time.Millisecond)
-		cache.Put("https://example.com", &repo.IndexFile{})
+		cache.Put("https://example.com", &chartIndex{})
 
 		time.Sleep(50 * time.Millisecond)
 
@@ -46,7 +47,7 @@ func TestIndexCache(t *testing.T) {
 
 	t.Run("invalidate removes entry", func(t *testing.T) {
 		cache := NewIndexCache(10, time.Minute)
-		cache.Put("https://example.com", &repo.IndexFile{})
+		cache.Put("https://example.com", &chartIndex{})
 
 		cache.Invalidate("https://example.com")
 
@@ -56,8 +57,8 @@ func TestIndexCache(t *testing.T) {
 
 	t.Run("clear removes all entries", func(t *testing.T) {
 		cache := NewIndexCache(10, time.Minute)
-		cache.Put("https://a.com", &repo.IndexFile{})
-		cache.Put("https://b.com", &repo.IndexFile{})
+		cache.Put("https://a.com", &chartIndex{})
+		cache.Put("https://b.com", &chartIndex{})
 
 		cache.Clear()
 
@@ -70,9 +71,9 @@ func TestIndexCache(t *testing.T) {
 	t.Run("LRU eviction when capacity exceeded", func(t *testing.T) {
 		cache := NewIndexCache(2, time.Minute)
 
-		cache.Put("https://a.com", &repo.IndexFile{})
-		cache.Put("https://b.com", &repo.IndexFile{})
-		cache.Put("https://c.com", &repo.IndexFile{}) // Evicts a.com
+		cache.Put("https://a.com", &chartIndex{})
+		cache.Put("https://b.com", &chartIndex{})
+		cache.Put("https://c.com", &chartIndex{}) // Evicts a.com
 
 		_, okA := cache.Get("https://a.com")
 		_, okB := cache.Get("https://b.com")
@@ -87,15 +88,15 @@ func TestIndexCache(t *testing.T) {
 		cache := NewIndexCache(10, time.Minute)
 		assert.Equal(t, 0, cache.Len())
 
-		cache.Put("https://a.com", &repo.IndexFile{})
-		cache.Put("https://b.com", &repo.IndexFile{})
+		cache.Put("https://a.com", &chartIndex{})
+		cache.Put("https://b.com", &chartIndex{})
 		assert.Equal(t, 2, cache.Len())
 	})
 
 	t.Run("default values on zero", func(t *testing.T) {
 		cache := NewIndexCache(0, 0)
 		// Should not panic, uses defaults
-		cache.Put("https://example.com", &repo.IndexFile{})
+		cache.Put("https://example.com", &chartIndex{})
 		_, ok := cache.Get("https://example.com")
 		assert.True(t, ok)
 	})
@@ -116,13 +117,45 @@ func TestIndexCache(t *testing.T) {
 		assert.Equal(t, uint64(1), stats.Misses)
 
 		// Put and hit
-		cache.Put("https://example.com", &repo.IndexFile{})
+		cache.Put("https://example.com", &chartIndex{})
 		cache.Get("https://example.com")
 		stats = cache.Stats()
 		assert.Equal(t, uint64(1), stats.Hits)
 		assert.Equal(t, uint64(1), stats.Misses)
 		assert.Equal(t, 1, stats.Size)
 	})
+
+	t.Run("expiry is measured from fetch time", func(t *testing.T) {
+		cache := NewIndexCache(10, time.Minute)
+		cache.PutFetched("https://example.com", &chartIndex{}, time.Now().Add(-2*time.Minute))
+
+		_, ok := cache.Get("https://example.com")
+		assert.False(t, ok)
+		assert.Equal(t, uint64(1), cache.Stats().Misses)
+	})
+
+	t.Run("GetStale returns expired entries within max stale", func(t *testing.T) {
+		cache := newIndexCache(10, 0, time.Minute, time.Hour)
+		fetchedAt := time.Now().Add(-2 * time.Minute)
+		index := &chartIndex{}
+		cache.PutFetched("https://example.com", index, fetchedAt)
+
+		got, gotFetchedAt, ok := cache.GetStale("https://example.com")
+		assert.True(t, ok)
+		assert.Same(t, index, got)
+		assert.True(t, fetchedAt.Equal(gotFetchedAt))
+		assert.Equal(t, uint64(0), cache.Stats().Hits+cache.Stats().Misses, "GetStale does not count")
+	})
+
+	t.Run("Stats tracks not modified refreshes", func(t *testing.T) {
+		cache := NewIndexCache(10, time.Minute)
+
+		cache.RecordNotModified(1024)
+		cache.RecordNotModified(512)
+		stats := cache.Stats()
+		assert.Equal(t, uint64(2), stats.NotModified)
+		assert.Equal(t, uint64(1536), stats.BytesSaved)
+	})
 }
 
 func TestChartCache(t *testing.T) {
@@ -225,6 +258,32 @@ func TestChartCache(t *testing.T) {
 		assert.True(t, ok)
 	})
 
+	t.Run("verification stored with chart", func(t *testing.T) {
+		cache := NewChartCache(10)
+		v := &Verification{Method: MethodProvenance, Status: StatusVerified, Signer: "Helm Test <test@example.com>"}
+
+		cache.PutVerified("repo", "nginx", "1.0.0", makeChart("nginx"), v)
+
+		got, ok := cache.Verification("repo", "nginx", "1.0.0")
+		require.True(t, ok)
+		assert.Equal(t, v, got)
+
+		// Peeking at the verification does not count as a hit
+		assert.Equal(t, uint64(0), cache.Stats().Hits)
+	})
+
+	t.Run("verification missing for uncached chart", func(t *testing.T) {
+		cache := NewChartCache(10)
+		cache.Put("repo", "nginx", "1.0.0", makeChart("nginx"))
+
+		got, ok := cache.Verification("repo", "nginx", "1.0.0")
+		assert.True(t, ok)
+		assert.Nil(t, got)
+
+		_, ok = cache.Verification("repo", "redis", "1.0.0")
+		assert.False(t, ok)
+	})
+
 	t.Run("Stats tracks hits and misses", func(t *testing.T) {
 		cache := NewChartCache(10)
 
@@ -255,7 +314,7 @@ func TestIndexCache_Stats_Concurrent(t *testing.T) {
 
 	// Pre-populate some entries
 	for i := 0; i < 10; i++ {
-		cache.Put("https://repo-"+strconv.Itoa(i)+".com", &repo.IndexFile{})
+		cache.Put("https://repo-"+strconv.Itoa(i)+".com", &chartIndex{})
 	}
 
 	var wg sync.WaitGroup
@@ -345,6 +404,71 @@ func TestChartCache_Stats_Concurrent(t *testing.T) {
 	assert.Greater(t, stats.Hits+stats.Misses, uint64(0), "should have recorded operations")
 }
 
+func TestFailureCache(t *testing.T) {
+	notFound := &ChartNotFoundError{Repository: "https://example.com", Chart: "nginx"}
+
+	t.Run("cached failures wrap the original error", func(t *testing.T) {
+		cache := newFailureCache(10, time.Minute)
+		key := makeChartKey("https://example.com", "nginx", "")
+
+		_, ok := cache.Get(key)
+		assert.False(t, ok)
+
+		cache.Put(key, notFound)
+		err, ok := cache.Get(key)
+		require.True(t, ok)
+		assert.True(t, IsChartNotFound(err))
+		assert.Contains(t, err.Error(), "cached failure")
+		assert.WithinDuration(t, time.Now().Add(time.Minute), err.ExpiresAt, time.Second)
+
+		stats := cache.Stats()
+		assert.Equal(t, uint64(1), stats.Hits)
+		assert.Equal(t, uint64(1), stats.Misses)
+		assert.Equal(t, 1, stats.Size)
+	})
+
+	t.Run("failures expire after the TTL", func(t *testing.T) {
+		cache := newFailureCache(10, 20*time.Millisecond)
+		cache.Put("key", notFound)
+
+		require.Eventually(t, func() bool {
+			_, ok := cache.Get("key")
+			return !ok
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("cached failures are not cached again", func(t *testing.T) {
+		cache := newFailureCache(10, time.Minute)
+		cache.Put("key", &CachedFailureError{Err: notFound, ExpiresAt: time.Now()})
+
+		assert.Zero(t, cache.Len())
+	})
+
+	t.Run("invalidate repository", func(t *testing.T) {
+		cache := newFailureCache(10, time.Minute)
+		cache.Put(makeChartKey("https://example.com", "", ""), notFound)
+		cache.Put(makeChartKey("https://example.com", "nginx", "1.0.0"), notFound)
+		cache.Put(makeChartKey("https://example.com/other", "nginx", ""), notFound)
+
+		cache.InvalidateRepository("https://example.com")
+
+		assert.Equal(t, 1, cache.Len())
+		_, ok := cache.Get(makeChartKey("https://example.com/other", "nginx", ""))
+		assert.True(t, ok)
+	})
+
+	t.Run("zero TTL disables the cache", func(t *testing.T) {
+		cache := newFailureCache(10, 0)
+		require.Nil(t, cache)
+
+		cache.Put("key", notFound)
+		_, ok := cache.Get("key")
+		assert.False(t, ok)
+		assert.Zero(t, cache.Len())
+		cache.Clear()
+	})
+}
+
 func TestMakeChartKey_NoCollision(t *testing.T) {
 	// These inputs previously collided when using \x00 as separator.
 	// With length-prefixed encoding, they must produce distinct keys.
@@ -500,3 +624,80 @@ func TestRepoLockManager(t *testing.T) {
 		assert.Equal(t, 0, count, "locks map should be empty after all concurrent unlocks")
 	})
 }
+
+func TestCacheByteBudget(t *testing.T) {
+	makeChart := func(name string, size int) *chartv2.Chart {
+		return &chartv2.Chart{
+			Metadata:  &chartv2.Metadata{Name: name, Version: "1.0.0"},
+			Templates: []*common.File{{Name: "templates/data.yaml", Data: make([]byte, size)}},
+		}
+	}
+	makeIndex := func(charts int) *chartIndex {
+		index := &repo.IndexFile{Entries: map[string]repo.ChartVersions{}}
+		for i := range charts {
+			name := "chart" + strconv.Itoa(i)
+			index.Entries[name] = repo.ChartVersions{{
+				Metadata: &chartv2.Metadata{Name: name, Version: "1.0.0"},
+				URLs:     []string{name + "-1.0.0.tgz"},
+			}}
+		}
+		return newChartIndex(index)
+	}
+
+	t.Run("chart cache evicts least recently used by bytes", func(t *testing.T) {
+		chartSize := estimateChartBytes(makeChart("a", 1000))
+		cache := newChartCache(10, 2*chartSize+chartSize/2)
+
+		cache.Put("repo", "a", "1.0.0", makeChart("a", 1000))
+		cache.Put("repo", "b", "1.0.0", makeChart("b", 1000))
+		assert.Equal(t, 2*chartSize, cache.Stats().Bytes)
+
+		cache.Get("repo", "a", "1.0.0") // b is now least recently used
+		cache.Put("repo", "c", "1.0.0", makeChart("c", 1000))
+
+		_, ok := cache.Get("repo", "b", "1.0.0")
+		assert.False(t, ok)
+		_, ok = cache.Get("repo", "a", "1.0.0")
+		assert.True(t, ok)
+		assert.Equal(t, 2, cache.Len())
+		assert.Equal(t, 2*chartSize, cache.Stats().Bytes)
+	})
+
+	t.Run("chart larger than the budget is not cached", func(t *testing.T) {
+		cache := newChartCache(10, 1024)
+
+		cache.Put("repo", "big", "1.0.0", makeChart("big", 4096))
+
+		_, ok := cache.Get("repo", "big", "1.0.0")
+		assert.False(t, ok)
+		assert.Equal(t, int64(0), cache.Stats().Bytes)
+	})
+
+	t.Run("replacing a chart does not double count", func(t *testing.T) {
+		cache := newChartCache(10, 0)
+
+		cache.Put("repo", "a", "1.0.0", makeChart("a", 1000))
+		cache.Put("repo", "a", "1.0.0", makeChart("a", 2000))
+
+		assert.Equal(t, estimateChartBytes(makeChart("a", 2000)), cache.Stats().Bytes)
+		cache.Clear()
+		assert.Equal(t, int64(0), cache.Stats().Bytes)
+	})
+
+	t.Run("index cache evicts least recently used by bytes", func(t *testing.T) {
+		indexSize := estimateIndexBytes(makeIndex(100))
+		cache := newIndexCache(10, 2*indexSize+indexSize/2, time.Minute, 0)
+
+		cache.Put("https://a.example.com", makeIndex(100))
+		cache.Put("https://b.example.com", makeIndex(100))
+		cache.Put("https://c.example.com", makeIndex(100))
+
+		_, ok := cache.Get("https://a.example.com")
+		assert.False(t, ok)
+		assert.Equal(t, 2, cache.Len())
+		assert.Equal(t, 2*indexSize, cache.Stats().Bytes)
+
+		cache.Invalidate("https://b.example.com")
+		assert.Equal(t, indexSize, cache.Stats().Bytes)
+	})
+}
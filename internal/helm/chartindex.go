@@ -0,0 +1,208 @@
+package helm
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	repo "helm.sh/helm/v4/pkg/repo/v1"
+)
+
+// chartIndex is the compact, read-only form of a repository index that is
+// cached in memory. It keeps only the fields the tools use, shares repeated
+// strings (versions, app versions, descriptions, keywords), and is built once
+// so that lookups need neither sorting nor semver parsing.
+type chartIndex struct {
+	names  []string                  // Chart names, sorted
+	charts map[string][]indexVersion // Versions by chart name, newest first
+	size   int64                     // Estimated memory held, in bytes
+}
+
+// indexVersion is one chart version from a repository index.
+type indexVersion struct {
+	Version     string
+	AppVersion  string
+	Description string
+	Keywords    []string
+	Created     time.Time
+	Deprecated  bool
+	Digest      string
+	URLs        []string
+}
+
+// newChartIndex builds a compact index from an index file parsed by Helm's
+// loader. Entries without chart metadata are skipped.
+func newChartIndex(index *repo.IndexFile) *chartIndex {
+	b := newIndexBuilder()
+	for name, entries := range index.Entries {
+		for _, e := range entries {
+			if e == nil || e.Metadata == nil {
+				continue
+			}
+			b.add(name, indexVersion{
+				Version:     e.Version,
+				AppVersion:  e.AppVersion,
+				Description: e.Description,
+				Keywords:    e.Keywords,
+				Created:     e.Created,
+				Deprecated:  e.Deprecated,
+				Digest:      e.Digest,
+				URLs:        e.URLs,
+			}, nil)
+		}
+	}
+	return b.build()
+}
+
+// chartNames returns the names of all charts in the index, sorted.
+func (ix *chartIndex) chartNames() []string {
+	return append([]string(nil), ix.names...)
+}
+
+// versions returns the versions of a chart, newest first. The slice must
+// not be modified.
+func (ix *chartIndex) versions(name string) ([]indexVersion, bool) {
+	versions, ok := ix.charts[name]
+	return versions, ok
+}
+
+// lookup returns the index entry for an exact chart version.
+func (ix *chartIndex) lookup(name, version string) (*indexVersion, bool) {
+	versions := ix.charts[name]
+	for i := range versions {
+		if versions[i].Version == version {
+			return &versions[i], true
+		}
+	}
+	return nil, false
+}
+
+// indexBuilder accumulates chart versions for a chartIndex, interning
+// strings and totalling the memory they hold as it goes.
+type indexBuilder struct {
+	strings map[string]string
+	charts  map[string]*byVersionDesc
+	size    int64
+}
+
+func newIndexBuilder() *indexBuilder {
+	return &indexBuilder{
+		strings: make(map[string]string),
+		charts:  make(map[string]*byVersionDesc),
+	}
+}
+
+// add adds a version of the named chart. parsed is the version's semver, or
+// nil to parse it here; versions that are not valid semver sort last.
+func (b *indexBuilder) add(name string, v indexVersion, parsed *semver.Version) {
+	if parsed == nil {
+		parsed, _ = semver.NewVersion(v.Version)
+	}
+	chart, ok := b.charts[name]
+	if !ok {
+		chart = &byVersionDesc{}
+		b.charts[b.intern(name)] = chart
+		b.size += mapEntryBytes + sliceHeaderBytes + stringHeaderBytes
+	}
+	chart.versions = append(chart.versions, indexVersion{
+		Version:     b.intern(v.Version),
+		AppVersion:  b.intern(v.AppVersion),
+		Description: b.intern(v.Description),
+		Keywords:    b.internAll(v.Keywords),
+		Created:     v.Created,
+		Deprecated:  v.Deprecated,
+		Digest:      b.unique(v.Digest),
+		URLs:        b.uniqueAll(v.URLs),
+	})
+	chart.parsed = append(chart.parsed, parsed)
+	b.size += indexVersionBytes
+}
+
+// build sorts the versions of each chart newest first, matching
+// repo.IndexFile.SortEntries, and returns the finished index.
+func (b *indexBuilder) build() *chartIndex {
+	ix := &chartIndex{
+		names:  make([]string, 0, len(b.charts)),
+		charts: make(map[string][]indexVersion, len(b.charts)),
+		size:   b.size,
+	}
+	for name, chart := range b.charts {
+		sort.Stable(chart)
+		ix.names = append(ix.names, name)
+		ix.charts[name] = slices.Clip(chart.versions)
+	}
+	sort.Strings(ix.names)
+	return ix
+}
+
+// intern returns a shared copy of s, so repeated values are stored once.
+// The copy does not retain the memory s was sliced from.
+func (b *indexBuilder) intern(s string) string {
+	if s == "" {
+		return ""
+	}
+	if shared, ok := b.strings[s]; ok {
+		return shared
+	}
+	s = strings.Clone(s)
+	b.strings[s] = s
+	b.size += int64(len(s))
+	return s
+}
+
+// internAll interns every string in values.
+func (b *indexBuilder) internAll(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]string, len(values))
+	for i, s := range values {
+		out[i] = b.intern(s)
+	}
+	b.size += sliceHeaderBytes + int64(len(values))*stringHeaderBytes
+	return out
+}
+
+// unique copies a string that is not worth interning because it is (nearly)
+// always distinct, such as a digest.
+func (b *indexBuilder) unique(s string) string {
+	b.size += int64(len(s))
+	return strings.Clone(s)
+}
+
+// uniqueAll is unique for a slice of strings.
+func (b *indexBuilder) uniqueAll(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]string, len(values))
+	for i, s := range values {
+		out[i] = b.unique(s)
+	}
+	b.size += sliceHeaderBytes + int64(len(values))*stringHeaderBytes
+	return out
+}
+
+// byVersionDesc sorts versions newest first using their pre-parsed semver,
+// keeping versions that failed to parse (nil) at the end.
+type byVersionDesc struct {
+	versions []indexVersion
+	parsed   []*semver.Version
+}
+
+func (s *byVersionDesc) Len() int { return len(s.versions) }
+
+func (s *byVersionDesc) Swap(i, j int) {
+	s.versions[i], s.versions[j] = s.versions[j], s.versions[i]
+	s.parsed[i], s.parsed[j] = s.parsed[j], s.parsed[i]
+}
+
+func (s *byVersionDesc) Less(i, j int) bool {
+	a, b := s.parsed[i], s.parsed[j]
+	if a == nil || b == nil {
+		return a != nil
+	}
+	return b.LessThan(a)
+}
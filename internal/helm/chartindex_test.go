@@ -0,0 +1,216 @@
+package helm
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+	repo "helm.sh/helm/v4/pkg/repo/v1"
+)
+
+func TestChartIndex(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	index := newChartIndex(&repo.IndexFile{Entries: map[string]repo.ChartVersions{
+		"nginx": {
+			{Metadata: &chartv2.Metadata{Name: "nginx", Version: "1.9.0", AppVersion: "1.25"}},
+			{Metadata: &chartv2.Metadata{Name: "nginx", Version: "not-semver"}},
+			{Metadata: &chartv2.Metadata{Name: "nginx", Version: "1.10.0", AppVersion: "1.25", Description: "Web server", Keywords: []string{"web", "proxy"}, Deprecated: true},
+				Created: created, Digest: "sha256:abc", URLs: []string{"nginx-1.10.0.tgz"}},
+			{Metadata: &chartv2.Metadata{Name: "nginx", Version: "1.10.0-rc.1"}},
+			nil,
+		},
+		"apache":  {{Metadata: &chartv2.Metadata{Name: "apache", Version: "2.0.0"}}},
+		"broken":  {{URLs: []string{"broken-1.0.0.tgz"}}},
+		"alpine":  {{Metadata: &chartv2.Metadata{Name: "alpine", Version: "0.1.0"}}},
+		"missing": nil,
+	}})
+
+	t.Run("names are sorted and skip charts without versions", func(t *testing.T) {
+		assert.Equal(t, []string{"alpine", "apache", "nginx"}, index.chartNames())
+	})
+
+	t.Run("versions are sorted newest first", func(t *testing.T) {
+		versions, ok := index.versions("nginx")
+		require.True(t, ok)
+		got := make([]string, 0, len(versions))
+		for _, v := range versions {
+			got = append(got, v.Version)
+		}
+		assert.Equal(t, []string{"1.10.0", "1.10.0-rc.1", "1.9.0", "not-semver"}, got)
+
+		_, ok = index.versions("broken")
+		assert.False(t, ok)
+	})
+
+	t.Run("lookup keeps the fields the tools use", func(t *testing.T) {
+		v, ok := index.lookup("nginx", "1.10.0")
+		require.True(t, ok)
+		assert.Equal(t, indexVersion{
+			Version:     "1.10.0",
+			AppVersion:  "1.25",
+			Description: "Web server",
+			Keywords:    []string{"web", "proxy"},
+			Created:     created,
+			Deprecated:  true,
+			Digest:      "sha256:abc",
+			URLs:        []string{"nginx-1.10.0.tgz"},
+		}, *v)
+
+		_, ok = index.lookup("nginx", "9.9.9")
+		assert.False(t, ok)
+		_, ok = index.lookup("unknown", "1.0.0")
+		assert.False(t, ok)
+	})
+
+	t.Run("chartNames returns a copy", func(t *testing.T) {
+		names := index.chartNames()
+		names[0] = "changed"
+		assert.Equal(t, "alpine", index.chartNames()[0])
+	})
+
+	t.Run("repeated strings are counted once", func(t *testing.T) {
+		entries := func(appVersion string) repo.ChartVersions {
+			var versions repo.ChartVersions
+			for i := range 10 {
+				versions = append(versions, &repo.ChartVersion{Metadata: &chartv2.Metadata{
+					Version:    "1.0." + strconv.Itoa(i),
+					AppVersion: appVersion,
+				}})
+			}
+			return versions
+		}
+		shared := newChartIndex(&repo.IndexFile{Entries: map[string]repo.ChartVersions{"a": entries("shared-app-version")}})
+		distinct := newChartIndex(&repo.IndexFile{Entries: map[string]repo.ChartVersions{"a": entries("")}})
+		assert.Equal(t, int64(len("shared-app-version")), shared.size-distinct.size)
+	})
+}
+
+// syntheticIndex builds an index with the given number of charts, each with
+// the given number of versions, in the unsorted order Helm's loader returns.
+func syntheticIndex(charts, versions int) *repo.IndexFile {
+	index := &repo.IndexFile{Entries: make(map[string]repo.ChartVersions, charts)}
+	for i := range charts {
+		name := "chart-" + strconv.Itoa(i)
+		entries := make(repo.ChartVersions, 0, versions)
+		for j := range versions {
+			version := "1." + strconv.Itoa(j%20) + "." + strconv.Itoa(j/20)
+			entries = append(entries, &repo.ChartVersion{
+				Metadata: &chartv2.Metadata{
+					APIVersion:  "v2",
+					Name:        name,
+					Version:     version,
+					AppVersion:  "2." + strconv.Itoa(j%5) + ".0",
+					Description: "A synthetic chart used for benchmarking",
+					Keywords:    []string{"benchmark", "synthetic"},
+				},
+				Created: time.Unix(int64(j), 0),
+				Digest:  "sha256:" + strconv.Itoa(i) + "-" + strconv.Itoa(j),
+				URLs:    []string{name + "-" + version + ".tgz"},
+			})
+		}
+		index.Entries[name] = entries
+	}
+	return index
+}
+
+// BenchmarkIndex compares preparing and querying a parsed index as a
+// repo.IndexFile, as the client used to, against the compact chartIndex.
+func BenchmarkIndex(b *testing.B) {
+	const charts, versions = 500, 100
+	lookup := func(i int) (string, string) {
+		return "chart-" + strconv.Itoa(i%charts), "1." + strconv.Itoa(i%20) + ".0"
+	}
+
+	b.Run("build/IndexFile", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			b.StopTimer()
+			index := syntheticIndex(charts, versions)
+			b.StartTimer()
+			index.SortEntries()
+		}
+	})
+	b.Run("build/chartIndex", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			b.StopTimer()
+			index := syntheticIndex(charts, versions)
+			b.StartTimer()
+			newChartIndex(index)
+		}
+	})
+
+	sorted := syntheticIndex(charts, versions)
+	sorted.SortEntries()
+	compact := newChartIndex(syntheticIndex(charts, versions))
+	b.Run("retained/IndexFile", func(b *testing.B) {
+		var n uint64
+		for b.Loop() {
+			n = retainedBytes(func() any { return syntheticIndex(charts, versions) })
+		}
+		b.ReportMetric(float64(n), "retained-B")
+	})
+	b.Run("retained/chartIndex", func(b *testing.B) {
+		var n uint64
+		for b.Loop() {
+			n = retainedBytes(func() any { return newChartIndex(syntheticIndex(charts, versions)) })
+		}
+		b.ReportMetric(float64(n), "retained-B")
+	})
+
+	b.Run("names/IndexFile", func(b *testing.B) {
+		for b.Loop() {
+			names := make([]string, 0, len(sorted.Entries))
+			for name := range sorted.Entries {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+	})
+	b.Run("names/chartIndex", func(b *testing.B) {
+		for b.Loop() {
+			compact.chartNames()
+		}
+	})
+
+	b.Run("lookup/IndexFile", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			name, version := lookup(i)
+			for _, v := range sorted.Entries[name] {
+				if v.Version == version {
+					break
+				}
+			}
+			i++
+		}
+	})
+	b.Run("lookup/chartIndex", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			compact.lookup(lookup(i))
+			i++
+		}
+	})
+}
+
+// retainedBytes returns the heap memory still held by the value build returns
+// after a garbage collection.
+func retainedBytes(build func() any) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	v := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(v)
+	if after.HeapAlloc < before.HeapAlloc {
+		return 0
+	}
+	return after.HeapAlloc - before.HeapAlloc
+}
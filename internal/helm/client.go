@@ -2,36 +2,66 @@ package helm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
-	"helm.sh/helm/v4/pkg/chart/loader"
+	"golang.org/x/sync/singleflight"
 	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/cli"
 	"helm.sh/helm/v4/pkg/downloader"
 	"helm.sh/helm/v4/pkg/getter"
 	"helm.sh/helm/v4/pkg/registry"
 	repo "helm.sh/helm/v4/pkg/repo/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/traceutil"
 )
 
+// maxManifestBytes bounds the size of chart manifests fetched to check cached
+// charts. Helm chart manifests are well under a kilobyte.
+const maxManifestBytes = 1 << 20
+
 // Client implements ChartService for interacting with Helm repositories.
 type Client struct {
 	opts           *clientOptions
 	settings       *cli.EnvSettings
 	indexCache     *IndexCache
 	chartCache     *ChartCache
+	failureCache   *FailureCache // nil when failures are not cached
 	registryClient *registry.Client
+	registryErr    error         // Why registryClient could not be created
+	ociClient      remote.Client // Fetches OCI manifests to check cached charts against
+	dialer         *safeDialer
+	transport      *http.Transport
+	httpClient     *http.Client
+	disk           *diskCache // nil when the disk cache is disabled
+	cosign         *cosignVerifier
+	downloads      singleflight.Group // Coalesces concurrent loads of the same chart version
+	fetches        *fetchLimiter      // Bounds concurrent downloads
+	refreshMu      sync.Mutex
+	refreshes      map[string]indexRefresh // Index refresh state by validated repository URL
 	logger         *zap.Logger
 }
 
-// Ensure Client implements ChartService.
-var _ ChartService = (*Client)(nil)
+// Ensure Client implements ChartService and ChartVerifier.
+var (
+	_ ChartService  = (*Client)(nil)
+	_ ChartVerifier = (*Client)(nil)
+)
 
 // NewClient creates a new Helm client with the given options.
 func NewClient(opts ...Option) *Client {
@@ -43,7 +73,7 @@ func NewClient(opts ...Option) *Client {
 	}
 
 	// Ensure cache directory exists
-	if err := os.MkdirAll(o.cacheDir, 0o755); err != nil {
+	if err := os.MkdirAll(o.cacheDir, 0o700); err != nil {
 		o.logger.Warn("failed to create cache directory", zap.Error(err))
 	}
 
@@ -52,20 +82,73 @@ func NewClient(opts ...Option) *Client {
 	settings.RegistryConfig = filepath.Join(o.cacheDir, "registry.json")
 	settings.RepositoryConfig = filepath.Join(o.cacheDir, "repositories.yaml")
 
-	regClient, err := registry.NewClient(
+	// All outbound connections are checked at dial time, closing the window
+	// between URL validation and the fetch in which DNS could be rebound.
+	validation := ValidationOptions{
+		AllowPrivateIPs: o.allowPrivateIPs,
+		AllowedHosts:    o.allowedHosts,
+		DeniedHosts:     o.deniedHosts,
+		AllowedCIDRs:    o.allowedCIDRs,
+		DeniedCIDRs:     o.deniedCIDRs,
+		resolver:        o.resolver,
+	}
+	dialer := newSafeDialer(validation.allowIP, o.resolver, newProxies(o.proxy))
+	transport := newSafeTransport(dialer, validation)
+	httpClient := &http.Client{Transport: transport}
+
+	regOpts := []registry.ClientOption{
+		registry.ClientOptHTTPClient(httpClient),
 		registry.ClientOptCredentialsFile(settings.RegistryConfig),
 		registry.ClientOptEnableCache(true),
-	)
-	if err != nil {
-		o.logger.Warn("failed to create OCI registry client; OCI operations will be unavailable", zap.Error(err))
+	}
+	if o.plainHTTP {
+		regOpts = append(regOpts, registry.ClientOptPlainHTTP())
+	}
+	regClient, regErr := registry.NewClient(regOpts...)
+	if regErr != nil {
+		o.logger.Warn("failed to create OCI registry client; OCI operations will be unavailable", zap.Error(regErr))
+	}
+
+	ociClient := newRegistryClient(settings.RegistryConfig, httpClient)
+
+	// A verifier without keys rejects every signature, so a bad key file
+	// fails closed rather than silently disabling verification.
+	var cosign *cosignVerifier
+	if o.cosign.mode != VerifyOff {
+		keys, err := loadCosignKeys(o.cosignKeys)
+		if err != nil {
+			o.logger.Warn("failed to load cosign keys; OCI chart signatures will not verify", zap.Error(err))
+		}
+		cosign = newCosignVerifier(keys, ociClient, o.plainHTTP)
+	}
+
+	var disk *diskCache
+	if o.diskCacheBytes > 0 {
+		err := checkPrivateDir(o.cacheDir)
+		if err == nil {
+			disk, err = newDiskCache(filepath.Join(o.cacheDir, "store"), o.diskCacheBytes, o.logger)
+		}
+		if err != nil {
+			o.logger.Warn("failed to create disk cache; caching in memory only", zap.Error(err))
+		}
 	}
 
 	return &Client{
 		opts:           o,
 		settings:       settings,
-		indexCache:     NewIndexCache(o.indexCacheSize, o.indexTTL),
-		chartCache:     NewChartCache(o.chartCacheSize),
+		indexCache:     newIndexCache(o.indexCacheSize, o.indexCacheBytes, o.indexTTL, o.indexMaxStale),
+		chartCache:     newChartCache(o.chartCacheSize, o.chartCacheBytes),
+		failureCache:   newFailureCache(0, o.failureTTL),
 		registryClient: regClient,
+		registryErr:    regErr,
+		ociClient:      ociClient,
+		dialer:         dialer,
+		transport:      transport,
+		httpClient:     &http.Client{Transport: transport, Timeout: o.timeout},
+		disk:           disk,
+		cosign:         cosign,
+		fetches:        newFetchLimiter(o.maxFetches, o.maxHostFetches, o.fetchQueueTimeout),
+		refreshes:      make(map[string]indexRefresh),
 		logger:         o.logger,
 	}
 }
@@ -76,9 +159,50 @@ func (c *Client) validationOpts() ValidationOptions {
 		AllowPrivateIPs: c.opts.allowPrivateIPs,
 		AllowedHosts:    c.opts.allowedHosts,
 		DeniedHosts:     c.opts.deniedHosts,
+		AllowedCIDRs:    c.opts.allowedCIDRs,
+		DeniedCIDRs:     c.opts.deniedCIDRs,
+		resolver:        c.opts.resolver,
 	}
 }
 
+// checkPolicy evaluates the chart policy for a target before any download.
+// When the policy requires signed charts, repositories without an applicable
+// verification policy are refused up front since they can never comply.
+func (c *Client) checkPolicy(t policyTarget) error {
+	p := c.opts.policy
+	if p == nil {
+		return nil
+	}
+	if err := p.evaluate(t); err != nil {
+		return err
+	}
+	if p.RequireSigned && !c.verifiesSignatures(t.repository) {
+		return p.requireSignature(t, nil)
+	}
+	return nil
+}
+
+// verifiesSignatures reports whether charts from the repository are
+// signature-verified: provenance for HTTP repositories, cosign for OCI.
+func (c *Client) verifiesSignatures(repoURL string) bool {
+	if registry.IsOCI(repoURL) {
+		return c.cosign != nil && c.opts.cosign.appliesTo(repoURL)
+	}
+	return c.opts.provenance.appliesTo(repoURL)
+}
+
+// chartTransport returns the transport for a chart download. When pinnedHost
+// is set, a dedicated transport refuses requests (including redirect hops) to
+// any other host; callers should close its idle connections when done.
+func (c *Client) chartTransport(pinnedHost string) *http.Transport {
+	if pinnedHost == "" {
+		return c.transport
+	}
+	t := c.transport.Clone()
+	t.Proxy = requestChecker(c.dialer, c.validationOpts(), pinnedHost)
+	return t
+}
+
 // ListCharts returns all chart names available in the repository.
 func (c *Client) ListCharts(ctx context.Context, repoURL string) ([]string, error) {
 	if registry.IsOCI(repoURL) {
@@ -94,46 +218,30 @@ func (c *Client) ListCharts(ctx context.Context, repoURL string) ([]string, erro
 		return nil, err
 	}
 
-	seen := make(map[string]bool)
-	for _, versions := range index.Entries {
-		for _, v := range versions {
-			if v != nil && v.Name != "" && !seen[v.Name] {
-				seen[v.Name] = true
-				break
-			}
-		}
-	}
-
-	charts := make([]string, 0, len(seen))
-	for name := range seen {
-		charts = append(charts, name)
-	}
-	sort.Strings(charts)
-
-	return charts, nil
+	return index.chartNames(), nil
 }
 
 // ListVersions returns all versions of a chart with metadata.
 func (c *Client) ListVersions(ctx context.Context, repoURL, chart string) ([]ChartVersion, error) {
+	validatedURL, err := c.validateRepository(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkPolicy(policyTarget{repository: validatedURL, chart: chart}); err != nil {
+		return nil, err
+	}
+
 	if registry.IsOCI(repoURL) {
-		return c.ociListVersions(ctx, repoURL, chart)
+		return c.ociListVersions(ctx, repoURL, validatedURL, chart)
 	}
 
-	index, err := c.getIndex(ctx, repoURL, false)
+	entries, err := c.indexVersions(ctx, repoURL, validatedURL, chart)
 	if err != nil {
 		return nil, err
 	}
 
-	entries, ok := index.Entries[chart]
-	if !ok {
-		return nil, &ChartNotFoundError{Repository: repoURL, Chart: chart}
-	}
-
 	versions := make([]ChartVersion, 0, len(entries))
 	for _, entry := range entries {
-		if entry == nil || entry.Metadata == nil {
-			continue
-		}
 		versions = append(versions, ChartVersion{
 			Version:    entry.Version,
 			AppVersion: entry.AppVersion,
@@ -146,30 +254,50 @@ func (c *Client) ListVersions(ctx context.Context, repoURL, chart string) ([]Cha
 }
 
 // GetLatestVersion returns the latest version string for a chart.
+// With a chart policy, the latest version the policy allows is returned.
 func (c *Client) GetLatestVersion(ctx context.Context, repoURL, chart string) (string, error) {
+	validatedURL, err := c.validateRepository(ctx, repoURL)
+	if err != nil {
+		return "", err
+	}
+	if err := c.checkPolicy(policyTarget{repository: validatedURL, chart: chart}); err != nil {
+		return "", err
+	}
+
+	var versions []ChartVersion
 	if registry.IsOCI(repoURL) {
-		versions, err := c.ociListVersions(ctx, repoURL, chart)
+		versions, err = c.ociListVersions(ctx, repoURL, validatedURL, chart)
 		if err != nil {
 			return "", err
 		}
-		if len(versions) == 0 {
-			return "", &ChartNotFoundError{Repository: repoURL, Chart: chart}
+	} else {
+		entries, err := c.indexVersions(ctx, repoURL, validatedURL, chart)
+		if err != nil {
+			return "", err
+		}
+		// Index entries are sorted by version (newest first)
+		for _, entry := range entries {
+			versions = append(versions, ChartVersion{Version: entry.Version, Deprecated: entry.Deprecated})
 		}
-		return versions[0].Version, nil
-	}
-
-	index, err := c.getIndex(ctx, repoURL, false)
-	if err != nil {
-		return "", err
 	}
-
-	entries, ok := index.Entries[chart]
-	if !ok || len(entries) == 0 {
+	if len(versions) == 0 {
 		return "", &ChartNotFoundError{Repository: repoURL, Chart: chart}
 	}
+	if c.opts.policy == nil {
+		return versions[0].Version, nil
+	}
 
-	// Index entries are sorted by version (newest first)
-	return entries[0].Version, nil
+	var firstErr error
+	for _, v := range versions {
+		err := c.checkPolicy(policyTarget{repository: validatedURL, chart: chart, version: v.Version, deprecated: v.Deprecated})
+		if err == nil {
+			return v.Version, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", firstErr
 }
 
 // GetValues returns the values.yaml contents for a chart.
@@ -240,12 +368,142 @@ func (c *Client) GetDependencies(ctx context.Context, repoURL, chartName, versio
 	return extractDependencies(hc)
 }
 
+// VerifyChart returns the signature verification result for a chart version,
+// downloading the chart if it is not already cached. It returns nil if no
+// verification policy applies to the repository.
+func (c *Client) VerifyChart(ctx context.Context, repoURL, chartName, version string) (*Verification, error) {
+	if registry.IsOCI(repoURL) {
+		return c.ociVerifyChart(ctx, repoURL, chartName, version)
+	}
+
+	validatedURL, err := ValidateRepoURL(ctx, repoURL, c.validationOpts())
+	if err != nil {
+		return nil, err
+	}
+	if !c.opts.provenance.appliesTo(validatedURL) {
+		return nil, nil
+	}
+
+	if v, ok := c.chartCache.Verification(validatedURL, chartName, version); ok {
+		return v, nil
+	}
+	if _, err := c.loadHelmChart(ctx, validatedURL, chartName, version); err != nil {
+		return nil, err
+	}
+	v, _ := c.chartCache.Verification(validatedURL, chartName, version)
+	return v, nil
+}
+
+// verifyConcurrency bounds the OCI signature checks VerifyVersions runs at
+// once. Each check also waits for a download slot of the registry's host.
+const verifyConcurrency = 4
+
+// VerifyVersions returns verification results for several chart versions.
+// OCI signatures are checked against the registry without pulling the chart,
+// and a check that fails is reported in that version's result;
+// provenance results are only reported for charts already downloaded.
+func (c *Client) VerifyVersions(ctx context.Context, repoURL, chartName string, versions []string) (map[string]*Verification, error) {
+	results := make(map[string]*Verification, len(versions))
+
+	if registry.IsOCI(repoURL) {
+		validatedURL, err := ValidateOCIURL(ctx, repoURL, c.validationOpts())
+		if err != nil {
+			return nil, err
+		}
+		if c.cosign == nil || !c.opts.cosign.appliesTo(validatedURL) {
+			return results, nil
+		}
+
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+		sem := make(chan struct{}, verifyConcurrency)
+		for _, version := range versions {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				v := c.ociVerifyVersion(ctx, validatedURL, chartName, version)
+				mu.Lock()
+				results[version] = v
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		return results, nil
+	}
+
+	validatedURL, err := ValidateRepoURL(ctx, repoURL, c.validationOpts())
+	if err != nil {
+		return nil, err
+	}
+	if !c.opts.provenance.appliesTo(validatedURL) {
+		return results, nil
+	}
+	for _, version := range versions {
+		if v, ok := c.chartCache.Verification(validatedURL, chartName, version); ok && v != nil {
+			results[version] = v
+		}
+	}
+	return results, nil
+}
+
+// validateRepository validates the repository URL of a version listing, as
+// an OCI registry URL for oci:// URLs and as an HTTP repository URL otherwise.
+func (c *Client) validateRepository(ctx context.Context, repoURL string) (string, error) {
+	if registry.IsOCI(repoURL) {
+		if c.registryClient == nil {
+			return "", &RepositoryError{URL: repoURL, Op: "list_versions", Message: "OCI registry client is not available"}
+		}
+		return ValidateOCIURL(ctx, repoURL, c.validationOpts())
+	}
+	return ValidateRepoURL(ctx, repoURL, c.validationOpts())
+}
+
+// indexVersions returns the index entries of a chart, newest first.
+func (c *Client) indexVersions(ctx context.Context, repoURL, validatedURL, chart string) ([]indexVersion, error) {
+	key := makeChartKey(validatedURL, chart, "")
+	if err, ok := c.failureCache.Get(key); ok {
+		return nil, err
+	}
+
+	index, err := c.getIndex(ctx, validatedURL, false)
+	if err != nil {
+		return nil, err
+	}
+	entries, ok := index.versions(chart)
+	if !ok {
+		err := &ChartNotFoundError{Repository: repoURL, Chart: chart}
+		c.recordFailure(ctx, key, err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// recordFailure caches a chart-not-found result or repository failure under
+// key. Failures caused by the caller's own cancellation are not cached.
+func (c *Client) recordFailure(ctx context.Context, key string, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	if IsChartNotFound(err) || IsRepositoryError(err) || IsIndexTooLarge(err) {
+		c.failureCache.Put(key, err)
+	}
+}
+
 // getIndex retrieves the repository index, using cache if available.
-func (c *Client) getIndex(ctx context.Context, repoURL string, forceRefresh bool) (*repo.IndexFile, error) {
+// Repository failures are cached, and served while an expired index
+// within the max stale age is not available.
+func (c *Client) getIndex(ctx context.Context, repoURL string, forceRefresh bool) (_ *chartIndex, err error) {
+	ctx, span := traceutil.StartSpan(ctx, tracerName, "helm.getIndex", attribute.Bool("mcp_helm.index.force_refresh", forceRefresh))
+	defer func() { traceutil.EndSpan(span, err) }()
+
 	validatedURL, err := ValidateRepoURL(ctx, repoURL, c.validationOpts())
 	if err != nil {
 		return nil, err
 	}
+	span.SetAttributes(attribute.String("url.full", validatedURL))
 
 	// Acquire per-repo lock
 	unlock := c.indexCache.LockRepo(validatedURL)
@@ -254,40 +512,33 @@ func (c *Client) getIndex(ctx context.Context, repoURL string, forceRefresh bool
 	// Check cache first
 	if !forceRefresh {
 		if index, ok := c.indexCache.Get(validatedURL); ok {
+			span.SetAttributes(attribute.Bool("mcp_helm.cache.hit", true))
 			return index, nil
 		}
+		if c.opts.staleRevalidate {
+			if index, ok := c.staleIndex(validatedURL); ok {
+				span.SetAttributes(attribute.Bool("mcp_helm.cache.hit", true))
+				c.revalidateIndex(validatedURL)
+				return index, nil
+			}
+		}
 	}
 
-	// Fetch index
-	c.logger.Debug("fetching repository index", zap.String("url", validatedURL))
-
-	chartRepo, err := repo.NewChartRepository(&repo.Entry{
-		Name: sanitizeRepoName(validatedURL),
-		URL:  validatedURL,
-	}, getter.All(c.settings, getter.WithTimeout(c.opts.timeout)))
-	if err != nil {
-		return nil, &RepositoryError{URL: validatedURL, Op: "create", Message: "failed to create repository", Err: err}
-	}
-	chartRepo.CachePath = c.settings.RepositoryCache
-
-	res := runWithContext(ctx, func() (string, error) {
-		return chartRepo.DownloadIndexFile()
-	})
-	defer res.Wait()
-	if res.Err != nil {
-		return nil, &RepositoryError{URL: validatedURL, Op: "fetch", Message: "failed to download index", Err: res.Err}
+	key := makeChartKey(validatedURL, "", "")
+	if !forceRefresh {
+		if err, ok := c.failureCache.Get(key); ok {
+			if index, ok := c.staleIndex(validatedURL); ok {
+				return index, nil
+			}
+			return nil, err
+		}
 	}
-	indexPath := res.Val
 
-	index, err := repo.LoadIndexFile(indexPath)
+	index, err := c.refreshIndex(ctx, validatedURL, forceRefresh)
 	if err != nil {
-		return nil, &RepositoryError{URL: validatedURL, Op: "parse", Message: "failed to parse index", Err: err}
+		c.recordFailure(ctx, key, err)
 	}
-
-	index.SortEntries()
-	c.indexCache.Put(validatedURL, index)
-
-	return index, nil
+	return index, err
 }
 
 // loadHelmChart loads a chart, using cache if available.
@@ -301,11 +552,32 @@ func (c *Client) loadHelmChart(ctx context.Context, repoURL, chartName, version
 		return nil, err
 	}
 
+	target := policyTarget{repository: validatedURL, chart: chartName, version: version}
+	if err := c.checkPolicy(target); err != nil {
+		return nil, err
+	}
+
 	// Check cache first
 	if chart, ok := c.chartCache.Get(validatedURL, chartName, version); ok {
 		return chart, nil
 	}
+	key := makeChartKey(validatedURL, chartName, version)
+	if err, ok := c.failureCache.Get(key); ok {
+		return nil, err
+	}
 
+	chart, err := c.coalesceLoad(ctx, validatedURL, chartName, version, func(ctx context.Context) (*chartv2.Chart, error) {
+		return c.downloadHelmChart(ctx, validatedURL, chartName, version, target)
+	})
+	if err != nil {
+		c.recordFailure(ctx, key, err)
+	}
+	return chart, err
+}
+
+// downloadHelmChart resolves a chart version in the repository index,
+// downloads it and adds it to the cache.
+func (c *Client) downloadHelmChart(ctx context.Context, validatedURL, chartName, version string, target policyTarget) (*chartv2.Chart, error) {
 	// Get index to find chart URL
 	index, err := c.getIndex(ctx, validatedURL, false)
 	if err != nil {
@@ -313,30 +585,26 @@ func (c *Client) loadHelmChart(ctx context.Context, repoURL, chartName, version
 	}
 
 	// Find chart version
-	var chartVersion *repo.ChartVersion
-	entries, ok := index.Entries[chartName]
+	chartVersion, ok := index.lookup(chartName, version)
 	if !ok {
 		return nil, &ChartNotFoundError{Repository: validatedURL, Chart: chartName, Version: version}
 	}
 
-	for _, entry := range entries {
-		if entry.Version == version {
-			chartVersion = entry
-			break
-		}
-	}
-	if chartVersion == nil {
-		return nil, &ChartNotFoundError{Repository: validatedURL, Chart: chartName, Version: version}
+	// Deprecation is only known from the index entry
+	target.deprecated = chartVersion.Deprecated
+	if err := c.checkPolicy(target); err != nil {
+		return nil, err
 	}
 
 	if len(chartVersion.URLs) == 0 {
 		return nil, &RepositoryError{URL: validatedURL, Op: "load", Message: "no download URLs for chart"}
 	}
 
-	// Resolve chart URL
-	chartURL := chartVersion.URLs[0]
-	if !strings.HasPrefix(chartURL, "http://") && !strings.HasPrefix(chartURL, "https://") {
-		chartURL = strings.TrimSuffix(validatedURL, "/") + "/" + strings.TrimPrefix(chartURL, "/")
+	// Resolve chart URL the same way Helm does, so relative references
+	// ("../", "//host/...") are validated as the URL actually fetched.
+	chartURL, err := repo.ResolveReferenceURL(validatedURL, chartVersion.URLs[0])
+	if err != nil {
+		return nil, &RepositoryError{URL: validatedURL, Op: "load", Message: "invalid chart URL in index", Err: err}
 	}
 
 	// Validate chart URL
@@ -345,48 +613,86 @@ func (c *Client) loadHelmChart(ctx context.Context, repoURL, chartName, version
 		return nil, err
 	}
 
-	// Download chart
-	c.logger.Debug("downloading chart",
-		zap.String("chart", chartName),
-		zap.String("version", version),
-		zap.String("url", validatedChartURL),
-	)
+	// Optionally pin the download, including redirects, to the repository host
+	var pinnedHost string
+	if c.opts.restrictChartHost {
+		pinnedHost = repoHost(validatedURL)
+		if !strings.EqualFold(repoHost(validatedChartURL), pinnedHost) {
+			return nil, &URLValidationError{URL: validatedChartURL, Reason: "chart downloads are restricted to repository host " + pinnedHost}
+		}
+	}
+	transport := c.chartTransport(pinnedHost)
+	defer transport.CloseIdleConnections()
 
 	tempDir, err := os.MkdirTemp("", "mcp-helm-chart-")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
-
-	dl := downloader.ChartDownloader{
-		Out:              io.Discard,
-		Getters:          getter.All(c.settings),
-		Options:          []getter.Option{getter.WithTimeout(c.opts.timeout)},
-		RepositoryConfig: c.settings.RepositoryConfig,
-		RepositoryCache:  c.settings.RepositoryCache,
-		ContentCache:     c.settings.ContentCache,
-		Verify:           downloader.VerifyNever,
-	}
-
-	res := runWithContext(ctx, func() (string, error) {
-		path, _, err := dl.DownloadTo(validatedChartURL, version, tempDir)
-		return path, err
-	})
-	// Wait for the goroutine to finish before removing tempDir,
+	wait := func() {}
+	// Wait for any download goroutine to finish before removing tempDir,
 	// even if context was cancelled, to avoid a directory race.
 	defer func() {
-		res.Wait()
+		wait()
 		if err := os.RemoveAll(tempDir); err != nil {
 			c.logger.Warn("failed to clean up temp directory",
 				zap.String("path", tempDir),
 				zap.Error(err))
 		}
 	}()
-	if res.Err != nil {
-		return nil, &RepositoryError{URL: validatedURL, Op: "download", Message: "failed to download chart", Err: res.Err}
+
+	// Charts on disk are keyed by the index digest too, so a version that is
+	// republished with different content is downloaded again.
+	verify := c.opts.provenance.appliesTo(validatedURL)
+	diskKey := makeChartKey(validatedURL, chartName, version) + chartVersion.Digest
+	chartPath, fromDisk := c.restoreHelmChart(diskKey, chartVersion.Digest, tempDir, chartName, version, verify)
+	if !fromDisk {
+		c.logger.Debug("downloading chart",
+			zap.String("chart", chartName),
+			zap.String("version", version),
+			zap.String("url", validatedChartURL),
+		)
+
+		// VerifyLater fetches the .prov file alongside the chart (if published)
+		// without failing the download; verification itself happens below so that
+		// unsigned and invalid charts can be told apart.
+		dl := downloader.ChartDownloader{
+			Out:              io.Discard,
+			Getters:          getter.All(c.settings),
+			Options:          []getter.Option{getter.WithTimeout(c.opts.timeout), getter.WithTransport(transport)},
+			RepositoryConfig: c.settings.RepositoryConfig,
+			RepositoryCache:  c.settings.RepositoryCache,
+			ContentCache:     c.settings.ContentCache,
+			Verify:           downloader.VerifyNever,
+		}
+		if verify {
+			dl.Verify = downloader.VerifyLater
+		}
+
+		release, err := c.waitFetch(ctx, validatedChartURL)
+		if err != nil {
+			return nil, err
+		}
+		ctx, done := c.startFetch(ctx, validatedChartURL, FetchChart)
+		res := runWithContext(ctx, func() (string, error) {
+			defer release()
+			path, _, err := dl.DownloadTo(validatedChartURL, version, tempDir)
+			return path, err
+		})
+		wait = res.Wait
+		if res.Err != nil {
+			done(0, res.Err)
+			return nil, &RepositoryError{URL: validatedURL, Op: "download", Message: "failed to download chart", Err: res.Err}
+		}
+		chartPath = res.Val
+		var size int64
+		if fi, err := os.Stat(chartPath); err == nil {
+			size = fi.Size()
+		}
+		done(size, nil)
 	}
-	chartPath := res.Val
 
-	// Check chart file size before decompression
+	// Check chart file size before decompression; the decompressed size is
+	// bounded by the archive limits while loading
 	if c.opts.maxChartBytes > 0 {
 		fi, err := os.Stat(chartPath)
 		if err != nil {
@@ -397,23 +703,105 @@ func (c *Client) loadHelmChart(ctx context.Context, repoURL, chartName, version
 		}
 	}
 
+	// Verify provenance before the archive is decompressed
+	var verification *Verification
+	if verify {
+		verification = verifyProvenance(chartPath, c.opts.keyring)
+		c.logger.Debug("verified chart provenance",
+			zap.String("chart", chartName),
+			zap.String("version", version),
+			zap.String("status", string(verification.Status)),
+			zap.String("signer", verification.Signer),
+		)
+		if c.opts.provenance.mode == VerifyEnforce && !verification.Verified() {
+			return nil, &VerificationError{Repository: validatedURL, Chart: chartName, Version: version, Verification: verification}
+		}
+	}
+	if err := c.opts.policy.requireSignature(target, verification); err != nil {
+		return nil, err
+	}
+
 	// Load chart
-	loaded, err := loader.Load(chartPath)
+	data, err := os.ReadFile(chartPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load chart: %w", err)
+		return nil, fmt.Errorf("failed to read downloaded chart: %w", err)
+	}
+	chart, err := c.loadArchive(ctx, data)
+	if err != nil {
+		return nil, err
 	}
 
-	chart, ok := loaded.(*chartv2.Chart)
-	if !ok {
-		return nil, fmt.Errorf("unsupported chart format")
+	if !fromDisk && archiveMatches(data, chartVersion.Digest) {
+		c.storeHelmChart(diskKey, chartPath, data, verify)
 	}
 
 	// Cache and return
-	c.chartCache.Put(validatedURL, chartName, version, chart)
+	c.chartCache.PutVerified(validatedURL, chartName, version, chart, verification)
 
 	return chart, nil
 }
 
+// restoreHelmChart writes a chart archive (and its provenance file, if one
+// was published) from the disk cache into dir. It reports false if the chart
+// is not cached, or if provenance is needed but was not fetched when the
+// chart was cached. An archive that does not match digest, the chart's
+// digest in the repository index, is dropped from the cache.
+func (c *Client) restoreHelmChart(key, digest, dir, chartName, version string, needProvenance bool) (string, bool) {
+	e, data, ok := c.disk.get(diskKindChart, key)
+	if !ok || (needProvenance && !e.ProvenanceFetched) {
+		return "", false
+	}
+	if !archiveMatches(data, digest) {
+		c.disk.drop(diskKindChart, key, "cached chart does not match the index digest")
+		return "", false
+	}
+	chartPath := filepath.Join(dir, filepath.Base(chartName)+"-"+filepath.Base(version)+".tgz")
+	if err := os.WriteFile(chartPath, data, 0o600); err != nil {
+		return "", false
+	}
+	if e.Provenance != "" {
+		prov, ok := c.disk.blob(e.Provenance)
+		if !ok || os.WriteFile(chartPath+".prov", prov, 0o600) != nil {
+			return "", false
+		}
+	}
+	c.logger.Debug("loaded chart from disk cache",
+		zap.String("chart", chartName),
+		zap.String("version", version),
+	)
+	return chartPath, true
+}
+
+// storeHelmChart adds a downloaded chart archive, and its provenance file if
+// one was fetched, to the disk cache.
+func (c *Client) storeHelmChart(key, chartPath string, data []byte, provenanceFetched bool) {
+	if c.disk == nil {
+		return
+	}
+	e := diskEntry{ProvenanceFetched: provenanceFetched, FetchedAt: time.Now()}
+	if prov, err := os.ReadFile(chartPath + ".prov"); err == nil {
+		digest, err := c.disk.putBlob(prov)
+		if err != nil {
+			c.logger.Warn("failed to cache provenance file", zap.Error(err))
+			return
+		}
+		e.Provenance = digest
+	}
+	if err := c.disk.put(diskKindChart, key, data, e); err != nil {
+		c.logger.Warn("failed to cache chart on disk", zap.Error(err))
+	}
+}
+
+// archiveMatches reports whether a chart archive matches its digest in a
+// repository index, a hex SHA-256 digest with an optional "sha256:" prefix.
+// Charts indexed without a digest match any archive.
+func archiveMatches(data []byte, digest string) bool {
+	if digest == "" {
+		return true
+	}
+	return strings.EqualFold(blobDigest(data), "sha256:"+strings.TrimPrefix(digest, "sha256:"))
+}
+
 // ociRef builds an OCI reference from a validated OCI URL and chart name.
 // e.g. oci://ghcr.io/traefik/helm + traefik → ghcr.io/traefik/helm/traefik
 func ociRef(validatedURL, chartName string) string {
@@ -429,24 +817,40 @@ func ociRefVersioned(validatedURL, chartName, version string) string {
 }
 
 // ociListVersions lists chart versions from an OCI registry using Tags().
-func (c *Client) ociListVersions(ctx context.Context, repoURL, chartName string) ([]ChartVersion, error) {
+func (c *Client) ociListVersions(ctx context.Context, repoURL, validatedURL, chartName string) ([]ChartVersion, error) {
 	if c.registryClient == nil {
 		return nil, &RepositoryError{URL: repoURL, Op: "list_versions", Message: "OCI registry client is not available"}
 	}
 
-	validatedURL, err := ValidateOCIURL(ctx, repoURL, c.validationOpts())
-	if err != nil {
+	key := makeChartKey(validatedURL, chartName, "")
+	if err, ok := c.failureCache.Get(key); ok {
 		return nil, err
 	}
 
+	versions, err := c.ociTagVersions(ctx, repoURL, validatedURL, chartName)
+	if err != nil {
+		c.recordFailure(ctx, key, err)
+	}
+	return versions, err
+}
+
+// ociTagVersions lists the semver tags of an OCI chart, newest first.
+func (c *Client) ociTagVersions(ctx context.Context, repoURL, validatedURL, chartName string) ([]ChartVersion, error) {
 	ref := ociRef(validatedURL, chartName)
 
 	c.logger.Debug("listing OCI tags", zap.String("ref", ref))
 
-	res := runWithContext(ctx, func() ([]string, error) {
+	release, err := c.waitFetch(ctx, validatedURL)
+	if err != nil {
+		return nil, err
+	}
+	fetchCtx, done := c.startFetch(ctx, validatedURL, FetchOCITags)
+	res := runWithContext(fetchCtx, func() ([]string, error) {
+		defer release()
 		return c.registryClient.Tags(ref)
 	})
 	defer res.Wait()
+	done(0, res.Err)
 	if res.Err != nil {
 		return nil, &RepositoryError{URL: repoURL, Op: "list_versions", Message: "failed to list OCI tags", Err: res.Err}
 	}
@@ -495,87 +899,267 @@ func (c *Client) ociLoadChart(ctx context.Context, repoURL, chartName, version s
 		return nil, err
 	}
 
+	target := policyTarget{repository: validatedURL, chart: chartName, version: version}
+	if err := c.checkPolicy(target); err != nil {
+		return nil, err
+	}
+
 	// Check cache first
 	if chart, ok := c.chartCache.Get(validatedURL, chartName, version); ok {
 		return chart, nil
 	}
+	key := makeChartKey(validatedURL, chartName, version)
+	if err, ok := c.failureCache.Get(key); ok {
+		return nil, err
+	}
 
+	chart, err := c.coalesceLoad(ctx, validatedURL, chartName, version, func(ctx context.Context) (*chartv2.Chart, error) {
+		return c.ociPullChart(ctx, repoURL, validatedURL, chartName, version, target)
+	})
+	if err != nil {
+		c.recordFailure(ctx, key, err)
+	}
+	return chart, err
+}
+
+// ociPullChart pulls a chart version from an OCI registry and adds it to the cache.
+func (c *Client) ociPullChart(ctx context.Context, repoURL, validatedURL, chartName, version string, target policyTarget) (*chartv2.Chart, error) {
 	ref := ociRefVersioned(validatedURL, chartName, version)
 
+	// The disk cache is keyed by manifest digest, so resolve the tag first
+	// (a single manifest request) to find out whether the content changed.
+	var data []byte
+	var digest, diskKey string
+	if c.disk != nil {
+		release, err := c.waitFetch(ctx, validatedURL)
+		if err != nil {
+			return nil, err
+		}
+		fetchCtx, done := c.startFetch(ctx, validatedURL, FetchManifest)
+		manifestDigest, layerDigest, err := c.resolveOCIChart(fetchCtx, ref)
+		release()
+		done(0, err)
+		if err == nil {
+			digest = manifestDigest
+			diskKey = makeChartKey(validatedURL, chartName, version) + digest
+			if _, cached, ok := c.disk.get(diskKindChart, diskKey); ok {
+				if blobDigest(cached) == layerDigest {
+					c.logger.Debug("loaded OCI chart from disk cache", zap.String("ref", ref))
+					data = cached
+				} else {
+					c.disk.drop(diskKindChart, diskKey, "cached OCI chart does not match the manifest's chart layer")
+				}
+			}
+		}
+	}
+
+	fromDisk := data != nil
+	if !fromDisk {
+		pulled, pulledDigest, err := c.ociPull(ctx, repoURL, ref, target)
+		if err != nil {
+			return nil, err
+		}
+		data, digest = pulled, pulledDigest
+		diskKey = makeChartKey(validatedURL, chartName, version) + digest
+	}
+
+	// Check chart size before decompression
+	chartSize := int64(len(data))
+	if c.opts.maxChartBytes > 0 && chartSize > c.opts.maxChartBytes {
+		return nil, &ChartTooLargeError{Size: chartSize, Limit: c.opts.maxChartBytes}
+	}
+
+	// Verify cosign signatures against the digest that was actually pulled
+	var verification *Verification
+	if c.cosign != nil && c.opts.cosign.appliesTo(validatedURL) {
+		verification = c.cosignVerify(ctx, ref, digest)
+		c.logger.Debug("verified chart cosign signature",
+			zap.String("chart", chartName),
+			zap.String("version", version),
+			zap.String("status", string(verification.Status)),
+		)
+		if c.opts.cosign.mode == VerifyEnforce && !verification.Verified() {
+			return nil, &VerificationError{Repository: validatedURL, Chart: chartName, Version: version, Verification: verification}
+		}
+	}
+	if err := c.opts.policy.requireSignature(target, verification); err != nil {
+		return nil, err
+	}
+
+	chart, err := c.loadArchive(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Charts restored from disk have not had their deprecation checked yet
+	if fromDisk && chart.Metadata != nil && chart.Metadata.Deprecated {
+		target.deprecated = true
+		if err := c.checkPolicy(target); err != nil {
+			return nil, err
+		}
+	}
+
+	if !fromDisk && c.disk != nil && digest != "" {
+		if err := c.disk.put(diskKindChart, diskKey, data, diskEntry{FetchedAt: time.Now()}); err != nil {
+			c.logger.Warn("failed to cache chart on disk", zap.Error(err))
+		}
+	}
+
+	c.chartCache.PutVerified(validatedURL, chartName, version, chart, verification)
+
+	return chart, nil
+}
+
+// resolveOCIChart fetches the manifest that ref points to, and returns the
+// manifest digest and the digest of its chart layer.
+func (c *Client) resolveOCIChart(ctx context.Context, ref string) (string, string, error) {
+	repository, err := remote.NewRepository(ref)
+	if err != nil {
+		return "", "", err
+	}
+	repository.Client = c.ociClient
+	repository.PlainHTTP = c.opts.plainHTTP
+	desc, rc, err := repository.Manifests().FetchReference(ctx, repository.Reference.Reference)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = rc.Close() }()
+	if desc.Size > maxManifestBytes {
+		return "", "", fmt.Errorf("manifest too large (%d bytes)", desc.Size)
+	}
+	data, err := content.ReadAll(rc, desc)
+	if err != nil {
+		return "", "", err
+	}
+	var m ocispec.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", "", fmt.Errorf("parsing manifest: %w", err)
+	}
+	for _, layer := range m.Layers {
+		if layer.MediaType == registry.ChartLayerMediaType || layer.MediaType == registry.LegacyChartLayerMediaType {
+			return desc.Digest.String(), layer.Digest.String(), nil
+		}
+	}
+	return "", "", errors.New("manifest has no chart layer")
+}
+
+// ociPull pulls a chart from an OCI registry and returns the archive and the
+// manifest digest.
+func (c *Client) ociPull(ctx context.Context, repoURL, ref string, target policyTarget) ([]byte, string, error) {
 	c.logger.Debug("pulling OCI chart",
-		zap.String("chart", chartName),
-		zap.String("version", version),
+		zap.String("chart", target.chart),
+		zap.String("version", target.version),
 		zap.String("ref", ref),
 	)
 
-	res := runWithContext(ctx, func() (*registry.PullResult, error) {
+	release, err := c.waitFetch(ctx, repoURL)
+	if err != nil {
+		return nil, "", err
+	}
+	fetchCtx, done := c.startFetch(ctx, repoURL, FetchOCIPull)
+	res := runWithContext(fetchCtx, func() (*registry.PullResult, error) {
+		defer release()
 		return c.registryClient.Pull(ref, registry.PullOptWithChart(true))
 	})
 	defer res.Wait()
+	var size int64
+	if res.Err == nil && res.Val.Chart != nil {
+		size = int64(len(res.Val.Chart.Data))
+	}
+	done(size, res.Err)
 	if res.Err != nil {
-		return nil, &RepositoryError{URL: repoURL, Op: "download", Message: "failed to pull OCI chart", Err: res.Err}
+		return nil, "", &RepositoryError{URL: repoURL, Op: "download", Message: "failed to pull OCI chart", Err: res.Err}
 	}
 
 	pullResult := res.Val
 	if pullResult.Chart == nil || len(pullResult.Chart.Data) == 0 {
-		return nil, &RepositoryError{URL: repoURL, Op: "download", Message: "OCI pull returned empty chart data"}
+		return nil, "", &RepositoryError{URL: repoURL, Op: "download", Message: "OCI pull returned empty chart data"}
 	}
 
-	// Check chart size before decompression
-	chartSize := int64(len(pullResult.Chart.Data))
-	if c.opts.maxChartBytes > 0 && chartSize > c.opts.maxChartBytes {
-		return nil, &ChartTooLargeError{Size: chartSize, Limit: c.opts.maxChartBytes}
+	// OCI registries have no index, so deprecation is only known from the
+	// chart metadata in the manifest config.
+	if pullResult.Chart.Meta != nil && pullResult.Chart.Meta.Deprecated {
+		target.deprecated = true
+		if err := c.checkPolicy(target); err != nil {
+			return nil, "", err
+		}
 	}
 
-	// Write to temp file for loader.Load
-	tempDir, err := os.MkdirTemp("", "mcp-helm-oci-chart-")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	var digest string
+	if pullResult.Manifest != nil {
+		digest = pullResult.Manifest.Digest
 	}
-	defer func() {
-		if err := os.RemoveAll(tempDir); err != nil {
-			c.logger.Warn("failed to clean up temp directory",
-				zap.String("path", tempDir),
-				zap.Error(err))
+	return pullResult.Chart.Data, digest, nil
+}
+
+// coalesceLoad runs load once for concurrent requests of the same chart
+// version, so that only one download and temp directory is used and all
+// waiters share its result or error.
+//
+// The shared load is detached from the caller's cancellation, since other
+// waiters may still need it; it is bounded by the download timeout instead.
+// Each caller still returns as soon as its own context is done.
+func (c *Client) coalesceLoad(ctx context.Context, validatedURL, chartName, version string, load func(context.Context) (*chartv2.Chart, error)) (*chartv2.Chart, error) {
+	key := makeChartKey(validatedURL, chartName, version)
+	ch := c.downloads.DoChan(key, func() (any, error) {
+		// A previous flight may have filled the cache just before this one started
+		if chart, ok := c.chartCache.Peek(validatedURL, chartName, version); ok {
+			return chart, nil
 		}
-	}()
+		return load(context.WithoutCancel(ctx))
+	})
 
-	chartPath := filepath.Join(tempDir, filepath.Base(chartName)+"-"+filepath.Base(version)+".tgz")
-	if err := os.WriteFile(chartPath, pullResult.Chart.Data, 0o600); err != nil {
-		return nil, fmt.Errorf("failed to write chart data: %w", err)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*chartv2.Chart), nil
 	}
+}
 
-	loaded, err := loader.Load(chartPath)
+// ociVerifyChart returns the cosign verification result for an OCI chart
+// without pulling the chart layers. It returns nil if cosign verification
+// does not apply to the registry.
+func (c *Client) ociVerifyChart(ctx context.Context, repoURL, chartName, version string) (*Verification, error) {
+	validatedURL, err := ValidateOCIURL(ctx, repoURL, c.validationOpts())
 	if err != nil {
-		return nil, fmt.Errorf("failed to load chart: %w", err)
+		return nil, err
 	}
-
-	chart, ok := loaded.(*chartv2.Chart)
-	if !ok {
-		return nil, fmt.Errorf("unsupported chart format")
+	if c.cosign == nil || !c.opts.cosign.appliesTo(validatedURL) {
+		return nil, nil
 	}
+	return c.ociVerifyVersion(ctx, validatedURL, chartName, version), nil
+}
 
-	c.chartCache.Put(validatedURL, chartName, version, chart)
+// ociVerifyVersion returns the cosign verification result for a chart
+// version in a validated registry the verifier applies to, from the chart
+// cache or else by checking its signatures once a download slot is free.
+func (c *Client) ociVerifyVersion(ctx context.Context, validatedURL, chartName, version string) *Verification {
+	if v, ok := c.chartCache.Verification(validatedURL, chartName, version); ok && v != nil {
+		return v
+	}
+	release, err := c.waitFetch(ctx, validatedURL)
+	if err != nil {
+		return failedVerification(err)
+	}
+	defer release()
+	return c.cosignVerify(ctx, ociRefVersioned(validatedURL, chartName, version), "")
+}
 
-	return chart, nil
+// cosignVerify verifies the cosign signatures of an OCI reference. Registry
+// errors are reported as an invalid result so that enforcement fails closed.
+func (c *Client) cosignVerify(ctx context.Context, ref, digest string) *Verification {
+	v, err := c.cosign.verify(ctx, ref, digest)
+	if err != nil {
+		return failedVerification(err)
+	}
+	return v
 }
 
-// sanitizeRepoName converts a URL to a valid filename for use as the Helm repo name.
-// This is necessary because Helm uses the repo name to create cache filenames,
-// and URLs contain characters (like colons) that are invalid in Windows paths.
-func sanitizeRepoName(url string) string {
-	// Replace characters that are invalid in Windows filenames
-	replacer := strings.NewReplacer(
-		"://", "_",
-		":", "_",
-		"/", "_",
-		"\\", "_",
-		"?", "_",
-		"*", "_",
-		"<", "_",
-		">", "_",
-		"|", "_",
-		"\"", "_",
-	)
-	return replacer.Replace(url)
+// failedVerification reports a cosign check that could not be completed.
+func failedVerification(err error) *Verification {
+	return &Verification{Method: MethodCosign, Status: StatusInvalid, Message: err.Error()}
 }
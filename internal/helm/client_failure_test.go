@@ -24,6 +24,7 @@ func (s *FailureSuite) testClient(opts ...Option) ChartService {
 		WithTimeout(5 * time.Second),
 		WithAllowPrivateIPs(true), // Required for httptest servers on localhost
 		WithLogger(zap.NewNop()),
+		WithCacheDir(s.T().TempDir()),
 	}
 	return NewClient(append(defaults, opts...)...)
 }
@@ -207,6 +208,27 @@ entries:
 	}
 }
 
+func (s *FailureSuite) TestOversizedIndex_ReturnsError() {
+	index := "apiVersion: v1\nentries: {}\n" + strings.Repeat("# padding\n", 100)
+	for _, chunked := range []bool{false, true} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/x-yaml")
+			if chunked {
+				// Without a Content-Length the limit is enforced while reading
+				w.(http.Flusher).Flush()
+			}
+			_, _ = w.Write([]byte(index))
+		}))
+
+		client := s.testClient(WithMaxIndexBytes(100))
+		_, err := client.ListCharts(context.Background(), server.URL)
+		server.Close()
+
+		s.Require().Error(err, "Oversized index should return error")
+		s.True(IsIndexTooLarge(err), "Should be IndexTooLargeError, got: %T: %v", err, err)
+	}
+}
+
 // =============================================================================
 // Content-Type Tests
 // =============================================================================
@@ -0,0 +1,574 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClient_CoalescesDownloads(t *testing.T) {
+	const callers = 10
+	archive := buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n")
+
+	newClient := func(t *testing.T) *Client {
+		return NewClient(
+			WithTimeout(5*time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+		)
+	}
+
+	// getConcurrently starts callers concurrent GetValues calls, releases the
+	// gated download once the first request has arrived and the other callers
+	// have had time to issue their own, and returns every caller's result.
+	getConcurrently := func(t *testing.T, client *Client, repo *chartRepo) ([][]byte, []error) {
+		t.Helper()
+
+		values := make([][]byte, callers)
+		errs := make([]error, callers)
+		var wg sync.WaitGroup
+		for i := range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				values[i], errs[i] = client.GetValues(context.Background(), repo.URL, "app", "1.0.0")
+			}()
+		}
+
+		require.Eventually(t, func() bool { return repo.downloads.Load() > 0 }, 5*time.Second, 5*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		close(repo.gate)
+		wg.Wait()
+		return values, errs
+	}
+
+	t.Run("concurrent callers share one download", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		repo.gate = make(chan struct{})
+		client := newClient(t)
+
+		values, errs := getConcurrently(t, client, repo)
+		for i := range callers {
+			require.NoError(t, errs[i])
+			assert.Equal(t, "replicaCount: 1\n", string(values[i]))
+		}
+		assert.Equal(t, int64(1), repo.downloads.Load())
+	})
+
+	t.Run("concurrent callers share the error", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		repo.gate = make(chan struct{})
+		repo.fail = true
+		client := newClient(t)
+
+		_, errs := getConcurrently(t, client, repo)
+		for i := range callers {
+			require.Error(t, errs[i])
+			assert.True(t, IsRepositoryError(errs[i]), "expected RepositoryError, got %T: %v", errs[i], errs[i])
+		}
+		assert.Equal(t, int64(1), repo.downloads.Load())
+
+		// Failures are not remembered; the next call downloads again
+		repo.fail = false
+		_, err := client.GetValues(context.Background(), repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), repo.downloads.Load())
+	})
+
+	t.Run("cancelled caller does not cancel the shared download", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		repo.gate = make(chan struct{})
+		client := newClient(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		leaderErr := make(chan error, 1)
+		go func() {
+			_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+			leaderErr <- err
+		}()
+		require.Eventually(t, func() bool { return repo.downloads.Load() > 0 }, 5*time.Second, 5*time.Millisecond)
+
+		waiterDone := make(chan error, 1)
+		go func() {
+			_, err := client.GetValues(context.Background(), repo.URL, "app", "1.0.0")
+			waiterDone <- err
+		}()
+
+		cancel()
+		assert.ErrorIs(t, <-leaderErr, context.Canceled)
+
+		close(repo.gate)
+		require.NoError(t, <-waiterDone)
+		assert.Equal(t, int64(1), repo.downloads.Load())
+	})
+}
+
+func TestClient_DiskCache(t *testing.T) {
+	ctx := context.Background()
+	archive := buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n")
+
+	newClient := func(cacheDir string, opts ...Option) *Client {
+		return NewClient(append([]Option{
+			WithTimeout(5 * time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(cacheDir),
+			WithLogger(zap.NewNop()),
+		}, opts...)...)
+	}
+
+	t.Run("restarted client is served from disk", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		cacheDir := t.TempDir()
+
+		_, err := newClient(cacheDir).GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+
+		values, err := newClient(cacheDir).GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+		assert.Contains(t, string(values), "replicaCount: 1")
+		assert.Equal(t, int64(1), repo.indexHits.Load())
+		assert.Equal(t, int64(1), repo.downloads.Load())
+	})
+
+	t.Run("expired index is refetched", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		cacheDir := t.TempDir()
+
+		_, err := newClient(cacheDir, WithIndexTTL(time.Second)).GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+		time.Sleep(1100 * time.Millisecond)
+		_, err = newClient(cacheDir, WithIndexTTL(time.Second)).GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), repo.indexHits.Load())
+		assert.Equal(t, int64(1), repo.downloads.Load(), "chart versions are immutable")
+	})
+
+	t.Run("chart not matching the index digest is downloaded again", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		repo.digest = strings.TrimPrefix(blobDigest(archive), "sha256:")
+		cacheDir := t.TempDir()
+
+		client := newClient(cacheDir)
+		_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+
+		// Replace the cached archive, ref and all
+		key := makeChartKey(repo.URL, "app", "1.0.0") + repo.digest
+		poisoned := buildChartArchive(t, "app", "1.0.0", "replicaCount: 99\n")
+		require.NoError(t, client.disk.put(diskKindChart, key, poisoned, diskEntry{FetchedAt: time.Now()}))
+
+		values, err := newClient(cacheDir).GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, "replicaCount: 1\n", string(values))
+		assert.Equal(t, int64(2), repo.downloads.Load())
+
+		_, data, ok := client.disk.get(diskKindChart, key)
+		require.True(t, ok, "the download is cached again")
+		assert.Equal(t, archive, data)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		cacheDir := t.TempDir()
+
+		for range 2 {
+			_, err := newClient(cacheDir, WithDiskCacheSize(0)).GetValues(ctx, repo.URL, "app", "1.0.0")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int64(2), repo.downloads.Load())
+		assert.NoDirExists(t, filepath.Join(cacheDir, "store"))
+	})
+}
+
+func TestClient_ConditionalIndexRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	newClient := func(t *testing.T) *Client {
+		return NewClient(
+			WithTimeout(5*time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+		)
+	}
+
+	// expire backdates the cached index so that the next load refreshes it.
+	expire := func(t *testing.T, client *Client, url string) {
+		t.Helper()
+		e, _, ok := client.disk.get(diskKindIndex, url)
+		require.True(t, ok)
+		e.FetchedAt = time.Now().Add(-time.Hour)
+		require.NoError(t, client.disk.update(diskKindIndex, *e))
+	}
+
+	t.Run("unchanged index is revalidated with ETag", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", buildChartArchive(t, "app", "1.0.0", ""))
+		client := newClient(t)
+
+		_, err := client.loadIndex(ctx, repo.URL, false)
+		require.NoError(t, err)
+		e, _, _ := client.disk.get(diskKindIndex, repo.URL)
+		require.NotEmpty(t, e.ETag)
+		expire(t, client, repo.URL)
+
+		loaded, err := client.loadIndex(ctx, repo.URL, false)
+		require.NoError(t, err)
+		_, ok := loaded.index.lookup("app", "1.0.0")
+		assert.True(t, ok)
+		assert.Equal(t, int64(2), repo.indexHits.Load())
+
+		stats := client.indexCache.Stats()
+		assert.Equal(t, uint64(1), stats.NotModified)
+		assert.Equal(t, uint64(e.Size), stats.BytesSaved)
+
+		// The 304 restarted the TTL
+		_, err = client.loadIndex(ctx, repo.URL, false)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), repo.indexHits.Load())
+	})
+
+	t.Run("unchanged index is revalidated with Last-Modified", func(t *testing.T) {
+		modTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		var requests, notModified atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests.Add(1)
+			if req.Header.Get("If-Modified-Since") != "" {
+				notModified.Add(1)
+			}
+			http.ServeContent(w, req, "index.yaml", modTime, strings.NewReader("apiVersion: v1\nentries: {}\n"))
+		}))
+		t.Cleanup(server.Close)
+		client := newClient(t)
+
+		_, err := client.loadIndex(ctx, server.URL, false)
+		require.NoError(t, err)
+		expire(t, client, server.URL)
+		_, err = client.loadIndex(ctx, server.URL, false)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(2), requests.Load())
+		assert.Equal(t, int64(1), notModified.Load())
+		assert.Equal(t, uint64(1), client.indexCache.Stats().NotModified)
+	})
+
+	t.Run("changed index is downloaded", func(t *testing.T) {
+		var requests atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			n := requests.Add(1)
+			w.Header().Set("ETag", fmt.Sprintf(`"v%d"`, n))
+			http.ServeContent(w, req, "index.yaml", time.Time{}, strings.NewReader("apiVersion: v1\nentries: {}\n"))
+		}))
+		t.Cleanup(server.Close)
+		client := newClient(t)
+
+		_, err := client.loadIndex(ctx, server.URL, false)
+		require.NoError(t, err)
+		expire(t, client, server.URL)
+		_, err = client.loadIndex(ctx, server.URL, false)
+		require.NoError(t, err)
+
+		e, _, ok := client.disk.get(diskKindIndex, server.URL)
+		require.True(t, ok)
+		assert.Equal(t, `"v2"`, e.ETag)
+		assert.Equal(t, uint64(0), client.indexCache.Stats().NotModified)
+	})
+
+	t.Run("index in memory is revalidated without the disk cache", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", buildChartArchive(t, "app", "1.0.0", ""))
+		client := NewClient(
+			WithTimeout(5*time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithDiskCacheSize(0),
+			WithLogger(zap.NewNop()),
+		)
+		validatedURL, err := ValidateRepoURL(ctx, repo.URL, client.validationOpts())
+		require.NoError(t, err)
+
+		first, err := client.getIndex(ctx, repo.URL, false)
+		require.NoError(t, err)
+		e, ok := client.indexCache.peek(validatedURL)
+		require.True(t, ok)
+		require.NotEmpty(t, e.validators.etag)
+		e.fetchedAt = time.Now().Add(-time.Hour)
+		client.indexCache.put(validatedURL, e)
+
+		second, err := client.getIndex(ctx, repo.URL, false)
+		require.NoError(t, err)
+		assert.Same(t, first, second, "the parsed index is reused")
+		assert.Equal(t, int64(2), repo.indexHits.Load())
+		assert.Equal(t, uint64(1), client.indexCache.Stats().NotModified)
+
+		e, _ = client.indexCache.peek(validatedURL)
+		assert.WithinDuration(t, time.Now(), e.fetchedAt, time.Minute, "the 304 restarted the TTL")
+	})
+
+	t.Run("force refresh is conditional", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", buildChartArchive(t, "app", "1.0.0", ""))
+		client := newClient(t)
+
+		_, err := client.loadIndex(ctx, repo.URL, false)
+		require.NoError(t, err)
+		_, err = client.loadIndex(ctx, repo.URL, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(2), repo.indexHits.Load())
+		assert.Equal(t, uint64(1), client.indexCache.Stats().NotModified)
+	})
+}
+
+func TestClient_FailureCache(t *testing.T) {
+	ctx := context.Background()
+
+	newClient := func(t *testing.T, opts ...Option) *Client {
+		return NewClient(append([]Option{
+			WithTimeout(5 * time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+			WithFailureTTL(time.Minute),
+		}, opts...)...)
+	}
+
+	t.Run("repository failures are cached", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		server.down.Store(true)
+		client := newClient(t)
+
+		_, err := client.ListCharts(ctx, server.URL)
+		require.True(t, IsRepositoryError(err), "expected RepositoryError, got %T: %v", err, err)
+		assert.False(t, IsCachedFailure(err))
+
+		_, err = client.ListCharts(ctx, server.URL)
+		assert.True(t, IsRepositoryError(err), "expected RepositoryError, got %T: %v", err, err)
+		assert.True(t, IsCachedFailure(err))
+		assert.Contains(t, err.Error(), "cached failure")
+		assert.Equal(t, int64(1), server.requests.Load())
+
+		// Forced refreshes bypass the failure cache
+		server.down.Store(false)
+		_, err = client.getIndex(ctx, server.URL, true)
+		require.NoError(t, err)
+	})
+
+	t.Run("missing charts and versions are cached", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", nil)
+		repo.fail = true
+		client := newClient(t)
+
+		for range 2 {
+			_, err := client.ListVersions(ctx, repo.URL, "missing")
+			assert.True(t, IsChartNotFound(err), "expected ChartNotFoundError, got %T: %v", err, err)
+			_, err = client.GetValues(ctx, repo.URL, "app", "9.9.9")
+			assert.True(t, IsChartNotFound(err), "expected ChartNotFoundError, got %T: %v", err, err)
+			_, err = client.GetValues(ctx, repo.URL, "app", "1.0.0")
+			assert.True(t, IsRepositoryError(err), "expected RepositoryError, got %T: %v", err, err)
+		}
+		assert.Equal(t, int64(1), repo.downloads.Load())
+		assert.Equal(t, 3, client.failureCache.Len())
+		assert.Equal(t, uint64(3), client.failureCache.Stats().Hits)
+	})
+
+	t.Run("stale index is served over a cached failure", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		client := newClient(t, WithIndexTTL(time.Minute), WithIndexMaxStale(time.Hour))
+
+		_, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		validatedURL, err := ValidateRepoURL(ctx, server.URL, client.validationOpts())
+		require.NoError(t, err)
+		index, _, _ := client.indexCache.GetStale(validatedURL)
+		client.indexCache.PutFetched(validatedURL, index, time.Now().Add(-10*time.Minute))
+		client.failureCache.Put(makeChartKey(validatedURL, "", ""), &RepositoryError{URL: validatedURL, Op: "fetch"})
+
+		charts, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app"}, charts)
+	})
+
+	t.Run("zero TTL disables caching failures", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		server.down.Store(true)
+		client := newClient(t, WithFailureTTL(0))
+
+		for range 2 {
+			_, err := client.ListCharts(ctx, server.URL)
+			assert.False(t, IsCachedFailure(err))
+		}
+		assert.Equal(t, int64(2), server.requests.Load())
+	})
+}
+
+// flakyIndexServer serves a fixed repository index that can be taken down
+// or held up.
+type flakyIndexServer struct {
+	*httptest.Server
+	down     atomic.Bool
+	hold     atomic.Bool   // Index requests wait for release while set
+	release  chan struct{} // Closed to let held requests through
+	requests atomic.Int64
+}
+
+func newFlakyIndexServer(t *testing.T) *flakyIndexServer {
+	t.Helper()
+
+	s := &flakyIndexServer{release: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.requests.Add(1)
+		if s.hold.Load() {
+			select {
+			case <-s.release:
+			case <-req.Context().Done():
+				return
+			}
+		}
+		if s.down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("apiVersion: v1\nentries:\n  app:\n    - name: app\n      version: 1.0.0\n      apiVersion: v2\n      urls:\n        - app-1.0.0.tgz\n"))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestClient_StaleIndex(t *testing.T) {
+	ctx := context.Background()
+
+	newClient := func(cacheDir string, opts ...Option) *Client {
+		return NewClient(append([]Option{
+			WithTimeout(5 * time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(cacheDir),
+			WithLogger(zap.NewNop()),
+			WithIndexTTL(time.Minute),
+		}, opts...)...)
+	}
+
+	// backdate makes the cached index (in memory and on disk) age old.
+	backdate := func(t *testing.T, client *Client, url string, age time.Duration) {
+		t.Helper()
+		validatedURL, err := ValidateRepoURL(ctx, url, client.validationOpts())
+		require.NoError(t, err)
+		fetchedAt := time.Now().Add(-age)
+		if index, _, ok := client.indexCache.GetStale(validatedURL); ok {
+			client.indexCache.PutFetched(validatedURL, index, fetchedAt)
+		}
+		e, _, ok := client.disk.get(diskKindIndex, validatedURL)
+		require.True(t, ok)
+		e.FetchedAt = fetchedAt
+		require.NoError(t, client.disk.update(diskKindIndex, *e))
+	}
+
+	t.Run("stale index is served when refresh fails", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		client := newClient(t.TempDir(), WithIndexMaxStale(time.Hour))
+
+		_, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		backdate(t, client, server.URL, 10*time.Minute)
+		server.down.Store(true)
+
+		charts, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app"}, charts)
+
+		status, err := client.IndexStatus(ctx, server.URL)
+		require.NoError(t, err)
+		require.NotNil(t, status)
+		assert.GreaterOrEqual(t, status.Age, 10*time.Minute)
+		assert.False(t, status.Revalidating)
+		assert.Contains(t, status.RefreshError, "503")
+
+		// Once the repository recovers the index is fresh again
+		server.down.Store(false)
+		_, err = client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		status, err = client.IndexStatus(ctx, server.URL)
+		require.NoError(t, err)
+		assert.Nil(t, status)
+	})
+
+	t.Run("index older than max stale is not served", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		client := newClient(t.TempDir(), WithIndexMaxStale(time.Hour))
+
+		_, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		backdate(t, client, server.URL, 2*time.Hour)
+		server.down.Store(true)
+
+		_, err = client.ListCharts(ctx, server.URL)
+		assert.True(t, IsRepositoryError(err), "expected RepositoryError, got %T: %v", err, err)
+	})
+
+	t.Run("stale serving is off by default", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		client := newClient(t.TempDir())
+
+		_, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		backdate(t, client, server.URL, 10*time.Minute)
+		server.down.Store(true)
+
+		_, err = client.ListCharts(ctx, server.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("stale index on disk is served after restart", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		cacheDir := t.TempDir()
+		client := newClient(cacheDir, WithIndexMaxStale(time.Hour))
+
+		_, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		backdate(t, client, server.URL, 10*time.Minute)
+		server.down.Store(true)
+
+		charts, err := newClient(cacheDir, WithIndexMaxStale(time.Hour)).ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app"}, charts)
+	})
+
+	t.Run("stale index is served while revalidating", func(t *testing.T) {
+		server := newFlakyIndexServer(t)
+		client := newClient(t.TempDir(), WithIndexMaxStale(time.Hour), WithStaleWhileRevalidate(true))
+
+		_, err := client.ListCharts(ctx, server.URL)
+		require.NoError(t, err)
+		backdate(t, client, server.URL, 10*time.Minute)
+		server.hold.Store(true)
+
+		// Every caller gets the stale index at once, and one refresh runs
+		for range 3 {
+			charts, err := client.ListCharts(ctx, server.URL)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"app"}, charts)
+		}
+		require.Eventually(t, func() bool { return server.requests.Load() == 2 }, 5*time.Second, 5*time.Millisecond)
+
+		status, err := client.IndexStatus(ctx, server.URL)
+		require.NoError(t, err)
+		require.NotNil(t, status)
+		assert.True(t, status.Revalidating)
+
+		close(server.release)
+		require.Eventually(t, func() bool {
+			status, err := client.IndexStatus(ctx, server.URL)
+			return err == nil && status == nil
+		}, 5*time.Second, 5*time.Millisecond)
+		assert.Equal(t, int64(2), server.requests.Load())
+	})
+}
@@ -0,0 +1,325 @@
+package helm
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+)
+
+// MethodCosign identifies cosign signature verification in Verification.Method.
+const MethodCosign = "cosign"
+
+// Cosign media types and annotations for key-based signatures.
+const (
+	cosignSimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	cosignSignatureArtifactType  = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	cosignSignatureAnnotation    = "dev.cosignproject.cosign/signature"
+	// cosignSignatureType is the critical.type of a simple signing payload.
+	cosignSignatureType = "cosign container image signature"
+)
+
+// maxSignatureBytes bounds the size of signature manifests and payloads fetched
+// from a registry. Real cosign payloads are well under a kilobyte.
+const maxSignatureBytes = 1 << 20
+
+// cosignResultCacheSize is the number of verified manifest digests remembered.
+const cosignResultCacheSize = 1024
+
+// cosignKey is a trusted public key used to verify cosign signatures.
+type cosignKey struct {
+	name string // Identity reported as the signer, derived from the key file name
+	key  crypto.PublicKey
+}
+
+// cosignVerifier verifies cosign signatures on OCI charts against static public
+// keys. Verification is fully offline with respect to Sigstore: no Fulcio
+// certificates or Rekor transparency log entries are consulted.
+//
+// Signatures are discovered both via the legacy "sha256-<hex>.sig" tag and via
+// OCI referrers with the cosign signature artifact type.
+type cosignVerifier struct {
+	keys      []cosignKey
+	client    remote.Client
+	plainHTTP bool                              // Registries are reached over HTTP
+	verified  *lru.Cache[string, *Verification] // keyed by manifest digest
+}
+
+// newCosignVerifier creates a verifier trusting the given keys, fetching
+// signatures with client, over plain HTTP if plainHTTP is set.
+func newCosignVerifier(keys []cosignKey, client remote.Client, plainHTTP bool) *cosignVerifier {
+	verified, _ := lru.New[string, *Verification](cosignResultCacheSize)
+
+	return &cosignVerifier{
+		keys:      keys,
+		client:    client,
+		plainHTTP: plainHTTP,
+		verified:  verified,
+	}
+}
+
+// newRegistryClient creates a client for registry requests made outside
+// Helm's registry client, with the credentials in the Helm registry config
+// file.
+func newRegistryClient(registryConfig string, httpClient *http.Client) *auth.Client {
+	authClient := &auth.Client{
+		Client: httpClient,
+		Cache:  auth.NewCache(),
+	}
+	if store, err := credentials.NewStore(registryConfig, credentials.StoreOptions{}); err == nil {
+		authClient.Credential = credentials.Credential(store)
+	}
+	return authClient
+}
+
+// loadCosignKeys parses PEM-encoded public keys (as written by `cosign generate-key-pair`).
+func loadCosignKeys(paths []string) ([]cosignKey, error) {
+	keys := make([]cosignKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading cosign key %s: %w", path, err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("cosign key %s: no PEM block found", path)
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cosign key %s: %w", path, err)
+		}
+		switch pub.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		default:
+			return nil, fmt.Errorf("cosign key %s: unsupported key type %T", path, pub)
+		}
+		keys = append(keys, cosignKey{name: filepath.Base(path), key: pub})
+	}
+	return keys, nil
+}
+
+// verify checks the cosign signatures of the manifest referenced by ref
+// (host/repository:tag). If digest is non-empty, the tag must currently
+// resolve to that manifest digest.
+func (v *cosignVerifier) verify(ctx context.Context, ref, digest string) (*Verification, error) {
+	repo, err := remote.NewRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	repo.Client = v.client
+	repo.PlainHTTP = v.plainHTTP
+
+	desc, err := repo.Resolve(ctx, repo.Reference.Reference)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", ref, err)
+	}
+	if digest != "" && desc.Digest.String() != digest {
+		return &Verification{
+			Method:  MethodCosign,
+			Status:  StatusInvalid,
+			Message: fmt.Sprintf("tag resolves to %s, expected %s", desc.Digest, digest),
+		}, nil
+	}
+
+	if cached, ok := v.verified.Get(desc.Digest.String()); ok {
+		return cached, nil
+	}
+
+	sigManifests, err := v.signatureManifests(ctx, repo, desc)
+	if err != nil {
+		return nil, err
+	}
+	if len(sigManifests) == 0 {
+		return &Verification{
+			Method:  MethodCosign,
+			Status:  StatusUnsigned,
+			Message: "no cosign signatures found for chart",
+		}, nil
+	}
+
+	var lastErr error
+	for _, m := range sigManifests {
+		signer, err := v.verifyManifest(ctx, repo, m, desc.Digest.String())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		result := &Verification{
+			Method: MethodCosign,
+			Status: StatusVerified,
+			Signer: signer,
+			Digest: desc.Digest.String(),
+		}
+		// Only successful results are cached: a chart may be signed after it
+		// was first seen, so unsigned and invalid results must be rechecked.
+		v.verified.Add(desc.Digest.String(), result)
+		return result, nil
+	}
+
+	return &Verification{
+		Method:  MethodCosign,
+		Status:  StatusInvalid,
+		Message: lastErr.Error(),
+	}, nil
+}
+
+// signatureManifests returns the cosign signature manifests attached to desc,
+// discovered via referrers and the legacy .sig tag.
+func (v *cosignVerifier) signatureManifests(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor) ([]ocispec.Manifest, error) {
+	var manifests []ocispec.Manifest
+
+	var referrers []ocispec.Descriptor
+	err := repo.Referrers(ctx, desc, cosignSignatureArtifactType, func(page []ocispec.Descriptor) error {
+		referrers = append(referrers, page...)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errdef.ErrNotFound) && !errors.Is(err, errdef.ErrUnsupported) {
+		return nil, fmt.Errorf("listing signature referrers: %w", err)
+	}
+	for _, d := range referrers {
+		m, err := fetchManifest(ctx, repo, d)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+
+	sigTag := strings.Replace(desc.Digest.String(), ":", "-", 1) + ".sig"
+	sigDesc, err := repo.Resolve(ctx, sigTag)
+	switch {
+	case errors.Is(err, errdef.ErrNotFound):
+		// No legacy signature tag
+	case err != nil:
+		return nil, fmt.Errorf("resolving signature tag: %w", err)
+	default:
+		m, err := fetchManifest(ctx, repo, sigDesc)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+
+	return manifests, nil
+}
+
+// verifyManifest checks each simple-signing layer of a signature manifest and
+// returns the name of the key that verified it.
+func (v *cosignVerifier) verifyManifest(ctx context.Context, repo *remote.Repository, m ocispec.Manifest, digest string) (string, error) {
+	lastErr := errors.New("signature manifest has no cosign signature layers")
+	for _, layer := range m.Layers {
+		if layer.MediaType != cosignSimpleSigningMediaType {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(layer.Annotations[cosignSignatureAnnotation])
+		if err != nil || len(sig) == 0 {
+			lastErr = errors.New("signature layer has no valid signature annotation")
+			continue
+		}
+		if layer.Size > maxSignatureBytes {
+			lastErr = fmt.Errorf("signature payload too large (%d bytes)", layer.Size)
+			continue
+		}
+		payload, err := content.FetchAll(ctx, repo.Blobs(), layer)
+		if err != nil {
+			return "", fmt.Errorf("fetching signature payload: %w", err)
+		}
+		if err := checkSimpleSigningPayload(payload, digest); err != nil {
+			lastErr = err
+			continue
+		}
+		name, err := v.verifySignature(payload, sig)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return name, nil
+	}
+	return "", lastErr
+}
+
+// verifySignature checks sig over payload against every trusted key.
+func (v *cosignVerifier) verifySignature(payload, sig []byte) (string, error) {
+	if len(v.keys) == 0 {
+		return "", errors.New("no trusted cosign public keys configured")
+	}
+	sum := sha256.Sum256(payload)
+	for _, k := range v.keys {
+		var ok bool
+		switch pub := k.key.(type) {
+		case *ecdsa.PublicKey:
+			ok = ecdsa.VerifyASN1(pub, sum[:], sig)
+		case *rsa.PublicKey:
+			ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) == nil ||
+				rsa.VerifyPSS(pub, crypto.SHA256, sum[:], sig, nil) == nil
+		case ed25519.PublicKey:
+			ok = ed25519.Verify(pub, payload, sig)
+		}
+		if ok {
+			return k.name, nil
+		}
+	}
+	return "", errors.New("signature does not match any trusted cosign key")
+}
+
+// simpleSigningPayload is the subset of the cosign "simple signing" payload
+// needed to bind a signature to a manifest digest.
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// checkSimpleSigningPayload ensures a signed payload is a cosign image
+// signature for the expected manifest digest.
+func checkSimpleSigningPayload(payload []byte, digest string) error {
+	var p simpleSigningPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("parsing signature payload: %w", err)
+	}
+	if p.Critical.Type != cosignSignatureType {
+		return fmt.Errorf("signature payload has type %q, not %q", p.Critical.Type, cosignSignatureType)
+	}
+	if p.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("signature is for %q, not %q", p.Critical.Image.DockerManifestDigest, digest)
+	}
+	return nil
+}
+
+// fetchManifest fetches and decodes an OCI image manifest.
+func fetchManifest(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor) (ocispec.Manifest, error) {
+	var m ocispec.Manifest
+	if desc.Size > maxSignatureBytes {
+		return m, fmt.Errorf("signature manifest too large (%d bytes)", desc.Size)
+	}
+	data, err := content.FetchAll(ctx, repo, desc)
+	if err != nil {
+		return m, fmt.Errorf("fetching signature manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("parsing signature manifest: %w", err)
+	}
+	return m, nil
+}
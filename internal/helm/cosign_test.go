@@ -0,0 +1,410 @@
+package helm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"helm.sh/helm/v4/pkg/registry"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// fakeRegistry is a minimal in-memory OCI distribution registry serving a
+// single repository.
+type fakeRegistry struct {
+	*httptest.Server
+	repo      string
+	mu        sync.Mutex
+	blobs     map[digest.Digest][]byte
+	manifests map[digest.Digest]ocispec.Manifest
+	tags      map[string]digest.Digest
+	referrers bool // Serve the OCI referrers API
+}
+
+func newFakeRegistry(t *testing.T, repo string) *fakeRegistry {
+	t.Helper()
+
+	r := &fakeRegistry{
+		repo:      repo,
+		blobs:     make(map[digest.Digest][]byte),
+		manifests: make(map[digest.Digest]ocispec.Manifest),
+		tags:      make(map[string]digest.Digest),
+	}
+	r.Server = httptest.NewTLSServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// ociURL returns the oci:// URL of the repository's parent namespace.
+func (r *fakeRegistry) ociURL() string {
+	return "oci://" + strings.TrimPrefix(r.URL, "https://") + "/" + r.repo[:strings.LastIndex(r.repo, "/")]
+}
+
+// connect points client's OCI registry client and cosign verifier at the
+// registry, trusting its test certificate.
+func (r *fakeRegistry) connect(t *testing.T, client *Client) {
+	t.Helper()
+	regClient, err := registry.NewClient(registry.ClientOptHTTPClient(r.Client()))
+	require.NoError(t, err)
+	client.registryClient = regClient
+	client.ociClient = &auth.Client{Client: r.Client()}
+	if client.cosign != nil {
+		client.cosign.client = client.ociClient
+	}
+}
+
+func (r *fakeRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prefix := "/v2/" + r.repo + "/"
+	if req.URL.Path == "/v2/" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, prefix) {
+		http.NotFound(w, req)
+		return
+	}
+	kind, ref, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, prefix), "/")
+
+	switch kind {
+	case "manifests":
+		dgst, ok := r.tags[ref]
+		if !ok {
+			dgst = digest.Digest(ref)
+		}
+		data, ok := r.blobs[dgst]
+		if _, isManifest := r.manifests[dgst]; !ok || !isManifest {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if req.Method != http.MethodHead {
+			_, _ = w.Write(data)
+		}
+	case "blobs":
+		data, ok := r.blobs[digest.Digest(ref)]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if req.Method != http.MethodHead {
+			_, _ = w.Write(data)
+		}
+	case "referrers":
+		if !r.referrers {
+			http.NotFound(w, req)
+			return
+		}
+		index := ocispec.Index{
+			MediaType: ocispec.MediaTypeImageIndex,
+			Manifests: []ocispec.Descriptor{},
+		}
+		index.SchemaVersion = 2
+		for dgst, m := range r.manifests {
+			if m.Subject != nil && m.Subject.Digest.String() == ref {
+				index.Manifests = append(index.Manifests, ocispec.Descriptor{
+					MediaType:    ocispec.MediaTypeImageManifest,
+					ArtifactType: m.ArtifactType,
+					Digest:       dgst,
+					Size:         int64(len(r.blobs[dgst])),
+				})
+			}
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageIndex)
+		_ = json.NewEncoder(w).Encode(index)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (r *fakeRegistry) putBlob(mediaType string, data []byte) ocispec.Descriptor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dgst := digest.FromBytes(data)
+	r.blobs[dgst] = data
+	return ocispec.Descriptor{MediaType: mediaType, Digest: dgst, Size: int64(len(data))}
+}
+
+func (r *fakeRegistry) putManifest(t *testing.T, tag string, m ocispec.Manifest) ocispec.Descriptor {
+	t.Helper()
+
+	m.SchemaVersion = 2
+	m.MediaType = ocispec.MediaTypeImageManifest
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	desc := r.putBlob(ocispec.MediaTypeImageManifest, data)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifests[desc.Digest] = m
+	if tag != "" {
+		r.tags[tag] = desc.Digest
+	}
+	return desc
+}
+
+// pushChart stores a chart archive as a Helm OCI artifact tagged with its version.
+func (r *fakeRegistry) pushChart(t *testing.T, name, version string, archive []byte) ocispec.Descriptor {
+	t.Helper()
+
+	config := r.putBlob(registry.ConfigMediaType, fmt.Appendf(nil, `{"name":%q,"version":%q,"apiVersion":"v2"}`, name, version))
+	layer := r.putBlob(registry.ChartLayerMediaType, archive)
+	return r.putManifest(t, version, ocispec.Manifest{
+		Config: config,
+		Layers: []ocispec.Descriptor{layer},
+	})
+}
+
+// cosignTestKey is a generated ECDSA key with its public half written as PEM.
+type cosignTestKey struct {
+	priv *ecdsa.PrivateKey
+	path string
+}
+
+func newCosignTestKey(t *testing.T, name string) *cosignTestKey {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	return &cosignTestKey{priv: priv, path: path}
+}
+
+// sign attaches a cosign signature over subject (claiming signedDigest) to
+// the registry, via referrers when useReferrers is set and the legacy .sig
+// tag otherwise.
+func (k *cosignTestKey) sign(t *testing.T, r *fakeRegistry, subject ocispec.Descriptor, signedDigest digest.Digest, useReferrers bool) {
+	t.Helper()
+
+	payload := fmt.Appendf(nil, `{"critical":{"identity":{"docker-reference":"%s"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`,
+		r.repo, signedDigest)
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, k.priv, sum[:])
+	require.NoError(t, err)
+
+	layer := r.putBlob(cosignSimpleSigningMediaType, payload)
+	layer.Annotations = map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig)}
+	m := ocispec.Manifest{
+		Config: r.putBlob("application/vnd.oci.image.config.v1+json", []byte("{}")),
+		Layers: []ocispec.Descriptor{layer},
+	}
+
+	if useReferrers {
+		m.ArtifactType = cosignSignatureArtifactType
+		m.Subject = &subject
+		r.putManifest(t, "", m)
+		return
+	}
+	r.putManifest(t, strings.Replace(subject.Digest.String(), ":", "-", 1)+".sig", m)
+}
+
+func TestCheckSimpleSigningPayload(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{"valid", `{"critical":{"image":{"docker-manifest-digest":"` + digest + `"},"type":"cosign container image signature"}}`, ""},
+		{"other digest", `{"critical":{"image":{"docker-manifest-digest":"sha256:00"},"type":"cosign container image signature"}}`, "signature is for"},
+		{"missing type", `{"critical":{"image":{"docker-manifest-digest":"` + digest + `"}}}`, "has type"},
+		{"other type", `{"critical":{"image":{"docker-manifest-digest":"` + digest + `"},"type":"atomic container signature"}}`, "has type"},
+		{"not JSON", `signature`, "parsing signature payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSimpleSigningPayload([]byte(tt.payload), digest)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadCosignKeys(t *testing.T) {
+	key := newCosignTestKey(t, "cosign.pub")
+
+	keys, err := loadCosignKeys([]string{key.path})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "cosign.pub", keys[0].name)
+
+	_, err = loadCosignKeys([]string{filepath.Join(t.TempDir(), "missing.pub")})
+	assert.Error(t, err)
+
+	notPEM := filepath.Join(t.TempDir(), "bad.pub")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a key"), 0o600))
+	_, err = loadCosignKeys([]string{notPEM})
+	assert.ErrorContains(t, err, "no PEM block")
+}
+
+func TestClient_Cosign(t *testing.T) {
+	ctx := context.Background()
+	archive := buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n")
+	key := newCosignTestKey(t, "cosign.pub")
+
+	newClient := func(t *testing.T, reg *fakeRegistry, mode VerificationMode, opts ...Option) *Client {
+		client := NewClient(append([]Option{
+			WithTimeout(5 * time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+			WithCosignMode(mode),
+			WithCosignKeys([]string{key.path}),
+		}, opts...)...)
+
+		reg.connect(t, client)
+		return client
+	}
+
+	t.Run("enforce serves chart signed via sig tag", func(t *testing.T) {
+		reg := newFakeRegistry(t, "charts/app")
+		desc := reg.pushChart(t, "app", "1.0.0", archive)
+		key.sign(t, reg, desc, desc.Digest, false)
+		client := newClient(t, reg, VerifyEnforce)
+
+		values, err := client.GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, "replicaCount: 1\n", string(values))
+
+		v, err := client.VerifyChart(ctx, reg.ociURL(), "app", "1.0.0")
+		require.NoError(t, err)
+		require.NotNil(t, v)
+		assert.Equal(t, MethodCosign, v.Method)
+		assert.Equal(t, StatusVerified, v.Status)
+		assert.Equal(t, "cosign.pub", v.Signer)
+		assert.Equal(t, desc.Digest.String(), v.Digest)
+	})
+
+	t.Run("enforce serves chart signed via referrers", func(t *testing.T) {
+		reg := newFakeRegistry(t, "charts/app")
+		reg.referrers = true
+		desc := reg.pushChart(t, "app", "1.0.0", archive)
+		key.sign(t, reg, desc, desc.Digest, true)
+		client := newClient(t, reg, VerifyEnforce)
+
+		_, err := client.GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+		require.NoError(t, err)
+	})
+
+	t.Run("enforce refuses unsigned chart", func(t *testing.T) {
+		reg := newFakeRegistry(t, "charts/app")
+		reg.pushChart(t, "app", "1.0.0", archive)
+		client := newClient(t, reg, VerifyEnforce)
+
+		_, err := client.GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+		require.Error(t, err)
+		require.True(t, IsVerificationError(err), "expected VerificationError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), string(StatusUnsigned))
+	})
+
+	t.Run("enforce refuses chart signed by untrusted key", func(t *testing.T) {
+		reg := newFakeRegistry(t, "charts/app")
+		desc := reg.pushChart(t, "app", "1.0.0", archive)
+		newCosignTestKey(t, "other.pub").sign(t, reg, desc, desc.Digest, false)
+		client := newClient(t, reg, VerifyEnforce)
+
+		_, err := client.GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+		require.Error(t, err)
+		require.True(t, IsVerificationError(err), "expected VerificationError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), "does not match any trusted cosign key")
+	})
+
+	t.Run("enforce refuses signature for a different digest", func(t *testing.T) {
+		reg := newFakeRegistry(t, "charts/app")
+		desc := reg.pushChart(t, "app", "1.0.0", archive)
+		key.sign(t, reg, desc, digest.FromString("something else"), false)
+		client := newClient(t, reg, VerifyEnforce)
+
+		_, err := client.GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+		require.Error(t, err)
+		require.True(t, IsVerificationError(err), "expected VerificationError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), string(StatusInvalid))
+	})
+
+	t.Run("annotate serves unsigned chart and reports versions", func(t *testing.T) {
+		reg := newFakeRegistry(t, "charts/app")
+		reg.pushChart(t, "app", "1.0.0", archive)
+		signed := reg.pushChart(t, "app", "2.0.0", buildChartArchive(t, "app", "2.0.0", "replicaCount: 2\n"))
+		key.sign(t, reg, signed, signed.Digest, false)
+		client := newClient(t, reg, VerifyAnnotate)
+
+		_, err := client.GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+		require.NoError(t, err)
+
+		results, err := client.VerifyVersions(ctx, reg.ociURL(), "app", []string{"2.0.0", "1.0.0"})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, StatusVerified, results["2.0.0"].Status)
+		assert.Equal(t, StatusUnsigned, results["1.0.0"].Status)
+	})
+
+	t.Run("verify versions reports failed checks per version", func(t *testing.T) {
+		reg := newFakeRegistry(t, "charts/app")
+		var versions []string
+		for i := range 10 {
+			version := fmt.Sprintf("1.0.%d", i)
+			desc := reg.pushChart(t, "app", version, buildChartArchive(t, "app", version, "replicaCount: 1\n"))
+			key.sign(t, reg, desc, desc.Digest, false)
+			versions = append(versions, version)
+		}
+		versions = append(versions, "9.9.9") // Not in the registry
+		client := newClient(t, reg, VerifyAnnotate, WithMaxHostFetches(2))
+
+		results, err := client.VerifyVersions(ctx, reg.ociURL(), "app", versions)
+		require.NoError(t, err)
+		require.Len(t, results, len(versions))
+		for _, version := range versions[:10] {
+			assert.Equal(t, StatusVerified, results[version].Status, version)
+		}
+		assert.Equal(t, StatusInvalid, results["9.9.9"].Status)
+		assert.Contains(t, results["9.9.9"].Message, "9.9.9")
+		assert.Empty(t, client.FetchQueue(), "download slots are freed")
+	})
+
+	t.Run("skipped host is not verified", func(t *testing.T) {
+		reg := newFakeRegistry(t, "charts/app")
+		reg.pushChart(t, "app", "1.0.0", archive)
+		client := newClient(t, reg, VerifyEnforce, WithCosignSkipHosts([]string{"127.0.0.1"}))
+
+		_, err := client.GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+		require.NoError(t, err)
+
+		v, err := client.VerifyChart(ctx, reg.ociURL(), "app", "1.0.0")
+		require.NoError(t, err)
+		assert.Nil(t, v)
+	})
+}
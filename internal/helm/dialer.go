@@ -0,0 +1,235 @@
+package helm
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ipResolver resolves hostnames to IP addresses. It is satisfied by
+// *net.Resolver and replaced in tests.
+type ipResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// safeDialer dials outbound connections only to addresses permitted by the
+// SSRF policy. Validating a URL before fetching is not sufficient on its own:
+// the HTTP client resolves the hostname again when connecting, so a DNS server
+// that answers differently the second time ("DNS rebinding") could steer the
+// connection to an internal address. safeDialer closes that gap by resolving
+// once, checking every address, and dialing the checked IPs directly. The
+// socket-level Control hook re-checks the address actually being connected,
+// so no code path can reach a blocked IP through this dialer.
+//
+// Every redirect hop opens its connection through the same dialer and is
+// therefore checked as well. Configured proxies are the exception: they are
+// chosen by the operator and dialed without checks, and the hosts requested
+// through them are checked instead (see newSafeTransport).
+type safeDialer struct {
+	allowIP     func(net.IP) bool
+	resolver    ipResolver
+	proxies     *proxies // nil when no proxy is used
+	dialer      *net.Dialer
+	proxyDialer *net.Dialer
+}
+
+// newSafeDialer creates a dialer that only connects to IPs accepted by allowIP,
+// or to the given proxies. A nil resolver uses net.DefaultResolver.
+func newSafeDialer(allowIP func(net.IP) bool, resolver ipResolver, proxies *proxies) *safeDialer {
+	d := &safeDialer{
+		allowIP:  allowIP,
+		resolver: resolver,
+		proxies:  proxies,
+	}
+	d.dialer = &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   d.control,
+	}
+	d.proxyDialer = &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return d
+}
+
+// DialContext resolves the address, rejects blocked IPs and connects to the
+// first reachable permitted IP.
+func (d *safeDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.proxies != nil && d.proxies.addrs[address] {
+		return d.proxyDialer.DialContext(ctx, network, address)
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := d.resolve(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// resolve resolves the host of address and returns its IPs, refusing the
+// host outright if any of them is blocked, matching validateHost.
+func (d *safeDialer) resolve(ctx context.Context, address string) ([]net.IP, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		ips, err = resolveHost(ctx, d.resolver, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("host %s resolved to no addresses", host)
+	}
+	for _, ip := range ips {
+		if !d.allowIP(ip) {
+			return nil, &URLValidationError{URL: address, Reason: fmt.Sprintf("connection to blocked IP address %s refused", ip)}
+		}
+	}
+	return ips, nil
+}
+
+// control runs just before the socket connects and checks the literal IP
+// being dialed.
+func (d *safeDialer) control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !d.allowIP(ip) {
+		return &URLValidationError{URL: address, Reason: fmt.Sprintf("connection to blocked IP address %s refused", host)}
+	}
+	return nil
+}
+
+// newSafeTransport returns an HTTP transport whose connections all go through
+// the safe dialer and whose requests, including every redirect hop, are
+// checked against the host allow/deny lists.
+//
+// The transport's Proxy hook is the only per-request callback available on an
+// *http.Transport (which is what Helm's getter accepts), so it checks the
+// requests as well as selecting their proxy. A proxy connects to the hosts
+// requested through it itself, so those are resolved and checked first: by
+// the Proxy hook for requests sent to the proxy as they are, and before the
+// CONNECT request for tunnels. The proxy resolves the host again, so it must
+// be trusted not to be steered by DNS rebinding.
+func newSafeTransport(d *safeDialer, opts ValidationOptions) *http.Transport {
+	return &http.Transport{
+		Proxy:       requestChecker(d, opts, ""),
+		DialContext: d.DialContext,
+		GetProxyConnectHeader: func(ctx context.Context, _ *url.URL, target string) (http.Header, error) {
+			_, err := d.resolve(ctx, target)
+			return nil, err
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		// Helm expects chart archives to be delivered as-is
+		DisableCompression: true,
+		TLSClientConfig:    &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+}
+
+// proxy selects the proxy of a request, if any, checking the host the proxy
+// will connect to unless it is reached through a CONNECT tunnel.
+func (d *safeDialer) proxy(req *http.Request) (*url.URL, error) {
+	if d.proxies == nil {
+		return nil, nil
+	}
+	proxyURL, err := d.proxies.proxyFor(req.URL)
+	if err != nil || proxyURL == nil {
+		return nil, err
+	}
+	tunneled := req.URL.Scheme == "https" && (proxyURL.Scheme == "http" || proxyURL.Scheme == "https")
+	if !tunneled {
+		if _, err := d.resolve(req.Context(), hostPort(req.URL)); err != nil {
+			return nil, err
+		}
+	}
+	return proxyURL, nil
+}
+
+// proxies selects the proxy of each request from an HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY configuration, as http.ProxyFromEnvironment does.
+type proxies struct {
+	proxyFor func(*url.URL) (*url.URL, error)
+	addrs    map[string]bool // host:port of each proxy, as the transport dials it
+}
+
+// newProxies returns the proxies configured by cfg, or nil if there are none.
+func newProxies(cfg *httpproxy.Config) *proxies {
+	p := &proxies{proxyFor: cfg.ProxyFunc(), addrs: make(map[string]bool)}
+	for _, raw := range []string{cfg.HTTPProxy, cfg.HTTPSProxy} {
+		if raw == "" {
+			continue
+		}
+		// As in httpproxy, a proxy without a scheme is an HTTP proxy
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			u, err = url.Parse("http://" + raw)
+		}
+		if err == nil && u.Host != "" {
+			p.addrs[hostPort(u)] = true
+		}
+	}
+	if len(p.addrs) == 0 {
+		return nil
+	}
+	return p
+}
+
+// hostPort returns the host and port of u, with the scheme's default port if
+// it has none.
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// requestChecker returns a Proxy hook that validates each request URL with
+// checkRequestURL and then selects its proxy with d.proxy.
+func requestChecker(d *safeDialer, opts ValidationOptions, pinnedHost string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if err := checkRequestURL(req.URL, opts, pinnedHost); err != nil {
+			return nil, err
+		}
+		return d.proxy(req)
+	}
+}
@@ -0,0 +1,447 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Kinds of entries kept in the disk cache.
+const (
+	diskKindChart = "charts"
+	diskKindIndex = "indexes"
+)
+
+const (
+	// diskLockStale is how long an eviction lock may be held before another
+	// process assumes its owner died and takes it over.
+	diskLockStale = time.Minute
+	// diskTempStale is the age after which leftover temp files are removed.
+	diskTempStale = time.Hour
+)
+
+// diskEntry is the metadata stored for a cached chart archive or index.
+type diskEntry struct {
+	Key               string    `json:"key"`
+	Digest            string    `json:"digest"`                      // Blob digest, "sha256:<hex>"
+	Size              int64     `json:"size"`                        // Blob size in bytes
+	Provenance        string    `json:"provenance,omitempty"`        // Digest of the chart's .prov blob, if one was published
+	ProvenanceFetched bool      `json:"provenanceFetched,omitempty"` // Whether the .prov file was looked for at all
+	ETag              string    `json:"etag,omitempty"`
+	LastModified      string    `json:"lastModified,omitempty"`
+	FetchedAt         time.Time `json:"fetchedAt"`
+}
+
+// diskCache is a persistent, content-addressed store for chart archives and
+// repository indexes that survives restarts.
+//
+// Data is stored once per digest under blobs/, and small JSON refs under
+// refs/<kind>/ map cache keys to digests and fetch metadata. Several processes
+// may share a cache directory: every file is written to a temp file and
+// renamed into place, blobs are immutable, and reads verify the blob digest,
+// discarding entries that are missing or corrupt. Eviction removes the least
+// recently used blobs once the store exceeds maxBytes and is serialized across
+// processes with a lock file.
+type diskCache struct {
+	root     string
+	maxBytes int64
+	logger   *zap.Logger
+
+	mu sync.Mutex // Serializes eviction within the process
+}
+
+// newDiskCache creates the cache directories under root, readable only by
+// the current user. It refuses a root owned by another user, who could plant
+// entries in it.
+func newDiskCache(root string, maxBytes int64, logger *zap.Logger) (*diskCache, error) {
+	for _, dir := range []string{"blobs", filepath.Join("refs", diskKindChart), filepath.Join("refs", diskKindIndex)} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o700); err != nil {
+			return nil, fmt.Errorf("creating disk cache: %w", err)
+		}
+	}
+	if err := checkPrivateDir(root); err != nil {
+		return nil, err
+	}
+	return &diskCache{root: root, maxBytes: maxBytes, logger: logger}, nil
+}
+
+// checkPrivateDir returns an error unless dir is a directory owned by the
+// current user.
+func checkPrivateDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("creating disk cache: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("creating disk cache: %s is not a directory", dir)
+	}
+	if !ownedByCurrentUser(info) {
+		return fmt.Errorf("creating disk cache: %s is not owned by the current user", dir)
+	}
+	return nil
+}
+
+// get returns the entry and data stored for key. It reports false if there is
+// no entry or its data is missing or fails the integrity check.
+func (d *diskCache) get(kind, key string) (*diskEntry, []byte, bool) {
+	if d == nil {
+		return nil, nil, false
+	}
+	refPath := d.refPath(kind, key)
+	raw, err := os.ReadFile(refPath)
+	if err != nil {
+		return nil, nil, false
+	}
+	var e diskEntry
+	if err := json.Unmarshal(raw, &e); err != nil || e.Key != key {
+		d.remove(refPath, "invalid disk cache ref")
+		return nil, nil, false
+	}
+	data, ok := d.blob(e.Digest)
+	if !ok {
+		d.remove(refPath, "disk cache ref points to missing or corrupt blob")
+		return nil, nil, false
+	}
+	d.touch(refPath)
+	return &e, data, true
+}
+
+// blob returns the data stored under digest after verifying it.
+func (d *diskCache) blob(digest string) ([]byte, bool) {
+	path, ok := d.blobPath(digest)
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	if blobDigest(data) != digest {
+		d.remove(path, "disk cache blob failed integrity check")
+		return nil, false
+	}
+	d.touch(path)
+	return data, true
+}
+
+// put stores data for key with the given metadata. Key, Digest and Size are
+// filled in from the arguments.
+func (d *diskCache) put(kind, key string, data []byte, e diskEntry) error {
+	if d == nil {
+		return nil
+	}
+	digest, err := d.putBlob(data)
+	if err != nil {
+		return err
+	}
+	e.Key, e.Digest, e.Size = key, digest, int64(len(data))
+	if err := d.writeRef(kind, key, e); err != nil {
+		return err
+	}
+	d.evict()
+	return nil
+}
+
+// drop removes the entry for key, leaving its blob for eviction, since other
+// entries may share it.
+func (d *diskCache) drop(kind, key, reason string) {
+	if d == nil {
+		return
+	}
+	d.remove(d.refPath(kind, key), reason)
+}
+
+// update replaces the metadata for an existing entry without rewriting its data.
+func (d *diskCache) update(kind string, e diskEntry) error {
+	if d == nil {
+		return nil
+	}
+	return d.writeRef(kind, e.Key, e)
+}
+
+// putBlob stores data under its digest and returns the digest.
+func (d *diskCache) putBlob(data []byte) (string, error) {
+	digest := blobDigest(data)
+	path, _ := d.blobPath(digest)
+	if _, err := os.Stat(path); err == nil {
+		d.touch(path)
+		return digest, nil
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", fmt.Errorf("writing disk cache blob: %w", err)
+	}
+	return digest, nil
+}
+
+// blobWriter streams data to a temp file, hashing it on the way, so that
+// large downloads can be stored without holding them in memory. Write errors
+// are recorded rather than returned, so that a failing disk only costs the
+// cache entry, not the download being copied.
+type blobWriter struct {
+	d    *diskCache // nil when the disk cache is disabled
+	f    *os.File
+	hash hash.Hash
+	size int64
+	err  error // First write error
+	done bool  // Committed or discarded
+}
+
+// create starts a blob in a temp file under the cache directory, or under
+// the system temp directory when the disk cache is disabled, in which case
+// the blob can be read back but commit does not store it. The caller must
+// commit or discard it.
+func (d *diskCache) create() (*blobWriter, error) {
+	dir := os.TempDir()
+	if d != nil {
+		dir = filepath.Join(d.root, "blobs")
+	}
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &blobWriter{d: d, f: f, hash: sha256.New()}, nil
+}
+
+func (w *blobWriter) Write(p []byte) (int, error) {
+	if w.err == nil {
+		if _, err := w.f.Write(p); err != nil {
+			w.err = err
+		}
+		w.hash.Write(p)
+		w.size += int64(len(p))
+	}
+	return len(p), nil
+}
+
+// path returns the temp file holding the data written so far, or the error
+// that stopped it being written.
+func (w *blobWriter) path() (string, error) {
+	return w.f.Name(), w.err
+}
+
+// commit stores the data written for key with the given metadata, as put
+// does. Key, Digest and Size are filled in.
+func (w *blobWriter) commit(kind, key string, e diskEntry) error {
+	if w.d == nil {
+		w.discard()
+		return nil
+	}
+	w.done = true
+	tmp := w.f.Name()
+	err := w.f.Close()
+	if w.err != nil {
+		err = w.err
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("writing disk cache blob: %w", err)
+	}
+
+	digest := "sha256:" + hex.EncodeToString(w.hash.Sum(nil))
+	path, _ := w.d.blobPath(digest)
+	if _, err := os.Stat(path); err == nil {
+		_ = os.Remove(tmp)
+		w.d.touch(path)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("writing disk cache blob: %w", err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("writing disk cache blob: %w", err)
+		}
+	}
+
+	e.Key, e.Digest, e.Size = key, digest, w.size
+	if err := w.d.writeRef(kind, key, e); err != nil {
+		return err
+	}
+	w.d.evict()
+	return nil
+}
+
+// discard removes the temp file, unless the blob was committed.
+func (w *blobWriter) discard() {
+	if w.done {
+		return
+	}
+	w.done = true
+	_ = w.f.Close()
+	_ = os.Remove(w.f.Name())
+}
+
+// writeRef atomically writes the ref for key.
+func (d *diskCache) writeRef(kind, key string, e diskEntry) error {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(d.refPath(kind, key), raw); err != nil {
+		return fmt.Errorf("writing disk cache ref: %w", err)
+	}
+	return nil
+}
+
+// refPath returns the ref file for a key. Keys are hashed so that arbitrary
+// URLs map to safe, fixed-length file names.
+func (d *diskCache) refPath(kind, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.root, "refs", kind, hex.EncodeToString(sum[:])+".json")
+}
+
+// blobPath returns the file for a digest, sharded by the first two hex digits.
+func (d *diskCache) blobPath(digest string) (string, bool) {
+	hexDigest, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok || len(hexDigest) != sha256.Size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hexDigest); err != nil {
+		return "", false
+	}
+	return filepath.Join(d.root, "blobs", hexDigest[:2], hexDigest), true
+}
+
+// evict removes the least recently used blobs until the store fits in
+// maxBytes. Refs to evicted blobs are dropped lazily by get. If another
+// process is already evicting, this is a no-op.
+func (d *diskCache) evict() {
+	if d.maxBytes <= 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	unlock, ok := d.tryLock()
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	type blobFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var (
+		blobs []blobFile
+		total int64
+	)
+	now := time.Now()
+	blobsDir := filepath.Join(d.root, "blobs")
+	_ = filepath.WalkDir(d.root, func(path string, de fs.DirEntry, err error) error {
+		if err != nil || de.IsDir() {
+			return nil
+		}
+		info, err := de.Info()
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(de.Name(), ".tmp-") {
+			if now.Sub(info.ModTime()) > diskTempStale {
+				_ = os.Remove(path)
+			}
+			return nil
+		}
+		if !strings.HasPrefix(path, blobsDir+string(filepath.Separator)) {
+			return nil
+		}
+		blobs = append(blobs, blobFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if total <= d.maxBytes {
+		return
+	}
+
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].modTime.Before(blobs[j].modTime) })
+	for _, b := range blobs {
+		if total <= d.maxBytes {
+			break
+		}
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		total -= b.size
+	}
+	d.logger.Debug("evicted disk cache entries", zap.Int64("size", total), zap.Int64("limit", d.maxBytes))
+}
+
+// tryLock takes the cross-process eviction lock, taking over a lock whose
+// owner appears to have died.
+func (d *diskCache) tryLock() (func(), bool) {
+	path := filepath.Join(d.root, ".evict.lock")
+	for range 2 {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, true
+		}
+		info, statErr := os.Stat(path)
+		if statErr != nil || time.Since(info.ModTime()) < diskLockStale {
+			return nil, false
+		}
+		_ = os.Remove(path)
+	}
+	return nil, false
+}
+
+// touch records a use of the file for LRU eviction.
+func (d *diskCache) touch(path string) {
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+}
+
+// remove deletes a cache file, logging why.
+func (d *diskCache) remove(path, reason string) {
+	d.logger.Warn(reason, zap.String("path", path))
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		d.logger.Warn("failed to remove disk cache file", zap.String("path", path), zap.Error(err))
+	}
+}
+
+// blobDigest returns the content digest of data.
+func blobDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic writes data to a temp file in the target directory and
+// renames it into place, so that readers never observe a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
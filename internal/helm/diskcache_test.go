@@ -0,0 +1,182 @@
+package helm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestDiskCache(t *testing.T, maxBytes int64) *diskCache {
+	t.Helper()
+
+	d, err := newDiskCache(t.TempDir(), maxBytes, zap.NewNop())
+	require.NoError(t, err)
+	return d
+}
+
+func TestDiskCache_PutGet(t *testing.T) {
+	d := newTestDiskCache(t, 1024*1024)
+	fetched := time.Now().Truncate(time.Second)
+
+	require.NoError(t, d.put(diskKindIndex, "https://charts.example.com", []byte("index"), diskEntry{ETag: `"v1"`, FetchedAt: fetched}))
+
+	e, data, ok := d.get(diskKindIndex, "https://charts.example.com")
+	require.True(t, ok)
+	assert.Equal(t, []byte("index"), data)
+	assert.Equal(t, "https://charts.example.com", e.Key)
+	assert.Equal(t, blobDigest([]byte("index")), e.Digest)
+	assert.Equal(t, int64(5), e.Size)
+	assert.Equal(t, `"v1"`, e.ETag)
+	assert.True(t, fetched.Equal(e.FetchedAt))
+
+	_, _, ok = d.get(diskKindChart, "https://charts.example.com")
+	assert.False(t, ok, "kinds are separate namespaces")
+
+	e.ETag = `"v2"`
+	require.NoError(t, d.update(diskKindIndex, *e))
+	e, _, ok = d.get(diskKindIndex, "https://charts.example.com")
+	require.True(t, ok)
+	assert.Equal(t, `"v2"`, e.ETag)
+}
+
+func TestDiskCache_NilIsDisabled(t *testing.T) {
+	var d *diskCache
+
+	require.NoError(t, d.put(diskKindChart, "key", []byte("data"), diskEntry{}))
+	_, _, ok := d.get(diskKindChart, "key")
+	assert.False(t, ok)
+}
+
+func TestDiskCache_Integrity(t *testing.T) {
+	t.Run("corrupt blob is discarded", func(t *testing.T) {
+		d := newTestDiskCache(t, 1024*1024)
+		require.NoError(t, d.put(diskKindChart, "key", []byte("chart"), diskEntry{}))
+
+		path, _ := d.blobPath(blobDigest([]byte("chart")))
+		require.NoError(t, os.WriteFile(path, []byte("tampered"), 0o644))
+
+		_, _, ok := d.get(diskKindChart, "key")
+		assert.False(t, ok)
+		assert.NoFileExists(t, path)
+		assert.NoFileExists(t, d.refPath(diskKindChart, "key"))
+	})
+
+	t.Run("missing blob is discarded", func(t *testing.T) {
+		d := newTestDiskCache(t, 1024*1024)
+		require.NoError(t, d.put(diskKindChart, "key", []byte("chart"), diskEntry{}))
+
+		path, _ := d.blobPath(blobDigest([]byte("chart")))
+		require.NoError(t, os.Remove(path))
+
+		_, _, ok := d.get(diskKindChart, "key")
+		assert.False(t, ok)
+		assert.NoFileExists(t, d.refPath(diskKindChart, "key"))
+	})
+
+	t.Run("malformed ref is discarded", func(t *testing.T) {
+		d := newTestDiskCache(t, 1024*1024)
+		require.NoError(t, os.WriteFile(d.refPath(diskKindChart, "key"), []byte("{"), 0o644))
+
+		_, _, ok := d.get(diskKindChart, "key")
+		assert.False(t, ok)
+		assert.NoFileExists(t, d.refPath(diskKindChart, "key"))
+	})
+}
+
+func TestDiskCache_Evict(t *testing.T) {
+	d := newTestDiskCache(t, 250)
+	old := time.Now().Add(-time.Hour)
+
+	for i := range 2 {
+		key := fmt.Sprintf("chart-%d", i)
+		require.NoError(t, d.put(diskKindChart, key, []byte(fmt.Sprintf("%0100d", i)), diskEntry{}))
+		path, _ := d.blobPath(blobDigest([]byte(fmt.Sprintf("%0100d", i))))
+		require.NoError(t, os.Chtimes(path, old.Add(time.Duration(i)*time.Minute), old.Add(time.Duration(i)*time.Minute)))
+	}
+
+	// Reading chart-0 makes chart-1 the least recently used.
+	_, _, ok := d.get(diskKindChart, "chart-0")
+	require.True(t, ok)
+
+	require.NoError(t, d.put(diskKindChart, "chart-2", []byte(fmt.Sprintf("%0100d", 2)), diskEntry{}))
+
+	_, _, ok = d.get(diskKindChart, "chart-1")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	_, _, ok = d.get(diskKindChart, "chart-0")
+	assert.True(t, ok)
+	_, _, ok = d.get(diskKindChart, "chart-2")
+	assert.True(t, ok)
+}
+
+func TestDiskCache_EvictLock(t *testing.T) {
+	t.Run("held lock skips eviction", func(t *testing.T) {
+		d := newTestDiskCache(t, 50)
+		lock := filepath.Join(d.root, ".evict.lock")
+		require.NoError(t, os.WriteFile(lock, nil, 0o600))
+
+		require.NoError(t, d.put(diskKindChart, "key", make([]byte, 100), diskEntry{}))
+		_, _, ok := d.get(diskKindChart, "key")
+		assert.True(t, ok, "another process owns eviction")
+	})
+
+	t.Run("stale lock is taken over", func(t *testing.T) {
+		d := newTestDiskCache(t, 50)
+		lock := filepath.Join(d.root, ".evict.lock")
+		require.NoError(t, os.WriteFile(lock, nil, 0o600))
+		stale := time.Now().Add(-2 * diskLockStale)
+		require.NoError(t, os.Chtimes(lock, stale, stale))
+
+		require.NoError(t, d.put(diskKindChart, "key", make([]byte, 100), diskEntry{}))
+		_, _, ok := d.get(diskKindChart, "key")
+		assert.False(t, ok)
+		assert.NoFileExists(t, lock)
+	})
+}
+
+func TestDiskCache_SharedAcrossInstances(t *testing.T) {
+	// Separate instances on one directory stand in for separate processes.
+	root := t.TempDir()
+	var wg sync.WaitGroup
+	for range 4 {
+		d, err := newDiskCache(root, 1024*1024, zap.NewNop())
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				key := fmt.Sprintf("chart-%d", j%5)
+				data := []byte(key)
+				assert.NoError(t, d.put(diskKindChart, key, data, diskEntry{}))
+				if _, got, ok := d.get(diskKindChart, key); ok {
+					assert.Equal(t, data, got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDiskCache_Private(t *testing.T) {
+	d := newTestDiskCache(t, 1024*1024)
+	require.NoError(t, d.put(diskKindChart, "key", []byte("chart"), diskEntry{}))
+
+	digest := blobDigest([]byte("chart"))
+	blob, _ := d.blobPath(digest)
+	for _, dir := range []string{filepath.Join(d.root, "blobs"), filepath.Dir(blob), filepath.Join(d.root, "refs", diskKindChart)} {
+		info, err := os.Stat(dir)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o700), info.Mode().Perm(), dir)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	_, err := newDiskCache(file, 1024*1024, zap.NewNop())
+	assert.Error(t, err, "root must be a directory")
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package helm
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// ownedByCurrentUser reports whether the file is owned by the effective user.
+func ownedByCurrentUser(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Geteuid()
+}
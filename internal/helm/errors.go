@@ -4,6 +4,7 @@ package helm
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // Sentinel errors for common conditions.
@@ -56,6 +57,22 @@ func (e *RepositoryError) Is(target error) bool {
 	return target == ErrRepoNotFound
 }
 
+// CachedFailureError wraps a failure that is served from the failure cache
+// instead of querying the repository again.
+type CachedFailureError struct {
+	Err       error
+	ExpiresAt time.Time // When the failure is dropped from the cache
+}
+
+func (e *CachedFailureError) Error() string {
+	retry := max(time.Until(e.ExpiresAt), 0).Round(time.Second)
+	return fmt.Sprintf("%v (cached failure, retry in %s)", e.Err, retry)
+}
+
+func (e *CachedFailureError) Unwrap() error {
+	return e.Err
+}
+
 // ValidationError indicates invalid input.
 type ValidationError struct {
 	Field   string
@@ -106,18 +123,113 @@ func (e *ChartTooLargeError) Error() string {
 	return fmt.Sprintf("chart file size %d bytes exceeds limit %d bytes", e.Size, e.Limit)
 }
 
+// IndexTooLargeError indicates that a repository index exceeds the size limit.
+type IndexTooLargeError struct {
+	Repository string
+	Size       int64 // Zero if the index was cut off at the limit
+	Limit      int64
+}
+
+func (e *IndexTooLargeError) Error() string {
+	if e.Size == 0 {
+		return fmt.Sprintf("repository %q index exceeds limit %d bytes", e.Repository, e.Limit)
+	}
+	return fmt.Sprintf("repository %q index size %d bytes exceeds limit %d bytes", e.Repository, e.Size, e.Limit)
+}
+
+// ArchiveLimitError indicates that a chart archive exceeds a decompression
+// limit, e.g. a small tarball that expands into too much data or too many files.
+type ArchiveLimitError struct {
+	Limit string // Limit that was exceeded: "total size", "file count", "file size", "path depth"
+	Value int64
+	Max   int64
+	Path  string // Offending file, if the limit applies to a single file
+}
+
+func (e *ArchiveLimitError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("chart archive %s %d exceeds limit %d", e.Limit, e.Value, e.Max)
+	}
+	return fmt.Sprintf("chart archive %s %d exceeds limit %d (file %q)", e.Limit, e.Value, e.Max, e.Path)
+}
+
+// VerificationError indicates that a chart failed signature verification
+// while verification is enforced.
+type VerificationError struct {
+	Repository   string
+	Chart        string
+	Version      string
+	Verification *Verification
+}
+
+func (e *VerificationError) Error() string {
+	method, status, msg := "signature", StatusInvalid, ""
+	if e.Verification != nil {
+		method, status, msg = e.Verification.Method, e.Verification.Status, e.Verification.Message
+	}
+	if msg == "" {
+		return fmt.Sprintf("chart %q version %q in repository %q failed %s verification: %s", e.Chart, e.Version, e.Repository, method, status)
+	}
+	return fmt.Sprintf("chart %q version %q in repository %q failed %s verification: %s: %s", e.Chart, e.Version, e.Repository, method, status, msg)
+}
+
+// PolicyError indicates that a chart was refused by the configured chart policy.
+type PolicyError struct {
+	Repository string
+	Chart      string
+	Version    string
+	Rule       string // Name of the violated rule
+	Reason     string
+}
+
+func (e *PolicyError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("chart %q in repository %q denied by policy rule %q: %s", e.Chart, e.Repository, e.Rule, e.Reason)
+	}
+	return fmt.Sprintf("chart %q version %q in repository %q denied by policy rule %q: %s", e.Chart, e.Version, e.Repository, e.Rule, e.Reason)
+}
+
+// FetchQueueTimeoutError indicates that a download waited too long for a free
+// slot under the concurrent download limits.
+type FetchQueueTimeoutError struct {
+	Host   string
+	Waited time.Duration
+}
+
+func (e *FetchQueueTimeoutError) Error() string {
+	return fmt.Sprintf("too many downloads in progress: no slot to download from %q after waiting %s", e.Host, e.Waited)
+}
+
 // IsChartTooLarge returns true if err wraps a ChartTooLargeError.
 func IsChartTooLarge(err error) bool {
 	var e *ChartTooLargeError
 	return errors.As(err, &e)
 }
 
+// IsIndexTooLarge returns true if err wraps an IndexTooLargeError.
+func IsIndexTooLarge(err error) bool {
+	var e *IndexTooLargeError
+	return errors.As(err, &e)
+}
+
+// IsArchiveLimitError returns true if err wraps an ArchiveLimitError.
+func IsArchiveLimitError(err error) bool {
+	var e *ArchiveLimitError
+	return errors.As(err, &e)
+}
+
 // IsChartNotFound returns true if err wraps a ChartNotFoundError.
 func IsChartNotFound(err error) bool {
 	var e *ChartNotFoundError
 	return errors.As(err, &e)
 }
 
+// IsCachedFailure returns true if err wraps a CachedFailureError.
+func IsCachedFailure(err error) bool {
+	var e *CachedFailureError
+	return errors.As(err, &e)
+}
+
 // IsRepositoryError returns true if err wraps a RepositoryError.
 func IsRepositoryError(err error) bool {
 	var e *RepositoryError
@@ -141,3 +253,21 @@ func IsOutputTooLarge(err error) bool {
 	var e *OutputTooLargeError
 	return errors.As(err, &e)
 }
+
+// IsVerificationError returns true if err wraps a VerificationError.
+func IsVerificationError(err error) bool {
+	var e *VerificationError
+	return errors.As(err, &e)
+}
+
+// IsPolicyError returns true if err wraps a PolicyError.
+func IsPolicyError(err error) bool {
+	var e *PolicyError
+	return errors.As(err, &e)
+}
+
+// IsFetchQueueTimeout returns true if err wraps a FetchQueueTimeoutError.
+func IsFetchQueueTimeout(err error) bool {
+	var e *FetchQueueTimeoutError
+	return errors.As(err, &e)
+}
@@ -2,7 +2,9 @@ package helm
 
 import (
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -169,6 +171,76 @@ func TestOutputTooLargeError(t *testing.T) {
 	})
 }
 
+func TestCachedFailureError(t *testing.T) {
+	t.Run("error message marks the failure as cached", func(t *testing.T) {
+		err := &CachedFailureError{
+			Err:       &ChartNotFoundError{Repository: "https://example.com", Chart: "nginx"},
+			ExpiresAt: time.Now().Add(30 * time.Second),
+		}
+
+		assert.Contains(t, err.Error(), `chart "nginx" not found`)
+		assert.Contains(t, err.Error(), "(cached failure, retry in 30s)")
+	})
+
+	t.Run("unwraps to the original error", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", &CachedFailureError{Err: &RepositoryError{URL: "https://example.com", Op: "fetch"}})
+
+		assert.True(t, IsCachedFailure(err))
+		assert.True(t, IsRepositoryError(err))
+		assert.False(t, IsCachedFailure(&RepositoryError{URL: "https://example.com", Op: "fetch"}))
+	})
+}
+
+func TestIndexTooLargeError(t *testing.T) {
+	t.Run("error message", func(t *testing.T) {
+		err := &IndexTooLargeError{Repository: "https://example.com", Size: 5000000, Limit: 2000000}
+		assert.Contains(t, err.Error(), "5000000")
+		assert.Contains(t, err.Error(), "2000000")
+
+		err = &IndexTooLargeError{Repository: "https://example.com", Limit: 2000000}
+		assert.Equal(t, `repository "https://example.com" index exceeds limit 2000000 bytes`, err.Error())
+	})
+
+	t.Run("IsIndexTooLarge helper works", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", &IndexTooLargeError{Limit: 100})
+
+		assert.True(t, IsIndexTooLarge(err))
+		assert.False(t, IsIndexTooLarge(&ChartTooLargeError{Size: 100, Limit: 10}))
+	})
+}
+
+func TestVerificationError(t *testing.T) {
+	t.Run("error message includes status and reason", func(t *testing.T) {
+		err := &VerificationError{
+			Repository: "https://repo.com",
+			Chart:      "nginx",
+			Version:    "1.0.0",
+			Verification: &Verification{
+				Method:  MethodProvenance,
+				Status:  StatusUnsigned,
+				Message: "no provenance file published for chart",
+			},
+		}
+
+		assert.Contains(t, err.Error(), "nginx")
+		assert.Contains(t, err.Error(), "provenance verification: unsigned")
+		assert.Contains(t, err.Error(), "no provenance file")
+	})
+
+	t.Run("nil verification", func(t *testing.T) {
+		err := &VerificationError{Repository: "https://repo.com", Chart: "nginx", Version: "1.0.0"}
+
+		assert.Contains(t, err.Error(), "signature verification: invalid")
+	})
+
+	t.Run("IsVerificationError helper works", func(t *testing.T) {
+		err := &VerificationError{Chart: "nginx"}
+
+		assert.True(t, IsVerificationError(err))
+		assert.False(t, IsVerificationError(errors.New("other error")))
+	})
+}
+
 func TestErrorsAs(t *testing.T) {
 	t.Run("As works with ChartNotFoundError", func(t *testing.T) {
 		err := &ChartNotFoundError{Chart: "nginx", Repository: "https://repo.com"}
@@ -196,3 +268,48 @@ func TestErrorsAs(t *testing.T) {
 		assert.True(t, errors.As(inner, &target))
 	})
 }
+
+func TestPolicyError(t *testing.T) {
+	t.Run("error message names the rule", func(t *testing.T) {
+		err := &PolicyError{
+			Repository: "https://repo.com",
+			Chart:      "nginx",
+			Version:    "1.0.0",
+			Rule:       "no-nginx",
+			Reason:     "matched deny rule",
+		}
+
+		assert.Equal(t, `chart "nginx" version "1.0.0" in repository "https://repo.com" denied by policy rule "no-nginx": matched deny rule`, err.Error())
+	})
+
+	t.Run("without version", func(t *testing.T) {
+		err := &PolicyError{Repository: "https://repo.com", Chart: "nginx", Rule: "default", Reason: "no allow rule matched"}
+
+		assert.NotContains(t, err.Error(), "version")
+		assert.Contains(t, err.Error(), `policy rule "default"`)
+	})
+
+	t.Run("IsPolicyError helper works", func(t *testing.T) {
+		assert.True(t, IsPolicyError(&PolicyError{Chart: "nginx"}))
+		assert.False(t, IsPolicyError(errors.New("other error")))
+	})
+}
+
+func TestArchiveLimitError(t *testing.T) {
+	t.Run("archive-wide limit", func(t *testing.T) {
+		err := &ArchiveLimitError{Limit: "file count", Value: 5001, Max: 5000}
+
+		assert.Equal(t, "chart archive file count 5001 exceeds limit 5000", err.Error())
+	})
+
+	t.Run("per-file limit names the file", func(t *testing.T) {
+		err := &ArchiveLimitError{Limit: "file size", Value: 6000000, Max: 5000000, Path: "app/values.yaml"}
+
+		assert.Equal(t, `chart archive file size 6000000 exceeds limit 5000000 (file "app/values.yaml")`, err.Error())
+	})
+
+	t.Run("IsArchiveLimitError helper works", func(t *testing.T) {
+		assert.True(t, IsArchiveLimitError(fmt.Errorf("wrapped: %w", &ArchiveLimitError{Limit: "total size"})))
+		assert.False(t, IsArchiveLimitError(&ChartTooLargeError{Size: 100, Limit: 10}))
+	})
+}
@@ -0,0 +1,163 @@
+package helm
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/traceutil"
+)
+
+// HostFetches reports the downloads from a host.
+type HostFetches struct {
+	Host    string `json:"host"`
+	Active  int    `json:"active"`  // Downloads in progress
+	Waiting int    `json:"waiting"` // Downloads queued for a free slot
+}
+
+// fetchLimiter bounds the downloads in progress, in total and per host.
+// Downloads over a limit queue for a slot, for at most the queue timeout.
+// It also counts the downloads from each host when unlimited.
+type fetchLimiter struct {
+	global       *semaphore.Weighted // nil without a global limit
+	perHost      int64               // Zero without a per-host limit
+	queueTimeout time.Duration       // Zero waits as long as the context allows
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlots // Hosts with downloads in progress or queued
+}
+
+// hostSlots holds the download slots of one host.
+type hostSlots struct {
+	sem     *semaphore.Weighted // nil without a per-host limit
+	active  int
+	waiting int
+}
+
+// newFetchLimiter creates a fetchLimiter allowing up to global downloads in
+// total and perHost from each host; zero disables a limit.
+func newFetchLimiter(global, perHost int, queueTimeout time.Duration) *fetchLimiter {
+	l := &fetchLimiter{
+		perHost:      int64(max(perHost, 0)),
+		queueTimeout: queueTimeout,
+		hosts:        make(map[string]*hostSlots),
+	}
+	if global > 0 {
+		l.global = semaphore.NewWeighted(int64(global))
+	}
+	return l
+}
+
+// acquire waits for a slot to download from host, and returns the function
+// that frees it. The host's slot is taken before the global one, so that
+// downloads queued for a busy host do not hold slots other hosts could use.
+// Waiting fails with a FetchQueueTimeoutError after the queue timeout, or
+// with the context's error.
+func (l *fetchLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mu.Lock()
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostSlots{}
+		if l.perHost > 0 {
+			h.sem = semaphore.NewWeighted(l.perHost)
+		}
+		l.hosts[host] = h
+	}
+	h.waiting++
+	l.mu.Unlock()
+
+	waitCtx := ctx
+	if l.queueTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, l.queueTimeout)
+		defer cancel()
+	}
+
+	err := acquireSlot(waitCtx, h.sem)
+	if err == nil {
+		if err = acquireSlot(waitCtx, l.global); err != nil {
+			releaseSlot(h.sem)
+		}
+	}
+
+	l.mu.Lock()
+	h.waiting--
+	if err != nil {
+		l.dropIdle(host, h)
+		l.mu.Unlock()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &FetchQueueTimeoutError{Host: host, Waited: l.queueTimeout}
+	}
+	h.active++
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			releaseSlot(l.global)
+			releaseSlot(h.sem)
+			l.mu.Lock()
+			h.active--
+			l.dropIdle(host, h)
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// dropIdle forgets a host without downloads in progress or queued. The
+// caller must hold mu.
+func (l *fetchLimiter) dropIdle(host string, h *hostSlots) {
+	if h.active == 0 && h.waiting == 0 {
+		delete(l.hosts, host)
+	}
+}
+
+// status returns the downloads of each host with downloads in progress or
+// queued, sorted by host.
+func (l *fetchLimiter) status() []HostFetches {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hosts := make([]HostFetches, 0, len(l.hosts))
+	for host, h := range l.hosts {
+		hosts = append(hosts, HostFetches{Host: host, Active: h.active, Waiting: h.waiting})
+	}
+	slices.SortFunc(hosts, func(a, b HostFetches) int { return strings.Compare(a.Host, b.Host) })
+	return hosts
+}
+
+// acquireSlot takes a slot of sem, if it limits anything.
+func acquireSlot(ctx context.Context, sem *semaphore.Weighted) error {
+	if sem == nil {
+		return nil
+	}
+	return sem.Acquire(ctx, 1)
+}
+
+// releaseSlot frees a slot taken by acquireSlot.
+func releaseSlot(sem *semaphore.Weighted) {
+	if sem != nil {
+		sem.Release(1)
+	}
+}
+
+// waitFetch waits for a slot to download from repoURL's host, tracing the
+// wait in a span. The returned function frees the slot; it must be called
+// once the download has finished, even if the caller stopped waiting for it.
+func (c *Client) waitFetch(ctx context.Context, repoURL string) (_ func(), err error) {
+	host := repoHost(repoURL)
+	ctx, span := traceutil.StartSpan(ctx, tracerName, "helm.fetch.wait", attribute.String("server.address", host))
+	defer func() { traceutil.EndSpan(span, err) }()
+	return c.fetches.acquire(ctx, host)
+}
+
+// FetchQueue reports the downloads in progress and queued for each host.
+func (c *Client) FetchQueue() []HostFetches {
+	return c.fetches.status()
+}
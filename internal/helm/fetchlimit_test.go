@@ -0,0 +1,156 @@
+package helm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFetchLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("limits each host", func(t *testing.T) {
+		l := newFetchLimiter(0, 1, 20*time.Millisecond)
+
+		release, err := l.acquire(ctx, "a.example.com")
+		require.NoError(t, err)
+
+		_, err = l.acquire(ctx, "a.example.com")
+		require.Error(t, err)
+		assert.True(t, IsFetchQueueTimeout(err))
+		assert.Contains(t, err.Error(), `"a.example.com"`)
+
+		// Other hosts have their own slots
+		releaseB, err := l.acquire(ctx, "b.example.com")
+		require.NoError(t, err)
+		releaseB()
+
+		release()
+		release() // Freeing twice has no effect
+		release, err = l.acquire(ctx, "a.example.com")
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("limits all hosts", func(t *testing.T) {
+		l := newFetchLimiter(1, 0, 20*time.Millisecond)
+
+		release, err := l.acquire(ctx, "a.example.com")
+		require.NoError(t, err)
+		_, err = l.acquire(ctx, "b.example.com")
+		assert.True(t, IsFetchQueueTimeout(err))
+		release()
+	})
+
+	t.Run("queued downloads get the next free slot", func(t *testing.T) {
+		l := newFetchLimiter(0, 1, time.Second)
+
+		release, err := l.acquire(ctx, "a.example.com")
+		require.NoError(t, err)
+
+		acquired := make(chan error, 1)
+		go func() {
+			next, err := l.acquire(ctx, "a.example.com")
+			if err == nil {
+				next()
+			}
+			acquired <- err
+		}()
+		require.Eventually(t, func() bool {
+			s := l.status()
+			return len(s) == 1 && s[0].Waiting == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, []HostFetches{{Host: "a.example.com", Active: 1, Waiting: 1}}, l.status())
+
+		release()
+		require.NoError(t, <-acquired)
+		assert.Empty(t, l.status(), "idle hosts are forgotten")
+	})
+
+	t.Run("returns the context error when cancelled", func(t *testing.T) {
+		l := newFetchLimiter(0, 1, 0)
+
+		release, err := l.acquire(ctx, "a.example.com")
+		require.NoError(t, err)
+		defer release()
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = l.acquire(cancelled, "a.example.com")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, IsFetchQueueTimeout(err))
+	})
+
+	t.Run("counts unlimited downloads", func(t *testing.T) {
+		l := newFetchLimiter(0, 0, 0)
+
+		r1, err := l.acquire(ctx, "a.example.com")
+		require.NoError(t, err)
+		r2, err := l.acquire(ctx, "a.example.com")
+		require.NoError(t, err)
+		assert.Equal(t, []HostFetches{{Host: "a.example.com", Active: 2}}, l.status())
+		r1()
+		r2()
+		assert.Empty(t, l.status())
+	})
+}
+
+func TestClient_FetchLimits(t *testing.T) {
+	ctx := context.Background()
+
+	// Index requests block until unblock is closed
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+		_, _ = w.Write([]byte("apiVersion: v1\nentries: {}\n"))
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() {
+		select {
+		case <-unblock:
+		default:
+			close(unblock)
+		}
+	})
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	client := NewClient(
+		WithAllowPrivateIPs(true),
+		WithCacheDir(t.TempDir()),
+		WithLogger(zap.NewNop()),
+		WithMaxHostFetches(1),
+		WithFetchQueueTimeout(50*time.Millisecond),
+	)
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.ListCharts(ctx, srv.URL+"/first")
+		first <- err
+	}()
+	require.Eventually(t, func() bool {
+		q := client.FetchQueue()
+		return len(q) == 1 && q[0].Active == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, u.Hostname(), client.FetchQueue()[0].Host)
+
+	// A second repository on the same host times out queueing
+	_, err = client.ListCharts(ctx, srv.URL+"/second")
+	require.Error(t, err)
+	assert.True(t, IsFetchQueueTimeout(err))
+
+	close(unblock)
+	require.NoError(t, <-first)
+
+	// Queue timeouts are not cached as repository failures
+	_, err = client.ListCharts(ctx, srv.URL+"/second")
+	assert.NoError(t, err)
+	assert.Empty(t, client.FetchQueue())
+}
@@ -0,0 +1,110 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// buildChartArchive packages a minimal chart with the given values.yaml and
+// returns the archive bytes.
+func buildChartArchive(t *testing.T, name, version, values string) []byte {
+	t.Helper()
+
+	c := &chartv2.Chart{
+		Metadata: &chartv2.Metadata{
+			APIVersion: chartv2.APIVersionV2,
+			Name:       name,
+			Version:    version,
+		},
+		Raw: []*common.File{{Name: "values.yaml", Data: []byte(values)}},
+	}
+
+	path, err := chartutil.Save(c, t.TempDir())
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+// chartRepo is an httptest-backed Helm repository serving a single chart.
+type chartRepo struct {
+	*httptest.Server
+	name       string
+	version    string
+	archive    []byte
+	prov       []byte        // Served at <archive>.prov when non-nil
+	deprecated bool          // Marks the version deprecated in the index
+	digest     string        // Archive digest listed in the index, if set
+	gate       chan struct{} // Archive downloads wait for it to close when non-nil
+	fail       bool          // Archive downloads return 500 when set
+	downloads  atomic.Int64
+	indexHits  atomic.Int64
+}
+
+// newChartRepo starts a repository server for one chart version.
+func newChartRepo(t *testing.T, name, version string, archive []byte) *chartRepo {
+	t.Helper()
+
+	r := &chartRepo{name: name, version: version, archive: archive}
+	archiveName := fmt.Sprintf("%s-%s.tgz", name, version)
+
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/index.yaml"):
+			r.indexHits.Add(1)
+			index := fmt.Sprintf("apiVersion: v1\nentries:\n  %s:\n    - name: %s\n      version: %q\n      apiVersion: v2\n      deprecated: %t\n      urls:\n        - %s\n",
+				name, name, version, r.deprecated, archiveName)
+			if r.digest != "" {
+				index += fmt.Sprintf("      digest: %s\n", r.digest)
+			}
+			// Serve validators so that clients can refresh conditionally
+			w.Header().Set("Content-Type", "application/x-yaml")
+			w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(index))))
+			http.ServeContent(w, req, "index.yaml", time.Time{}, strings.NewReader(index))
+		case strings.HasSuffix(req.URL.Path, "/"+archiveName+".prov"):
+			if r.prov == nil {
+				http.NotFound(w, req)
+				return
+			}
+			_, _ = w.Write(r.prov)
+		case strings.HasSuffix(req.URL.Path, "/"+archiveName):
+			r.downloads.Add(1)
+			if r.gate != nil {
+				select {
+				case <-r.gate:
+				case <-req.Context().Done():
+					return
+				}
+			}
+			if r.fail {
+				http.Error(w, "boom", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/gzip")
+			_, _ = w.Write(r.archive)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+// archiveName returns the file name of the served chart archive.
+func (r *chartRepo) archiveName() string {
+	return fmt.Sprintf("%s-%s.tgz", r.name, r.version)
+}
@@ -0,0 +1,414 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"helm.sh/helm/v4/pkg/registry"
+	repo "helm.sh/helm/v4/pkg/repo/v1"
+)
+
+// indexUserAgent is sent with repository index requests.
+const indexUserAgent = "mcp-helm"
+
+// indexRefresh is the state of the most recent refresh of a repository index.
+type indexRefresh struct {
+	revalidating bool  // A background refresh is in progress
+	err          error // Why the last refresh failed, nil if it succeeded
+}
+
+// refreshIndex loads the repository index and caches it. If loading fails
+// and an expired index younger than the max stale age is available, that is
+// served instead and the failure is recorded for IndexStatus. The caller must
+// hold the repository lock.
+func (c *Client) refreshIndex(ctx context.Context, validatedURL string, forceRefresh bool) (*chartIndex, error) {
+	e, err := c.loadIndex(ctx, validatedURL, forceRefresh)
+	if err != nil {
+		if ctx.Err() == nil {
+			if stale, ok := c.staleIndex(validatedURL); ok {
+				c.logger.Warn("serving stale repository index after refresh failed",
+					zap.String("url", validatedURL),
+					zap.Error(err),
+				)
+				c.setRefreshError(validatedURL, err)
+				return stale, nil
+			}
+		}
+		return nil, err
+	}
+
+	c.indexCache.put(validatedURL, e)
+	c.setRefreshError(validatedURL, nil)
+	return e.index, nil
+}
+
+// staleIndex returns an expired index that is still within the max stale age,
+// from memory or else from the disk cache. The caller must hold the
+// repository lock.
+func (c *Client) staleIndex(validatedURL string) (*chartIndex, bool) {
+	if c.opts.indexMaxStale <= 0 {
+		return nil, false
+	}
+	if index, fetchedAt, ok := c.indexCache.GetStale(validatedURL); ok {
+		return index, time.Since(fetchedAt) < c.opts.indexMaxStale
+	}
+
+	e, data, ok := c.disk.get(diskKindIndex, validatedURL)
+	if !ok || time.Since(e.FetchedAt) >= c.opts.indexMaxStale {
+		return nil, false
+	}
+	index, err := c.parseCachedIndex(validatedURL, e, data)
+	if err != nil {
+		return nil, false
+	}
+	c.indexCache.put(validatedURL, indexEntry{index: index, fetchedAt: e.FetchedAt, validators: e.validators()})
+	return index, true
+}
+
+// revalidateIndex refreshes a repository index in the background, unless a
+// refresh is already in progress.
+func (c *Client) revalidateIndex(validatedURL string) {
+	c.refreshMu.Lock()
+	state := c.refreshes[validatedURL]
+	if state.revalidating {
+		c.refreshMu.Unlock()
+		return
+	}
+	state.revalidating = true
+	c.refreshes[validatedURL] = state
+	c.refreshMu.Unlock()
+
+	go func() {
+		defer func() {
+			c.refreshMu.Lock()
+			defer c.refreshMu.Unlock()
+			state := c.refreshes[validatedURL]
+			state.revalidating = false
+			c.storeRefresh(validatedURL, state)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.timeout)
+		defer cancel()
+
+		unlock := c.indexCache.LockRepo(validatedURL)
+		defer unlock()
+		if _, ok := c.indexCache.Get(validatedURL); ok {
+			return // Refreshed by another caller meanwhile
+		}
+		c.logger.Debug("revalidating stale repository index", zap.String("url", validatedURL))
+		_, _ = c.refreshIndex(ctx, validatedURL, false)
+	}()
+}
+
+// setRefreshError records the outcome of the last refresh of an index.
+func (c *Client) setRefreshError(validatedURL string, err error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	state := c.refreshes[validatedURL]
+	state.err = err
+	c.storeRefresh(validatedURL, state)
+}
+
+// storeRefresh saves refresh state, dropping it once there is nothing to
+// report. The caller must hold refreshMu.
+func (c *Client) storeRefresh(validatedURL string, state indexRefresh) {
+	if state == (indexRefresh{}) {
+		delete(c.refreshes, validatedURL)
+		return
+	}
+	c.refreshes[validatedURL] = state
+}
+
+// IndexStatus returns the status of the index cached for the repository if
+// it is past its TTL, or nil if it is fresh or not cached. OCI registries
+// have no index.
+func (c *Client) IndexStatus(ctx context.Context, repoURL string) (*IndexStatus, error) {
+	if registry.IsOCI(repoURL) {
+		return nil, nil
+	}
+	validatedURL, err := ValidateRepoURL(ctx, repoURL, c.validationOpts())
+	if err != nil {
+		return nil, err
+	}
+
+	_, fetchedAt, ok := c.indexCache.GetStale(validatedURL)
+	if !ok {
+		return nil, nil
+	}
+	age := time.Since(fetchedAt)
+	if age < c.opts.indexTTL {
+		return nil, nil
+	}
+
+	c.refreshMu.Lock()
+	state := c.refreshes[validatedURL]
+	c.refreshMu.Unlock()
+
+	status := &IndexStatus{FetchedAt: fetchedAt, Age: age, Revalidating: state.revalidating}
+	if state.err != nil {
+		status.RefreshError = state.err.Error()
+	}
+	return status, nil
+}
+
+// indexValidators are the cache validators of a downloaded index, which
+// make its next download conditional, and the size of the download that a
+// 304 response saves.
+type indexValidators struct {
+	etag         string
+	lastModified string
+	size         int64
+}
+
+// conditional reports whether there are validators to send.
+func (v indexValidators) conditional() bool {
+	return v.etag != "" || v.lastModified != ""
+}
+
+// validators returns the validators of a downloaded index.
+func (e *diskEntry) validators() indexValidators {
+	return indexValidators{etag: e.ETag, lastModified: e.LastModified, size: e.Size}
+}
+
+// loadIndex returns the repository index and when it was fetched, from the
+// disk cache while it is within the index TTL, and downloads it otherwise.
+// Once the TTL has passed, the validators of the index held in memory, or
+// else of the one on disk, make the download conditional, so an unchanged
+// index costs a 304 response instead of the full document.
+func (c *Client) loadIndex(ctx context.Context, validatedURL string, forceRefresh bool) (indexEntry, error) {
+	cached, cachedData, ok := c.disk.get(diskKindIndex, validatedURL)
+	if ok && !forceRefresh && time.Since(cached.FetchedAt) < c.opts.indexTTL {
+		if index, err := c.parseCachedIndex(validatedURL, cached, cachedData); err == nil {
+			return indexEntry{index: index, fetchedAt: cached.FetchedAt, validators: cached.validators()}, nil
+		}
+		cached = nil
+	}
+
+	// The index in memory is revalidated in preference to the one on disk,
+	// since a 304 then needs no parsing, and there may be no disk cache.
+	prev, inMemory := c.indexCache.peek(validatedURL)
+	inMemory = inMemory && prev.validators.conditional()
+	var validators indexValidators
+	switch {
+	case inMemory:
+		validators = prev.validators
+	case cached != nil:
+		validators = cached.validators()
+	}
+
+	c.logger.Debug("fetching repository index", zap.String("url", validatedURL))
+
+	index, meta, err := c.fetchIndex(ctx, validatedURL, validators)
+	if err != nil {
+		return indexEntry{}, err
+	}
+	if index == nil {
+		// Not modified: keep the index and restart its TTL, picking up any
+		// validators the server refreshed.
+		if inMemory {
+			index = prev.index
+		} else if index, err = c.parseCachedIndex(validatedURL, cached, cachedData); err != nil {
+			index = nil
+		}
+		if index != nil {
+			refreshed := validators
+			if meta.ETag != "" {
+				refreshed.etag = meta.ETag
+			}
+			if meta.LastModified != "" {
+				refreshed.lastModified = meta.LastModified
+			}
+			if cached != nil && cached.ETag == validators.etag && cached.LastModified == validators.lastModified {
+				cached.FetchedAt = meta.FetchedAt
+				cached.ETag, cached.LastModified = refreshed.etag, refreshed.lastModified
+				if err := c.disk.update(diskKindIndex, *cached); err != nil {
+					c.logger.Warn("failed to update cached repository index", zap.String("url", validatedURL), zap.Error(err))
+				}
+			}
+			c.indexCache.RecordNotModified(validators.size)
+			return indexEntry{index: index, fetchedAt: meta.FetchedAt, validators: refreshed}, nil
+		}
+		if index, meta, err = c.fetchIndex(ctx, validatedURL, indexValidators{}); err != nil {
+			return indexEntry{}, err
+		}
+	}
+	return indexEntry{index: index, fetchedAt: meta.FetchedAt, validators: meta.validators()}, nil
+}
+
+// parseCachedIndex parses an index restored from the disk cache, whose entry
+// is e.
+func (c *Client) parseCachedIndex(validatedURL string, e *diskEntry, data []byte) (*chartIndex, error) {
+	if limit := c.opts.maxIndexBytes; int64(len(data)) > limit {
+		return nil, &IndexTooLargeError{Repository: validatedURL, Size: int64(len(data)), Limit: limit}
+	}
+	index, err := c.parseIndex(validatedURL, bytes.NewReader(data), func() (string, error) {
+		path, ok := c.disk.blobPath(e.Digest)
+		if !ok {
+			return "", fmt.Errorf("invalid digest %q", e.Digest)
+		}
+		return path, nil
+	})
+	if err != nil {
+		c.logger.Warn("failed to parse cached repository index", zap.String("url", validatedURL), zap.Error(err))
+		return nil, err
+	}
+	c.logger.Debug("loaded repository index from disk cache", zap.String("url", validatedURL))
+	return index, nil
+}
+
+// fetchIndex downloads and parses a repository's index.yaml, and returns it
+// with the response's cache validators. If validators are given they are
+// sent, and a 304 response is reported by returning a nil index.
+func (c *Client) fetchIndex(ctx context.Context, validatedURL string, validators indexValidators) (*chartIndex, diskEntry, error) {
+	release, err := c.waitFetch(ctx, validatedURL)
+	if err != nil {
+		return nil, diskEntry{}, err
+	}
+	defer release()
+	ctx, done := c.startFetch(ctx, validatedURL, FetchIndex)
+	index, meta, err := c.downloadIndex(ctx, validatedURL, validators)
+	done(meta.Size, err)
+	return index, meta, err
+}
+
+// downloadIndex downloads the index of a repository; see fetchIndex. The
+// response is parsed as it arrives and copied to a temp file in the disk
+// cache, which is stored once the index has parsed, and which Helm's loader
+// reads if the streaming parser does not support the index.
+func (c *Client) downloadIndex(ctx context.Context, validatedURL string, validators indexValidators) (*chartIndex, diskEntry, error) {
+	indexURL := strings.TrimSuffix(validatedURL, "/") + "/index.yaml"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, indexURL, nil)
+	if err != nil {
+		return nil, diskEntry{}, &RepositoryError{URL: validatedURL, Op: "fetch", Message: "failed to download index", Err: err}
+	}
+	req.Header.Set("User-Agent", indexUserAgent)
+	conditional := validators.conditional()
+	if validators.etag != "" {
+		req.Header.Set("If-None-Match", validators.etag)
+	}
+	if validators.lastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.lastModified)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, diskEntry{}, &RepositoryError{URL: validatedURL, Op: "fetch", Message: "failed to download index", Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	meta := diskEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+	}
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		return nil, meta, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, diskEntry{}, &RepositoryError{URL: validatedURL, Op: "fetch", Message: "failed to download index", Err: fmt.Errorf("unexpected status %s", resp.Status)}
+	}
+	limit := c.opts.maxIndexBytes
+	if resp.ContentLength > limit {
+		return nil, diskEntry{}, &IndexTooLargeError{Repository: validatedURL, Size: resp.ContentLength, Limit: limit}
+	}
+
+	body := &limitedBody{r: resp.Body, repository: validatedURL, limit: limit}
+	var r io.Reader = body
+	spool, spoolErr := c.disk.create()
+	if spoolErr != nil {
+		c.logger.Warn("failed to create disk cache file for repository index", zap.String("url", validatedURL), zap.Error(spoolErr))
+	} else {
+		defer spool.discard()
+		r = io.TeeReader(body, spool)
+	}
+	index, err := c.parseIndex(validatedURL, r, func() (string, error) {
+		if spoolErr != nil {
+			return "", spoolErr
+		}
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return "", err
+		}
+		return spool.path()
+	})
+	if err == nil {
+		// Read the rest of the document, so that all of it counts against
+		// the limit and is cached
+		_, err = io.Copy(io.Discard, r)
+	}
+	var tooLarge *IndexTooLargeError
+	switch {
+	case errors.As(body.err, &tooLarge):
+		return nil, diskEntry{}, tooLarge
+	case body.err != nil:
+		return nil, diskEntry{}, &RepositoryError{URL: validatedURL, Op: "fetch", Message: "failed to download index", Err: body.err}
+	case err != nil:
+		return nil, diskEntry{}, &RepositoryError{URL: validatedURL, Op: "parse", Message: "failed to parse index", Err: err}
+	}
+
+	meta.Size = body.n
+	if spool != nil {
+		if err := spool.commit(diskKindIndex, validatedURL, meta); err != nil {
+			c.logger.Warn("failed to cache repository index on disk", zap.String("url", validatedURL), zap.Error(err))
+		}
+	}
+	return index, meta, nil
+}
+
+// limitedBody reads a response body of at most limit bytes, counting the
+// bytes read. It fails with an IndexTooLargeError once the limit is passed,
+// and records the first error reading the body.
+type limitedBody struct {
+	r          io.Reader
+	repository string
+	limit      int64
+	n          int64
+	err        error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if b.n > b.limit {
+		b.err = &IndexTooLargeError{Repository: b.repository, Limit: b.limit}
+		return 0, b.err
+	}
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// parseIndex parses an index into its compact form. It uses the streaming
+// parser, and for indexes the streaming parser does not support, Helm's
+// loader on the file returned by file, which must hold the whole index.
+func (c *Client) parseIndex(validatedURL string, r io.Reader, file func() (string, error)) (*chartIndex, error) {
+	index, err := streamIndex(r)
+	if errors.Is(err, errUnsupportedIndex) {
+		c.logger.Debug("parsing repository index with Helm's loader", zap.String("url", validatedURL), zap.Error(err))
+		path, err := file()
+		if err != nil {
+			return nil, err
+		}
+		return loadIndexFile(path)
+	}
+	return index, err
+}
+
+// loadIndexFile parses an index file with Helm's loader.
+func loadIndexFile(path string) (*chartIndex, error) {
+	index, err := repo.LoadIndexFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newChartIndex(index), nil
+}
@@ -0,0 +1,827 @@
+package helm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// maxIndexLineBytes is the longest line the streaming index parser reads.
+// Longer lines are left to Helm's loader.
+const maxIndexLineBytes = 1024 * 1024
+
+// errUnsupportedIndex is returned by streamIndex for input it does not
+// handle. The caller falls back to Helm's loader, which also produces the
+// authoritative error when the input is invalid.
+var errUnsupportedIndex = errors.New("unsupported index format")
+
+// streamIndex parses a repository index.yaml into a compact index without
+// building the whole document in memory: lines are read one at a time, only
+// the fields the tools use are kept, and everything else is skipped.
+//
+// It handles the block-style YAML that Helm and common repository servers
+// generate. JSON, flow collections, anchors, aliases, tags, folded scalars,
+// multiple documents and plain scalars that YAML would read as numbers are
+// reported with errUnsupportedIndex.
+//
+// Entries are validated the way Helm's loader does for the fields that are
+// kept, and invalid entries are skipped. Unlike Helm, entries are not
+// rejected for invalid maintainers or dependencies, which are never read.
+func streamIndex(r io.Reader) (*chartIndex, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxIndexLineBytes)
+	p := &indexParser{scanner: scanner, b: newIndexBuilder()}
+	if err := p.document(); err != nil {
+		return nil, err
+	}
+	return p.b.build(), nil
+}
+
+// indexLine is a line of the index split into indentation and content.
+type indexLine struct {
+	indent int    // Leading spaces
+	text   string // Content after the leading spaces
+}
+
+// blank reports whether the line has no content.
+func (l indexLine) blank() bool {
+	return strings.TrimLeft(l.text, " \t") == ""
+}
+
+// indexParser is a recursive descent parser over the lines of an index.
+// Each parse method consumes exactly the lines of the node it parses.
+type indexParser struct {
+	scanner *bufio.Scanner
+	line    indexLine // Next unconsumed line, if pending
+	pending bool
+	lines   int
+	err     error // Sticky scanner error
+	b       *indexBuilder
+}
+
+// unsupported returns an errUnsupportedIndex describing why the streaming
+// parser gave up.
+func (p *indexParser) unsupported(format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %s", errUnsupportedIndex, p.lines, fmt.Sprintf(format, args...))
+}
+
+// peekRaw returns the next line without consuming it.
+func (p *indexParser) peekRaw() (indexLine, bool) {
+	if p.pending {
+		return p.line, true
+	}
+	if p.err != nil || !p.scanner.Scan() {
+		if p.err == nil {
+			p.err = p.scanner.Err()
+			if errors.Is(p.err, bufio.ErrTooLong) {
+				p.err = p.unsupported("line longer than %d bytes", maxIndexLineBytes)
+			}
+		}
+		return indexLine{}, false
+	}
+	text := strings.TrimSuffix(p.scanner.Text(), "\r")
+	if p.lines == 0 {
+		text = strings.TrimPrefix(text, "\ufeff")
+	}
+	p.lines++
+	if hasLineBreak(text) {
+		p.err = p.unsupported("line break other than line feed")
+		return indexLine{}, false
+	}
+	trimmed := strings.TrimLeft(text, " ")
+	p.line = indexLine{indent: len(text) - len(trimmed), text: trimmed}
+	p.pending = true
+	return p.line, true
+}
+
+// peek returns the next line with content, consuming blank and comment lines
+// before it.
+func (p *indexParser) peek() (indexLine, bool) {
+	for {
+		l, ok := p.peekRaw()
+		if !ok {
+			return l, false
+		}
+		if !l.blank() && l.text[0] != '#' {
+			if l.text[0] == '\t' {
+				p.err = p.unsupported("tab indentation")
+				p.pending = false
+				return indexLine{}, false
+			}
+			return l, true
+		}
+		p.consume()
+	}
+}
+
+// consume marks the peeked line as read.
+func (p *indexParser) consume() {
+	p.pending = false
+}
+
+// unread makes l the next line to be read, replacing the peeked line. It is
+// used to parse the content after a sequence dash as a line of its own.
+func (p *indexParser) unread(l indexLine) {
+	p.line = l
+	p.pending = true
+}
+
+// document parses the top-level mapping of the index.
+func (p *indexParser) document() error {
+	if l, ok := p.peek(); ok && l.indent == 0 && l.text == "---" {
+		p.consume()
+	}
+	l, ok := p.peek()
+	if !ok {
+		if p.err != nil {
+			return p.err
+		}
+		return p.unsupported("empty index")
+	}
+	if l.indent != 0 {
+		return p.unsupported("indented document")
+	}
+
+	var apiVersion string
+	var seenEntries bool
+	err := p.mapping(0, func(key, value string) error {
+		var err error
+		switch key {
+		case "apiVersion":
+			apiVersion, err = p.str(value, 0)
+		case "generated":
+			_, err = p.timestamp(value, 0)
+		case "entries":
+			if seenEntries {
+				return p.unsupported("duplicate entries")
+			}
+			seenEntries = true
+			err = p.entries(value)
+		default:
+			err = p.skip(value, 0)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if _, ok := p.peek(); ok {
+		return p.unsupported("unexpected content")
+	}
+	if p.err != nil {
+		return p.err
+	}
+	if apiVersion == "" {
+		return p.unsupported("missing apiVersion")
+	}
+	return nil
+}
+
+// entries parses the mapping of chart names to their versions.
+func (p *indexParser) entries(value string) error {
+	if value == "{}" || isNull(value) && !p.nested(0) {
+		return nil
+	}
+	if value != "" {
+		return p.unsupported("entries is not a mapping")
+	}
+	l, _ := p.peek()
+	seen := make(map[string]bool)
+	return p.mapping(l.indent, func(name, value string) error {
+		if seen[name] {
+			return p.unsupported("duplicate chart %q", name)
+		}
+		seen[name] = true
+		return p.chartVersions(name, value, l.indent)
+	})
+}
+
+// chartVersions parses the sequence of versions of a chart.
+func (p *indexParser) chartVersions(name, value string, parent int) error {
+	if value == "[]" || isNull(value) && !p.nested(parent) {
+		return nil
+	}
+	if value != "" {
+		return p.unsupported("versions of chart %q are not a sequence", name)
+	}
+	return p.sequence(parent, func(value string, seq, indent int) error {
+		if value == "" {
+			if l, ok := p.peek(); !ok || l.indent <= seq {
+				return nil // Helm skips empty entries
+			}
+		} else if isNull(value) {
+			return nil
+		}
+		return p.chartVersion(name, value, indent)
+	})
+}
+
+// chartVersion parses one version of a chart and adds it to the index if it
+// is valid.
+func (p *indexParser) chartVersion(chart, value string, indent int) error {
+	var v indexVersion
+	var name, chartType string
+	err := p.mappingValue(value, indent, func(key, value string, indent int) error {
+		var err error
+		switch key {
+		case "name":
+			name, err = p.str(value, indent)
+		case "version":
+			v.Version, err = p.str(value, indent)
+		case "appVersion":
+			v.AppVersion, err = p.str(value, indent)
+		case "description":
+			v.Description, err = p.str(value, indent)
+		case "keywords":
+			v.Keywords, err = p.strs(value, indent)
+		case "type":
+			chartType, err = p.str(value, indent)
+		case "deprecated":
+			v.Deprecated, err = p.boolean(value, indent)
+		case "created":
+			v.Created, err = p.timestamp(value, indent)
+		case "digest":
+			v.Digest, err = p.str(value, indent)
+		case "urls":
+			v.URLs, err = p.strs(value, indent)
+		default:
+			err = p.skip(value, indent)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Mirror the checks chart metadata validation applies to these fields.
+	name = sanitizeIndexString(name)
+	v.AppVersion = sanitizeIndexString(v.AppVersion)
+	v.Description = sanitizeIndexString(v.Description)
+	for i := range v.Keywords {
+		v.Keywords[i] = sanitizeIndexString(v.Keywords[i])
+	}
+	if name == "" || name != filepath.Base(name) || v.Version == "" {
+		return nil
+	}
+	parsed, err := semver.NewVersion(v.Version)
+	if err != nil {
+		return nil
+	}
+	if chartType != "" && chartType != "application" && chartType != "library" {
+		return nil
+	}
+	p.b.add(chart, v, parsed)
+	return nil
+}
+
+// mapping parses a block mapping with keys at indent, calling field with each
+// key and the rest of its line. field must consume the key's value.
+func (p *indexParser) mapping(indent int, field func(key, value string) error) error {
+	for {
+		l, ok := p.peek()
+		if !ok || l.indent < indent || l.indent == indent && isSequenceItem(l.text) {
+			return nil
+		}
+		if l.indent > indent {
+			return p.unsupported("unexpected indentation")
+		}
+		key, value, err := p.splitKey(l.text)
+		if err != nil {
+			return err
+		}
+		p.consume()
+		if err := field(key, value); err != nil {
+			return err
+		}
+	}
+}
+
+// mappingValue parses a block mapping that is the value of a sequence item.
+// It starts on the item's line at indent, or on the next line if value is
+// empty.
+func (p *indexParser) mappingValue(value string, indent int, field func(key, value string, indent int) error) error {
+	if value == "" {
+		l, _ := p.peek()
+		indent = l.indent
+	} else {
+		p.unread(indexLine{indent: indent, text: value})
+	}
+	return p.mapping(indent, func(key, value string) error {
+		return field(key, value, indent)
+	})
+}
+
+// sequence parses a block sequence whose dashes are at parent, or further
+// indented, calling item with the rest of each item's line, the indentation
+// of the dashes, and the indentation of the rest of the line.
+func (p *indexParser) sequence(parent int, item func(value string, seq, indent int) error) error {
+	l, ok := p.peek()
+	if !ok || !isSequenceItem(l.text) || l.indent < parent {
+		if ok && l.indent > parent {
+			return p.unsupported("expected a sequence")
+		}
+		return nil
+	}
+	indent := l.indent
+	for {
+		l, ok := p.peek()
+		if !ok || l.indent < indent || l.indent == indent && !isSequenceItem(l.text) {
+			return nil
+		}
+		if l.indent > indent {
+			return p.unsupported("unexpected indentation")
+		}
+		p.consume()
+		value := strings.TrimLeft(l.text[1:], " ")
+		if strings.HasPrefix(value, "#") {
+			value = ""
+		}
+		if err := item(value, indent, indent+len(l.text)-len(value)); err != nil {
+			return err
+		}
+	}
+}
+
+// nested reports whether the next content line starts a block nested under
+// a node at parent, which makes an empty value a collection rather than null.
+func (p *indexParser) nested(parent int) bool {
+	l, ok := p.peek()
+	return ok && (l.indent > parent || l.indent == parent && isSequenceItem(l.text))
+}
+
+// skip consumes the value of a key that is not needed, whatever its form.
+func (p *indexParser) skip(value string, parent int) error {
+	for {
+		l, ok := p.peekRaw()
+		if !ok {
+			return p.err
+		}
+		if l.blank() || l.text[0] == '#' || l.indent > parent ||
+			value == "" && l.indent == parent && isSequenceItem(l.text) {
+			p.consume()
+			continue
+		}
+		return nil
+	}
+}
+
+// strs parses a sequence of strings.
+func (p *indexParser) strs(value string, parent int) ([]string, error) {
+	if value == "[]" || isNull(value) && !p.nested(parent) {
+		return nil, nil
+	}
+	if value != "" {
+		return nil, p.unsupported("expected a sequence")
+	}
+	var out []string
+	err := p.sequence(parent, func(value string, seq, _ int) error {
+		s, err := p.str(value, seq)
+		out = append(out, s)
+		return err
+	})
+	return out, err
+}
+
+// str parses a scalar into the string Helm's loader would produce for it.
+func (p *indexParser) str(value string, parent int) (string, error) {
+	s, quoted, err := p.scalar(value, parent)
+	if err != nil || quoted {
+		return s, err
+	}
+	return p.plainString(s)
+}
+
+// boolean parses a scalar into a bool.
+func (p *indexParser) boolean(value string, parent int) (bool, error) {
+	s, quoted, err := p.scalar(value, parent)
+	if err != nil {
+		return false, err
+	}
+	if !quoted {
+		if isNull(s) {
+			return false, nil
+		}
+		if b, ok := yamlBool(s); ok {
+			return b, nil
+		}
+	}
+	return false, p.unsupported("expected a boolean")
+}
+
+// timestamp parses a scalar into a time, in the RFC 3339 form Helm expects.
+func (p *indexParser) timestamp(value string, parent int) (time.Time, error) {
+	s, err := p.str(value, parent)
+	if err != nil || s == "" {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, p.unsupported("invalid time %q", s)
+	}
+	return t, nil
+}
+
+// scalar parses a scalar value that starts on the current line, which has
+// already been consumed, and may continue on lines indented more than parent.
+// It reports whether the scalar was quoted or a block scalar, whose content
+// is always a string.
+func (p *indexParser) scalar(value string, parent int) (string, bool, error) {
+	if value == "" {
+		if p.nested(parent) {
+			return "", false, p.unsupported("expected a scalar")
+		}
+		return "", false, nil
+	}
+	switch value[0] {
+	case '"':
+		s, err := p.quoted(value[1:], parent, '"')
+		return s, true, err
+	case '\'':
+		s, err := p.quoted(value[1:], parent, '\'')
+		return s, true, err
+	case '|':
+		s, err := p.literal(value[1:], parent)
+		return s, true, err
+	case '>', '&', '*', '!', '[', ']', '{', '}', ',', '%', '@', '`', '?':
+		return "", false, p.unsupported("unsupported value %q", value)
+	}
+	if isSequenceItem(value) {
+		return "", false, p.unsupported("unexpected sequence")
+	}
+	s, err := p.plain(value, parent)
+	return s, false, err
+}
+
+// plain parses a plain (unquoted) scalar, folding continuation lines.
+func (p *indexParser) plain(value string, parent int) (string, error) {
+	s, commented := stripComment(value)
+	if strings.Contains(s, ": ") || strings.HasSuffix(s, ":") {
+		return "", p.unsupported("unexpected mapping")
+	}
+	var sb strings.Builder
+	sb.WriteString(s)
+	breaks := 0
+	for !commented {
+		l, ok := p.peekRaw()
+		if !ok {
+			break
+		}
+		if l.blank() {
+			p.consume()
+			breaks++
+			continue
+		}
+		text := strings.TrimLeft(l.text, " \t")
+		if l.indent <= parent || text[0] == '#' {
+			break
+		}
+		p.consume()
+		if text, commented = stripComment(text); strings.Contains(text, ": ") || strings.HasSuffix(text, ":") {
+			return "", p.unsupported("unexpected mapping")
+		}
+		foldBreaks(&sb, breaks)
+		breaks = 0
+		sb.WriteString(text)
+	}
+	return sb.String(), nil
+}
+
+// quoted parses a single- or double-quoted scalar after its opening quote,
+// folding continuation lines.
+func (p *indexParser) quoted(s string, parent int, quote byte) (string, error) {
+	var sb strings.Builder
+	for {
+		var line strings.Builder
+		escapedBreak := false
+		escaped := 0 // Length of line up to the last escape sequence.
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			switch {
+			case c == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+				line.WriteByte('\'')
+				i++
+			case c == quote:
+				sb.WriteString(line.String())
+				if rest := strings.TrimLeft(s[i+1:], " \t"); rest != "" && rest[0] != '#' {
+					return "", p.unsupported("unexpected content after quoted scalar")
+				}
+				return sb.String(), nil
+			case c == '\\' && quote == '"':
+				if i+1 == len(s) {
+					escapedBreak = true
+					break
+				}
+				r, n, ok := unescape(s[i+1:])
+				if !ok {
+					return "", p.unsupported("invalid escape in quoted scalar")
+				}
+				line.WriteString(r)
+				escaped = line.Len()
+				i += n
+			default:
+				line.WriteByte(c)
+			}
+		}
+
+		// The scalar continues on the next line: trailing white space that
+		// is not escaped is dropped and the line break folds into a space,
+		// or into the following empty lines.
+		text := line.String()
+		if !escapedBreak {
+			text = text[:escaped] + strings.TrimRight(text[escaped:], " \t")
+		}
+		sb.WriteString(text)
+		breaks := 0
+		for {
+			l, ok := p.peekRaw()
+			if !ok {
+				if p.err != nil {
+					return "", p.err
+				}
+				return "", p.unsupported("unterminated quoted scalar")
+			}
+			p.consume()
+			if !l.blank() {
+				if l.indent <= parent {
+					return "", p.unsupported("quoted scalar is not indented")
+				}
+				s = strings.TrimLeft(l.text, " \t")
+				break
+			}
+			breaks++
+		}
+		if !escapedBreak || breaks > 0 {
+			foldBreaks(&sb, breaks)
+		}
+	}
+}
+
+// literal parses a literal block scalar (|) given the rest of its header line.
+func (p *indexParser) literal(header string, parent int) (string, error) {
+	header, _ = stripComment(header)
+	chomp := byte(0)
+	switch header {
+	case "":
+	case "-", "+":
+		chomp = header[0]
+	default:
+		return "", p.unsupported("unsupported block scalar header %q", header)
+	}
+
+	var sb strings.Builder
+	indent := -1
+	empty := 0
+	wrote := false
+	for {
+		l, ok := p.peekRaw()
+		if !ok {
+			break
+		}
+		if l.blank() && (indent < 0 || l.indent <= indent) {
+			p.consume()
+			empty++
+			continue
+		}
+		if indent < 0 {
+			if l.indent <= parent {
+				break
+			}
+			indent = l.indent // The first content line sets the indentation
+		} else if l.indent < indent {
+			break
+		}
+		p.consume()
+		if wrote {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(strings.Repeat("\n", empty))
+		sb.WriteString(strings.Repeat(" ", l.indent-indent))
+		sb.WriteString(l.text)
+		empty = 0
+		wrote = true
+	}
+
+	switch {
+	case chomp == '+':
+		if wrote {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(strings.Repeat("\n", empty))
+	case chomp == 0 && wrote:
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// splitKey splits a mapping line into its key and the rest of the line.
+func (p *indexParser) splitKey(text string) (string, string, error) {
+	var key, rest string
+	switch text[0] {
+	case '"', '\'':
+		end := strings.IndexByte(text[1:], text[0]) + 1
+		if end == 0 || strings.ContainsAny(text[1:end], `\`) || strings.HasPrefix(text[end+1:], text[:1]) {
+			return "", "", p.unsupported("unsupported quoted key")
+		}
+		key, rest = text[1:end], text[end+1:]
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", p.unsupported("expected a mapping key")
+		}
+		rest = rest[1:]
+	case '&', '*', '!', '[', ']', '{', '}', ',', '%', '@', '`', '?', '|', '>':
+		return "", "", p.unsupported("unsupported key %q", text)
+	default:
+		i := strings.Index(text, ": ")
+		if i < 0 {
+			if !strings.HasSuffix(text, ":") {
+				return "", "", p.unsupported("expected a mapping key")
+			}
+			i = len(text) - 1
+		}
+		key, rest = strings.TrimRight(text[:i], " \t"), text[i+1:]
+		if key == "<<" {
+			return "", "", p.unsupported("merge keys")
+		}
+		if !plainKey(key) {
+			return "", "", p.unsupported("unsupported key %q", key)
+		}
+	}
+	rest = strings.TrimLeft(rest, " \t")
+	if strings.HasPrefix(rest, "#") {
+		rest = ""
+	}
+	return key, rest, nil
+}
+
+// plainString returns the string value of a plain scalar in a string field.
+// Null is empty; booleans and numbers are rejected by Helm's loader, so they
+// are left to it to report.
+func (p *indexParser) plainString(s string) (string, error) {
+	if isNull(s) {
+		return "", nil
+	}
+	if _, ok := yamlBool(s); ok || isNumber(s) {
+		return "", p.unsupported("%q is not a string", s)
+	}
+	return s, nil
+}
+
+// plainKey reports whether a plain mapping key is read as the same string by
+// Helm's loader, which converts non-string keys to strings.
+func plainKey(s string) bool {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(n, 10) == s {
+		return true
+	}
+	_, isBool := yamlBool(s)
+	return !isNull(s) && !isBool && !isNumber(s)
+}
+
+// yamlFloat matches the plain scalars YAML 1.1 resolves to floats.
+var yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+
+// isNumber reports whether YAML 1.1 resolves a plain scalar to a number.
+func isNumber(s string) bool {
+	if !strings.ContainsRune("+-.0123456789", rune(s[0])) {
+		return false
+	}
+	plain := strings.ReplaceAll(s, "_", "")
+	if _, err := strconv.ParseInt(plain, 0, 64); err == nil {
+		return true
+	}
+	if _, err := strconv.ParseUint(plain, 0, 64); err == nil {
+		return true
+	}
+	lower := strings.ToLower(strings.TrimLeft(plain, "+-"))
+	return yamlFloat.MatchString(plain) || lower == ".inf" || lower == ".nan" || strings.HasPrefix(lower, "0b")
+}
+
+// hasLineBreak reports whether text contains a character other than line
+// feed that YAML treats as a line break.
+func hasLineBreak(text string) bool {
+	if strings.IndexByte(text, '\r') >= 0 {
+		return true
+	}
+	// NEL, LS and PS are all encoded with one of these lead bytes
+	if strings.IndexByte(text, 0xc2) < 0 && strings.IndexByte(text, 0xe2) < 0 {
+		return false
+	}
+	return strings.ContainsAny(text, "\u0085\u2028\u2029")
+}
+
+// isNull reports whether a plain scalar is YAML null.
+func isNull(s string) bool {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return true
+	}
+	return false
+}
+
+// yamlBool returns the value of a plain scalar that YAML 1.1 resolves to a
+// boolean.
+func yamlBool(s string) (bool, bool) {
+	switch s {
+	case "y", "Y", "yes", "Yes", "YES", "true", "True", "TRUE", "on", "On", "ON":
+		return true, true
+	case "n", "N", "no", "No", "NO", "false", "False", "FALSE", "off", "Off", "OFF":
+		return false, true
+	}
+	return false, false
+}
+
+// isSequenceItem reports whether a line's content starts a sequence item.
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "-\t")
+}
+
+// stripComment removes a trailing comment and white space from plain
+// scalar text, reporting whether there was a comment.
+func stripComment(s string) (string, bool) {
+	commented := false
+	for i := 1; i < len(s); i++ {
+		if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+			s, commented = s[:i], true
+			break
+		}
+	}
+	return strings.TrimRight(s, " \t"), commented
+}
+
+// foldBreaks writes the folded form of a line break followed by the given
+// number of empty lines: a space if there are none, otherwise a newline for
+// each empty line.
+func foldBreaks(sb *strings.Builder, empty int) {
+	if empty == 0 {
+		sb.WriteByte(' ')
+		return
+	}
+	sb.WriteString(strings.Repeat("\n", empty))
+}
+
+// unescape decodes the double-quoted scalar escape sequence at the start of
+// s (after the backslash), returning the decoded text and the number of bytes
+// consumed.
+func unescape(s string) (string, int, bool) {
+	switch s[0] {
+	case '0':
+		return "\x00", 1, true
+	case 'a':
+		return "\a", 1, true
+	case 'b':
+		return "\b", 1, true
+	case 't', '\t':
+		return "\t", 1, true
+	case 'n':
+		return "\n", 1, true
+	case 'v':
+		return "\v", 1, true
+	case 'f':
+		return "\f", 1, true
+	case 'r':
+		return "\r", 1, true
+	case 'e':
+		return "\x1b", 1, true
+	case ' ', '"', '/', '\\':
+		return s[:1], 1, true
+	case 'N':
+		return "\u0085", 1, true
+	case '_':
+		return "\u00a0", 1, true
+	case 'L':
+		return "\u2028", 1, true
+	case 'P':
+		return "\u2029", 1, true
+	}
+	width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[0]]
+	if width == 0 || len(s) < 1+width {
+		return "", 0, false
+	}
+	code, err := strconv.ParseUint(s[1:1+width], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return "", 0, false
+	}
+	return string(rune(code)), 1 + width, true
+}
+
+// sanitizeIndexString normalizes white space and removes non-printable
+// characters, as chart metadata validation does.
+func sanitizeIndexString(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsPrint(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,399 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+	repo "helm.sh/helm/v4/pkg/repo/v1"
+)
+
+// writeIndex serializes an index the way `helm repo index` does.
+func writeIndex(t testing.TB, index *repo.IndexFile) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "index.yaml")
+	require.NoError(t, index.WriteFile(path, 0o644))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+// writeIndexFile writes index data to a temp file and returns its path.
+func writeIndexFile(t testing.TB, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "index.yaml")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+// requireSameIndex checks that the streaming parser supports data and agrees
+// with Helm's loader.
+func requireSameIndex(t *testing.T, data string) *chartIndex {
+	t.Helper()
+	want, err := loadIndexFile(writeIndexFile(t, []byte(data)))
+	require.NoError(t, err)
+	got, err := streamIndex(strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	return got
+}
+
+func TestStreamIndex_HelmGenerated(t *testing.T) {
+	created := time.Date(2025, 3, 4, 5, 6, 7, 890000000, time.UTC)
+	version := func(name, version string, mutate func(*repo.ChartVersion)) *repo.ChartVersion {
+		v := &repo.ChartVersion{
+			Metadata: &chartv2.Metadata{APIVersion: "v2", Name: name, Version: version},
+			URLs:     []string{"charts/" + name + "-" + version + ".tgz"},
+			Created:  created,
+			Digest:   "sha256:" + strings.Repeat("ab", 32),
+		}
+		if mutate != nil {
+			mutate(v)
+		}
+		return v
+	}
+
+	index := repo.NewIndexFile()
+	index.Entries = map[string]repo.ChartVersions{
+		"nginx": {
+			version("nginx", "15.0.0", func(v *repo.ChartVersion) {
+				v.AppVersion = "1.25.0"
+				v.Description = "NGINX Open Source is a web server that can be also used as a reverse proxy, load balancer, and HTTP cache. " + strings.Repeat("Long text wraps. ", 10)
+				v.Keywords = []string{"nginx", "http", "web", "www", "reverse proxy"}
+				v.Annotations = map[string]string{"category": "Infrastructure", "images": "- name: nginx\n  image: nginx:1.25\n"}
+				v.Maintainers = []*chartv2.Maintainer{{Name: "Broadcom", URL: "https://example.com"}}
+				v.Dependencies = []*chartv2.Dependency{{Name: "common", Repository: "oci://registry/charts", Version: "2.x.x", Tags: []string{"common"}}}
+			}),
+			version("nginx", "14.2.1", func(v *repo.ChartVersion) {
+				v.AppVersion = "1.10"
+				v.Description = "Line one\nLine two\n\n  indented: yes # not a comment"
+				v.Deprecated = true
+			}),
+			version("nginx", "15.0.0-rc.1", func(v *repo.ChartVersion) {
+				v.AppVersion = "true"
+				v.Description = `Quotes ' and " and \ and: colons # hashes`
+				v.Keywords = []string{"123", "0x1f", "null", "~", "- dash", "ünïcödé"}
+			}),
+		},
+		"with-dashes": {version("with-dashes", "0.1.0", func(v *repo.ChartVersion) {
+			v.Type = "library"
+			v.URLs = []string{"https://example.com/a.tgz", "https://mirror.example.com/a.tgz"}
+		})},
+		"123": {version("123", "1.0.0", nil)},
+	}
+	index.Generated = created
+
+	got := requireSameIndex(t, string(writeIndex(t, index)))
+	assert.Equal(t, []string{"123", "nginx", "with-dashes"}, got.chartNames())
+}
+
+func TestStreamIndex_Supported(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{
+			name: "comments, blank lines and indented sequences",
+			yaml: `# Generated by hand
+---
+apiVersion: v1 # trailing comment
+
+entries:
+  # first chart
+  app:
+    - name: app   # comment
+      version: 1.0.0
+
+      urls:
+        - app-1.0.0.tgz
+    -
+      name: app
+      version: 2.0.0
+`,
+		},
+		{
+			name: "windows line endings and byte order mark",
+			yaml: "\ufeffapiVersion: v1\r\nentries:\r\n  app:\r\n  - name: app\r\n    version: 1.0.0\r\n",
+		},
+		{
+			name: "quoted keys and values",
+			yaml: `apiVersion: "v1"
+entries:
+  "app":
+  - 'name': app
+    version: '1.0.0'
+    description: 'It''s
+      folded
+
+      twice'
+    appVersion: "esc\t\"apedé\
+      joined"
+`,
+		},
+		{
+			name: "plain multi-line and literal block scalars",
+			yaml: `apiVersion: v1
+entries:
+  app:
+  - name: app
+    version: 1.0.0
+    description: a plain
+      scalar over
+
+      lines
+    keywords:
+    - |
+      literal
+        indented
+
+    - |-
+      stripped
+    - |+
+      kept
+
+    annotations:
+      notes: |
+        entries:
+          fake:
+          - name: fake
+`,
+		},
+		{
+			name: "YAML typed scalars",
+			yaml: `apiVersion: v1
+entries:
+  app:
+  - name: app
+    version: 1.0.0
+    description: yes-ish 1.0
+    deprecated: on
+  - name: app
+    version: 2.0.0
+    appVersion: ~
+    deprecated: false
+    keywords: []
+`,
+		},
+		{
+			name: "invalid entries are skipped",
+			yaml: `apiVersion: v1
+entries:
+  app:
+  - name: app
+    version: not-semver
+  - version: 1.0.0
+  - name: ../app
+    version: 1.0.0
+  - name: app
+    version: 1.0.0
+    type: plugin
+  -
+  - ~
+  - name: app
+    version: 1.1.0
+  empty: []
+  missing:
+`,
+		},
+		{
+			name: "empty entries",
+			yaml: "apiVersion: v1\nentries: {}\ngenerated: \"2025-01-01T00:00:00Z\"\n",
+		},
+		{
+			name: "unknown fields are skipped",
+			yaml: `apiVersion: v1
+serverInfo:
+  contextPath: /
+publicKeys:
+- |
+  -----BEGIN PGP PUBLIC KEY BLOCK-----
+entries:
+  app:
+  - name: app
+    version: 1.0.0
+    removed: false
+    engine: gotpl
+    maintainers:
+      - name: someone
+        email: someone@example.com
+    dependencies:
+    - name: dep
+      version: "*"
+      import-values: [a, b]
+annotations:
+  x: y
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireSameIndex(t, tt.yaml)
+		})
+	}
+}
+
+func TestStreamIndex_Unsupported(t *testing.T) {
+	entry := "apiVersion: v1\nentries:\n  app:\n  - name: app\n    version: 1.0.0\n"
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{"JSON", `{"apiVersion": "v1", "entries": {}}`},
+		{"flow sequence", entry + "    keywords: [a, b]\n"},
+		{"flow mapping", entry + "    description: {a: b}\n"},
+		{"anchor", entry + "    description: &d text\n"},
+		{"alias", entry + "    description: *d\n"},
+		{"tag", entry + "    description: !!str text\n"},
+		{"folded scalar", entry + "    description: >\n      folded\n"},
+		{"indentation indicator", entry + "    description: |2\n       text\n"},
+		{"merge key", entry + "    <<: {version: 2.0.0}\n"},
+		{"float in string field", entry + "    appVersion: 1.10\n"},
+		{"integer in string field", entry + "    appVersion: 10\n"},
+		{"boolean in string field", entry + "    description: yes\n"},
+		{"null key", entry + "    ~: value\n"},
+		{"duplicate chart", entry + "  app:\n  - name: app\n    version: 2.0.0\n"},
+		{"missing apiVersion", "entries: {}\n"},
+		{"multiple documents", entry + "---\napiVersion: v1\n"},
+		{"tab indentation", entry + "\tdescription: text\n"},
+		{"invalid time", entry + "    created: yesterday\n"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := streamIndex(strings.NewReader(tt.yaml))
+			assert.ErrorIs(t, err, errUnsupportedIndex)
+		})
+	}
+
+	t.Run("long lines", func(t *testing.T) {
+		long := entry + "    description: " + strings.Repeat("x", maxIndexLineBytes) + "\n"
+		_, err := streamIndex(strings.NewReader(long))
+		assert.ErrorIs(t, err, errUnsupportedIndex)
+	})
+}
+
+func TestClient_ParseIndex(t *testing.T) {
+	client := NewClient(WithLogger(zap.NewNop()), WithMaxIndexBytes(1024))
+	const unsupported = `apiVersion: v1
+entries:
+  app:
+  - name: app
+    version: 1.0.0
+    keywords: [a, b]
+`
+	// parse parses data, giving the fallback a file with the same data.
+	parse := func(t *testing.T, data string) (*chartIndex, error) {
+		path := writeIndexFile(t, []byte(data))
+		return client.parseIndex("https://example.com", strings.NewReader(data), func() (string, error) { return path, nil })
+	}
+
+	t.Run("falls back to Helm's loader", func(t *testing.T) {
+		index, err := parse(t, unsupported)
+		require.NoError(t, err)
+		v, ok := index.lookup("app", "1.0.0")
+		require.True(t, ok)
+		assert.Equal(t, []string{"a", "b"}, v.Keywords)
+	})
+
+	t.Run("reports Helm's error for invalid indexes", func(t *testing.T) {
+		_, err := parse(t, "entries: {}\n")
+		assert.ErrorIs(t, err, repo.ErrNoAPIVersion)
+	})
+
+	t.Run("rejects cached indexes above the size limit", func(t *testing.T) {
+		_, err := client.parseCachedIndex("https://example.com", &diskEntry{}, make([]byte, 1025))
+		assert.True(t, IsIndexTooLarge(err))
+	})
+
+	t.Run("downloads unsupported indexes through the disk cache", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(unsupported))
+		}))
+		t.Cleanup(srv.Close)
+		client := NewClient(WithAllowPrivateIPs(true), WithCacheDir(t.TempDir()), WithLogger(zap.NewNop()))
+
+		charts, err := client.ListCharts(context.Background(), srv.URL)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app"}, charts)
+
+		e, data, ok := client.disk.get(diskKindIndex, srv.URL)
+		require.True(t, ok, "index is cached once parsed")
+		assert.Equal(t, unsupported, string(data))
+		assert.Equal(t, int64(len(unsupported)), e.Size)
+
+		temps, err := filepath.Glob(filepath.Join(client.disk.root, "blobs", ".tmp-*"))
+		require.NoError(t, err)
+		assert.Empty(t, temps)
+	})
+
+	t.Run("does not cache indexes that fail to parse", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("entries: {}\n"))
+		}))
+		t.Cleanup(srv.Close)
+		client := NewClient(WithAllowPrivateIPs(true), WithCacheDir(t.TempDir()), WithLogger(zap.NewNop()))
+
+		_, err := client.ListCharts(context.Background(), srv.URL)
+		require.Error(t, err)
+		_, _, ok := client.disk.get(diskKindIndex, srv.URL)
+		assert.False(t, ok)
+
+		temps, err := filepath.Glob(filepath.Join(client.disk.root, "blobs", ".tmp-*"))
+		require.NoError(t, err)
+		assert.Empty(t, temps)
+	})
+}
+
+// syntheticIndexYAML returns a Helm-generated index of about size bytes.
+func syntheticIndexYAML(b *testing.B, size int) []byte {
+	b.Helper()
+	generate := func(charts int) []byte {
+		index := syntheticIndex(charts, 100)
+		for _, versions := range index.Entries {
+			for _, v := range versions {
+				v.Maintainers = []*chartv2.Maintainer{{Name: "Maintainer", Email: "maintainer@example.com"}}
+				v.Annotations = map[string]string{"category": "Benchmark"}
+			}
+		}
+		return writeIndex(b, index)
+	}
+	sample := len(generate(100))
+	return generate(100 * size / sample)
+}
+
+// BenchmarkParseIndex compares the streaming parser with Helm's loader on a
+// synthetic 50 MB index.
+func BenchmarkParseIndex(b *testing.B) {
+	data := syntheticIndexYAML(b, 50*1024*1024)
+	b.Logf("index size: %d bytes", len(data))
+
+	b.Run("stream", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := streamIndex(bytes.NewReader(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("helm", func(b *testing.B) {
+		path := writeIndexFile(b, data)
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := loadIndexFile(path); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+)
+
+// CacheAdmin is a mock implementation of helm.CacheAdmin.
+type CacheAdmin struct {
+	mock.Mock
+}
+
+// Ensure CacheAdmin implements helm.CacheAdmin.
+var _ helm.CacheAdmin = (*CacheAdmin)(nil)
+
+// CacheStatus mocks the CacheStatus method.
+func (m *CacheAdmin) CacheStatus() *helm.CacheStatus {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*helm.CacheStatus)
+}
+
+// RefreshRepository mocks the RefreshRepository method.
+func (m *CacheAdmin) RefreshRepository(ctx context.Context, repoURL string) (*helm.CachedIndex, error) {
+	args := m.Called(ctx, repoURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*helm.CachedIndex), args.Error(1)
+}
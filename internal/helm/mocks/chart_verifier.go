@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+)
+
+// ChartVerifier is a mock implementation of helm.ChartVerifier.
+type ChartVerifier struct {
+	mock.Mock
+}
+
+// Ensure ChartVerifier implements helm.ChartVerifier.
+var _ helm.ChartVerifier = (*ChartVerifier)(nil)
+
+// VerifyChart mocks the VerifyChart method.
+func (m *ChartVerifier) VerifyChart(ctx context.Context, repoURL, chart, version string) (*helm.Verification, error) {
+	args := m.Called(ctx, repoURL, chart, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*helm.Verification), args.Error(1)
+}
+
+// VerifyVersions mocks the VerifyVersions method.
+func (m *ChartVerifier) VerifyVersions(ctx context.Context, repoURL, chart string, versions []string) (map[string]*helm.Verification, error) {
+	args := m.Called(ctx, repoURL, chart, versions)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]*helm.Verification), args.Error(1)
+}
@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+)
+
+// IndexStatusReporter is a mock implementation of helm.IndexStatusReporter.
+type IndexStatusReporter struct {
+	mock.Mock
+}
+
+// Ensure IndexStatusReporter implements helm.IndexStatusReporter.
+var _ helm.IndexStatusReporter = (*IndexStatusReporter)(nil)
+
+// IndexStatus mocks the IndexStatus method.
+func (m *IndexStatusReporter) IndexStatus(ctx context.Context, repoURL string) (*helm.IndexStatus, error) {
+	args := m.Called(ctx, repoURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*helm.IndexStatus), args.Error(1)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+)
+
+// ReadinessChecker is a mock implementation of helm.ReadinessChecker.
+type ReadinessChecker struct {
+	mock.Mock
+}
+
+// Ensure ReadinessChecker implements helm.ReadinessChecker.
+var _ helm.ReadinessChecker = (*ReadinessChecker)(nil)
+
+// CheckCacheDir mocks the CheckCacheDir method.
+func (m *ReadinessChecker) CheckCacheDir() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// CheckRegistry mocks the CheckRegistry method.
+func (m *ReadinessChecker) CheckRegistry() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// CheckRepository mocks the CheckRepository method.
+func (m *ReadinessChecker) CheckRepository(ctx context.Context, repoURL string) error {
+	args := m.Called(ctx, repoURL)
+	return args.Error(0)
+}
@@ -0,0 +1,130 @@
+package helm
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// blockedPrefixes is the built-in set of non-public address ranges that
+// outbound connections may not reach unless explicitly allowed. It extends the
+// standard library's private/loopback/link-local categories with the special
+// purpose ranges from the IANA registries that can still lead to internal
+// services (CGNAT, NAT64 and 6to4 translation, cloud metadata endpoints).
+// IPv4-mapped IPv6 addresses are unmapped before matching, so they are
+// covered by the IPv4 entries.
+var blockedPrefixes = mustParsePrefixes(
+	// IPv4
+	"0.0.0.0/8",       // "this network"
+	"10.0.0.0/8",      // private
+	"100.64.0.0/10",   // carrier-grade NAT
+	"127.0.0.0/8",     // loopback
+	"169.254.0.0/16",  // link-local, including cloud metadata (169.254.169.254)
+	"172.16.0.0/12",   // private
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation (TEST-NET-1)
+	"192.88.99.0/24",  // 6to4 relay anycast
+	"192.168.0.0/16",  // private
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation (TEST-NET-2)
+	"203.0.113.0/24",  // documentation (TEST-NET-3)
+	"224.0.0.0/4",     // multicast
+	"240.0.0.0/4",     // reserved, including broadcast
+	// IPv6
+	"::/128",            // unspecified
+	"::1/128",           // loopback
+	"64:ff9b::/96",      // NAT64
+	"64:ff9b:1::/48",    // local-use NAT64
+	"100::/64",          // discard-only
+	"2001::/23",         // IETF protocol assignments, including Teredo
+	"2001:db8::/32",     // documentation
+	"2002::/16",         // 6to4
+	"fc00::/7",          // unique local
+	"fd00:ec2::254/128", // AWS instance metadata (IPv6)
+	"fe80::/10",         // link-local
+	"ff00::/8",          // multicast
+)
+
+// ParseCIDRs parses a list of CIDR prefixes. A bare IP address is accepted as
+// a single-address prefix.
+func ParseCIDRs(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", v, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", v, err)
+		}
+		// Addresses are unmapped before matching, so mapped prefixes must be too.
+		if p.Addr().Is4In6() && p.Bits() >= 96 {
+			p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// mustParsePrefixes parses built-in prefixes and panics on error.
+func mustParsePrefixes(values ...string) []netip.Prefix {
+	prefixes, err := ParseCIDRs(values)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
+
+// allowIP reports whether connections to ip are permitted by the options.
+// Checks are applied in order: DeniedCIDRs always refuse, AllowedCIDRs always
+// permit, AllowPrivateIPs permits everything else, and otherwise the built-in
+// blocked ranges apply. IPv4-mapped IPv6 addresses are checked as IPv4.
+func (o ValidationOptions) allowIP(ip net.IP) bool {
+	addr, ok := ipAddr(ip)
+	if !ok {
+		return false
+	}
+	if containsAddr(o.DeniedCIDRs, addr) {
+		return false
+	}
+	if containsAddr(o.AllowedCIDRs, addr) {
+		return true
+	}
+	return o.AllowPrivateIPs || !isBlockedIP(ip)
+}
+
+// isBlockedIP returns true if the IP is in one of the built-in blocked ranges.
+func isBlockedIP(ip net.IP) bool {
+	addr, ok := ipAddr(ip)
+	if !ok {
+		return true
+	}
+	return containsAddr(blockedPrefixes, addr)
+}
+
+// ipAddr converts ip to a netip.Addr, unmapping IPv4-mapped IPv6 addresses.
+func ipAddr(ip net.IP) (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	return addr.Unmap(), ok
+}
+
+// containsAddr reports whether any prefix contains addr.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
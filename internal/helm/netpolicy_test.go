@@ -0,0 +1,101 @@
+package helm
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	t.Run("prefixes and bare addresses", func(t *testing.T) {
+		got, err := ParseCIDRs([]string{"10.1.2.0/24", " 192.168.1.7 ", "fd00::/8", "", "10.1.2.3/24", "::ffff:10.9.0.0/112"})
+		require.NoError(t, err)
+		assert.Equal(t, []netip.Prefix{
+			netip.MustParsePrefix("10.1.2.0/24"),
+			netip.MustParsePrefix("192.168.1.7/32"),
+			netip.MustParsePrefix("fd00::/8"),
+			netip.MustParsePrefix("10.1.2.0/24"),
+			netip.MustParsePrefix("10.9.0.0/16"),
+		}, got)
+	})
+
+	t.Run("invalid entry", func(t *testing.T) {
+		_, err := ParseCIDRs([]string{"10.0.0.0/8", "not-a-cidr"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `"not-a-cidr"`)
+
+		_, err = ParseCIDRs([]string{"10.0.0.0/33"})
+		assert.Error(t, err)
+	})
+}
+
+func TestValidationOptions_AllowIP(t *testing.T) {
+	internal := []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}
+	metadata := []netip.Prefix{netip.MustParsePrefix("169.254.169.254/32")}
+
+	tests := []struct {
+		name string
+		opts ValidationOptions
+		ip   string
+		want bool
+	}{
+		{"public allowed by default", ValidationOptions{}, "93.184.216.34", true},
+		{"private blocked by default", ValidationOptions{}, "10.20.0.5", false},
+		{"allowed CIDR opens only that subnet", ValidationOptions{AllowedCIDRs: internal}, "10.20.0.5", true},
+		{"allowed CIDR leaves other private ranges blocked", ValidationOptions{AllowedCIDRs: internal}, "10.30.0.5", false},
+		{"allowed CIDR matches mapped address", ValidationOptions{AllowedCIDRs: internal}, "::ffff:10.20.0.5", true},
+		{"denied CIDR overrides allow private", ValidationOptions{AllowPrivateIPs: true, DeniedCIDRs: metadata}, "169.254.169.254", false},
+		{"denied CIDR overrides allowed CIDR", ValidationOptions{AllowedCIDRs: internal, DeniedCIDRs: []netip.Prefix{netip.MustParsePrefix("10.20.1.0/24")}}, "10.20.1.1", false},
+		{"denied CIDR blocks public address", ValidationOptions{DeniedCIDRs: []netip.Prefix{netip.MustParsePrefix("93.184.216.0/24")}}, "93.184.216.34", false},
+		{"allow private still permits other ranges", ValidationOptions{AllowPrivateIPs: true, DeniedCIDRs: metadata}, "127.0.0.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.opts.allowIP(net.ParseIP(tt.ip)))
+		})
+	}
+
+	t.Run("nil IP is refused", func(t *testing.T) {
+		assert.False(t, ValidationOptions{AllowPrivateIPs: true}.allowIP(nil))
+	})
+}
+
+func TestClient_AllowedCIDRs(t *testing.T) {
+	ctx := context.Background()
+	archive := buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n")
+
+	newClient := func(opts ...Option) *Client {
+		return NewClient(append([]Option{
+			WithTimeout(5 * time.Second),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+		}, opts...)...)
+	}
+
+	t.Run("loopback allowed by CIDR", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		client := newClient(WithAllowedCIDRs([]netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}))
+		values, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, "replicaCount: 1\n", string(values))
+	})
+
+	t.Run("denied CIDR wins over allow private", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		client := newClient(
+			WithAllowPrivateIPs(true),
+			WithDeniedCIDRs([]netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}),
+		)
+		_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.Error(t, err)
+		assert.True(t, IsURLValidationError(err), "expected URLValidationError, got %T: %v", err, err)
+		assert.Equal(t, int64(0), repo.downloads.Load())
+	})
+}
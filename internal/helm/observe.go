@@ -0,0 +1,52 @@
+package helm
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/traceutil"
+)
+
+// FetchOp names a kind of outbound request to a repository or registry.
+type FetchOp string
+
+// Outbound request kinds reported to a FetchObserver.
+const (
+	FetchIndex    FetchOp = "index"        // Repository index download
+	FetchChart    FetchOp = "chart"        // Chart archive download from an HTTP repository
+	FetchOCITags  FetchOp = "oci_tags"     // OCI tag listing
+	FetchOCIPull  FetchOp = "oci_pull"     // OCI chart pull
+	FetchManifest FetchOp = "oci_manifest" // OCI manifest resolution, to look up a chart in the disk cache
+)
+
+// FetchObserver is notified of every outbound request, e.g. to export
+// metrics. Requests served from the memory or disk caches are not reported.
+// Implementations must be safe for concurrent use.
+type FetchObserver interface {
+	// ObserveFetch reports a finished request to host: how long it took, how
+	// many bytes were downloaded, and the error if it failed.
+	ObserveFetch(host string, op FetchOp, duration time.Duration, bytes int64, err error)
+}
+
+// tracerName names the tracer of this package's spans.
+const tracerName = "github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+
+// startFetch starts a request to repoURL, tracing it in a span. The returned
+// function ends the span and reports the request to the fetch observer.
+func (c *Client) startFetch(ctx context.Context, repoURL string, op FetchOp) (context.Context, func(bytes int64, err error)) {
+	host := repoHost(repoURL)
+	ctx, span := traceutil.StartSpan(ctx, tracerName, "helm.fetch."+string(op),
+		attribute.String("server.address", host),
+		attribute.String("mcp_helm.fetch.operation", string(op)),
+	)
+	start := time.Now()
+	return ctx, func(bytes int64, err error) {
+		span.SetAttributes(attribute.Int64("mcp_helm.fetch.bytes", bytes))
+		traceutil.EndSpan(span, err)
+		if c.opts.fetchObserver != nil {
+			c.opts.fetchObserver.ObserveFetch(host, op, time.Since(start), bytes, err)
+		}
+	}
+}
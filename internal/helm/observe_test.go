@@ -0,0 +1,203 @@
+package helm
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+// fetchRecorder records observed fetches.
+type fetchRecorder struct {
+	mu      sync.Mutex
+	fetches []observedFetch
+}
+
+type observedFetch struct {
+	host  string
+	op    FetchOp
+	bytes int64
+	err   error
+}
+
+func (r *fetchRecorder) ObserveFetch(host string, op FetchOp, _ time.Duration, bytes int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetches = append(r.fetches, observedFetch{host, op, bytes, err})
+}
+
+func (r *fetchRecorder) observed() []observedFetch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]observedFetch(nil), r.fetches...)
+}
+
+func TestClient_FetchObserver(t *testing.T) {
+	ctx := context.Background()
+	archive := buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n")
+	repo := newChartRepo(t, "app", "1.0.0", archive)
+	u, err := url.Parse(repo.URL)
+	require.NoError(t, err)
+
+	recorder := &fetchRecorder{}
+	client := NewClient(
+		WithAllowPrivateIPs(true),
+		WithCacheDir(t.TempDir()),
+		WithLogger(zap.NewNop()),
+		WithFetchObserver(recorder),
+	)
+
+	_, err = client.GetValues(ctx, repo.URL, "app", "1.0.0")
+	require.NoError(t, err)
+
+	fetches := recorder.observed()
+	require.Len(t, fetches, 2)
+	assert.Equal(t, FetchIndex, fetches[0].op)
+	assert.Equal(t, u.Hostname(), fetches[0].host)
+	assert.Positive(t, fetches[0].bytes)
+	assert.Equal(t, FetchChart, fetches[1].op)
+	assert.Equal(t, int64(len(archive)), fetches[1].bytes)
+	assert.NoError(t, fetches[1].err)
+
+	// Cached charts are not fetched again
+	_, err = client.GetValues(ctx, repo.URL, "app", "1.0.0")
+	require.NoError(t, err)
+	assert.Len(t, recorder.observed(), 2)
+
+	// Failed fetches are reported with their error
+	down := newFlakyIndexServer(t)
+	down.down.Store(true)
+	_, err = client.ListCharts(ctx, down.URL)
+	require.Error(t, err)
+	fetches = recorder.observed()
+	require.Len(t, fetches, 3)
+	assert.Equal(t, FetchIndex, fetches[2].op)
+	assert.Error(t, fetches[2].err)
+}
+
+func TestClient_FetchObserver_OCI(t *testing.T) {
+	ctx := context.Background()
+	archive := buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n")
+	reg := newFakeRegistry(t, "charts/app")
+	manifest := reg.pushChart(t, "app", "1.0.0", archive)
+	cacheDir := t.TempDir()
+
+	newClient := func(recorder *fetchRecorder) *Client {
+		client := NewClient(
+			WithAllowPrivateIPs(true),
+			WithCacheDir(cacheDir),
+			WithLogger(zap.NewNop()),
+			WithFetchObserver(recorder),
+		)
+		reg.connect(t, client)
+		return client
+	}
+
+	recorder := &fetchRecorder{}
+	_, err := newClient(recorder).GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+	require.NoError(t, err)
+	fetches := recorder.observed()
+	require.Len(t, fetches, 2)
+	assert.Equal(t, FetchManifest, fetches[0].op)
+	assert.NoError(t, fetches[0].err)
+	assert.Equal(t, FetchOCIPull, fetches[1].op)
+
+	// A new client finds the chart in the disk cache after resolving its tag
+	recorder = &fetchRecorder{}
+	_, err = newClient(recorder).GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+	require.NoError(t, err)
+	fetches = recorder.observed()
+	require.Len(t, fetches, 1)
+	assert.Equal(t, FetchManifest, fetches[0].op)
+
+	// A cached chart that does not match the manifest's chart layer is pulled again
+	recorder = &fetchRecorder{}
+	client := newClient(recorder)
+	key := makeChartKey(reg.ociURL(), "app", "1.0.0") + manifest.Digest.String()
+	poisoned := buildChartArchive(t, "app", "1.0.0", "replicaCount: 99\n")
+	require.NoError(t, client.disk.put(diskKindChart, key, poisoned, diskEntry{FetchedAt: time.Now()}))
+	values, err := client.GetValues(ctx, reg.ociURL(), "app", "1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "replicaCount: 1\n", string(values))
+	fetches = recorder.observed()
+	require.Len(t, fetches, 2)
+	assert.Equal(t, FetchOCIPull, fetches[1].op)
+}
+
+// recordSpans installs a global tracer provider exporting to an in-memory
+// exporter for the duration of the test.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return exporter
+}
+
+// spanNamed returns the first recorded span with the given name.
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	require.Failf(t, "span not recorded", "no span named %q", name)
+	return tracetest.SpanStub{}
+}
+
+func TestClient_Tracing(t *testing.T) {
+	exporter := recordSpans(t)
+	archive := buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n")
+	repo := newChartRepo(t, "app", "1.0.0", archive)
+
+	client := NewClient(
+		WithAllowPrivateIPs(true),
+		WithCacheDir(t.TempDir()),
+		WithLogger(zap.NewNop()),
+	)
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+	require.NoError(t, err)
+	root.End()
+
+	spans := exporter.GetSpans()
+	for _, s := range spans {
+		assert.Equal(t, root.SpanContext().TraceID(), s.SpanContext.TraceID(), "span %q", s.Name)
+	}
+
+	getIndex := spanNamed(t, spans, "helm.getIndex")
+	fetchIndex := spanNamed(t, spans, "helm.fetch.index")
+	assert.Equal(t, getIndex.SpanContext.SpanID(), fetchIndex.Parent.SpanID())
+	assert.Contains(t, fetchIndex.Attributes, attribute.String("mcp_helm.fetch.operation", "index"))
+
+	validate := spanNamed(t, spans, "helm.ValidateURL")
+	lookup := spanNamed(t, spans, "dns.lookup")
+	assert.Equal(t, validate.SpanContext.SpanID(), lookup.Parent.SpanID())
+
+	spanNamed(t, spans, "helm.fetch.chart")
+	load := spanNamed(t, spans, "helm.loader.Load")
+	assert.Contains(t, load.Attributes, attribute.Int("mcp_helm.chart.archive_bytes", len(archive)))
+
+	// Failures are recorded on the span
+	exporter.Reset()
+	down := newFlakyIndexServer(t)
+	down.down.Store(true)
+	_, err = client.ListCharts(context.Background(), down.URL)
+	require.Error(t, err)
+	failed := spanNamed(t, exporter.GetSpans(), "helm.fetch.index")
+	assert.Equal(t, codes.Error, failed.Status.Code)
+	assert.Equal(t, codes.Error, spanNamed(t, exporter.GetSpans(), "helm.getIndex").Status.Code)
+}
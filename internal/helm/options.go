@@ -1,11 +1,13 @@
 package helm
 
 import (
+	"net/netip"
 	"os"
 	"path/filepath"
 	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/net/http/httpproxy"
 )
 
 // Option configures a Client.
@@ -13,30 +15,64 @@ type Option func(*clientOptions)
 
 // clientOptions holds the configuration for a Client.
 type clientOptions struct {
-	timeout         time.Duration
-	indexTTL        time.Duration
-	indexCacheSize  int
-	chartCacheSize  int
-	maxOutputBytes  int
-	maxChartBytes   int64
-	allowPrivateIPs bool
-	allowedHosts    []string
-	deniedHosts     []string
-	cacheDir        string
-	logger          *zap.Logger
+	timeout           time.Duration
+	indexTTL          time.Duration
+	indexMaxStale     time.Duration
+	staleRevalidate   bool
+	failureTTL        time.Duration
+	indexCacheSize    int
+	indexCacheBytes   int64
+	chartCacheSize    int
+	chartCacheBytes   int64
+	diskCacheBytes    int64
+	maxOutputBytes    int
+	maxChartBytes     int64
+	maxIndexBytes     int64
+	archiveLimits     ArchiveLimits
+	allowPrivateIPs   bool
+	allowedHosts      []string
+	deniedHosts       []string
+	allowedCIDRs      []netip.Prefix
+	deniedCIDRs       []netip.Prefix
+	restrictChartHost bool
+	plainHTTP         bool // Reach OCI registries over HTTP
+	provenance        verificationPolicy
+	keyring           string
+	cosign            verificationPolicy
+	cosignKeys        []string
+	policy            *Policy
+	resolver          ipResolver        // nil uses net.DefaultResolver
+	proxy             *httpproxy.Config // Proxies for outbound requests
+	cacheDir          string
+	maxFetches        int
+	maxHostFetches    int
+	fetchQueueTimeout time.Duration
+	fetchObserver     FetchObserver
+	logger            *zap.Logger
 }
 
 // defaultOptions returns the default client options.
 func defaultOptions() *clientOptions {
 	return &clientOptions{
-		timeout:        30 * time.Second,
-		indexTTL:       5 * time.Minute,
-		indexCacheSize: 100,
-		chartCacheSize: 50,
-		maxOutputBytes: 2 * 1024 * 1024,
-		maxChartBytes:  50 * 1024 * 1024, // 50 MB
-		cacheDir:       filepath.Join(os.TempDir(), "mcp-helm-cache"),
-		logger:         zap.NewNop(),
+		timeout:           30 * time.Second,
+		indexTTL:          5 * time.Minute,
+		indexCacheSize:    100,
+		indexCacheBytes:   256 * 1024 * 1024, // 256 MB
+		chartCacheSize:    50,
+		chartCacheBytes:   256 * 1024 * 1024,  // 256 MB
+		diskCacheBytes:    1024 * 1024 * 1024, // 1 GB
+		maxOutputBytes:    2 * 1024 * 1024,
+		maxChartBytes:     50 * 1024 * 1024,  // 50 MB
+		maxIndexBytes:     100 * 1024 * 1024, // 100 MB
+		archiveLimits:     defaultArchiveLimits(),
+		maxFetches:        32,
+		maxHostFetches:    8,
+		fetchQueueTimeout: 30 * time.Second,
+		provenance:        verificationPolicy{mode: VerifyOff},
+		cosign:            verificationPolicy{mode: VerifyOff},
+		proxy:             httpproxy.FromEnvironment(),
+		cacheDir:          defaultCacheDir(),
+		logger:            zap.NewNop(),
 	}
 }
 
@@ -58,6 +94,37 @@ func WithIndexTTL(d time.Duration) Option {
 	}
 }
 
+// WithIndexMaxStale sets the maximum age of an expired repository index that
+// may still be served when refreshing it fails. Zero (the default) disables
+// serving stale indexes.
+func WithIndexMaxStale(d time.Duration) Option {
+	return func(o *clientOptions) {
+		if d >= 0 {
+			o.indexMaxStale = d
+		}
+	}
+}
+
+// WithStaleWhileRevalidate serves an expired repository index immediately
+// while it is refreshed in the background, instead of waiting for the refresh.
+// It only applies to indexes younger than the max stale age.
+func WithStaleWhileRevalidate(enabled bool) Option {
+	return func(o *clientOptions) {
+		o.staleRevalidate = enabled
+	}
+}
+
+// WithFailureTTL sets how long chart-not-found results and repository
+// failures are cached, so that retries fail fast instead of querying the
+// repository again. Zero (the default) disables caching failures.
+func WithFailureTTL(d time.Duration) Option {
+	return func(o *clientOptions) {
+		if d >= 0 {
+			o.failureTTL = d
+		}
+	}
+}
+
 // WithIndexCacheSize sets the maximum number of repository indexes to cache.
 func WithIndexCacheSize(n int) Option {
 	return func(o *clientOptions) {
@@ -76,6 +143,36 @@ func WithChartCacheSize(n int) Option {
 	}
 }
 
+// WithIndexCacheBytes sets the memory budget in bytes for cached repository
+// indexes, based on their estimated in-memory size. Zero removes the byte
+// limit, leaving only the entry count limit.
+func WithIndexCacheBytes(n int64) Option {
+	return func(o *clientOptions) {
+		if n >= 0 {
+			o.indexCacheBytes = n
+		}
+	}
+}
+
+// WithChartCacheBytes sets the memory budget in bytes for cached charts,
+// based on their estimated in-memory size. Zero removes the byte limit,
+// leaving only the entry count limit.
+func WithChartCacheBytes(n int64) Option {
+	return func(o *clientOptions) {
+		if n >= 0 {
+			o.chartCacheBytes = n
+		}
+	}
+}
+
+// WithDiskCacheSize sets the maximum size in bytes of the persistent chart and
+// index cache under the cache directory. Zero or a negative value disables it.
+func WithDiskCacheSize(n int64) Option {
+	return func(o *clientOptions) {
+		o.diskCacheBytes = n
+	}
+}
+
 // WithMaxOutputBytes sets the maximum output size for tool responses.
 func WithMaxOutputBytes(n int) Option {
 	return func(o *clientOptions) {
@@ -94,6 +191,34 @@ func WithMaxChartBytes(n int64) Option {
 	}
 }
 
+// WithMaxIndexBytes sets the maximum allowed size for repository index files.
+func WithMaxIndexBytes(n int64) Option {
+	return func(o *clientOptions) {
+		if n > 0 {
+			o.maxIndexBytes = n
+		}
+	}
+}
+
+// WithArchiveLimits sets the limits enforced while decompressing chart archives.
+// Non-positive fields keep their defaults.
+func WithArchiveLimits(l ArchiveLimits) Option {
+	return func(o *clientOptions) {
+		if l.MaxTotalBytes > 0 {
+			o.archiveLimits.MaxTotalBytes = l.MaxTotalBytes
+		}
+		if l.MaxFiles > 0 {
+			o.archiveLimits.MaxFiles = l.MaxFiles
+		}
+		if l.MaxFileBytes > 0 {
+			o.archiveLimits.MaxFileBytes = l.MaxFileBytes
+		}
+		if l.MaxPathDepth > 0 {
+			o.archiveLimits.MaxPathDepth = l.MaxPathDepth
+		}
+	}
+}
+
 // WithAllowPrivateIPs allows URLs that resolve to private IP addresses.
 func WithAllowPrivateIPs(allow bool) Option {
 	return func(o *clientOptions) {
@@ -115,6 +240,118 @@ func WithDeniedHosts(hosts []string) Option {
 	}
 }
 
+// WithAllowedCIDRs permits connections to the given networks even when they
+// fall in a range that is blocked by default.
+func WithAllowedCIDRs(prefixes []netip.Prefix) Option {
+	return func(o *clientOptions) {
+		o.allowedCIDRs = prefixes
+	}
+}
+
+// WithDeniedCIDRs refuses connections to the given networks, regardless of
+// WithAllowPrivateIPs and WithAllowedCIDRs.
+func WithDeniedCIDRs(prefixes []netip.Prefix) Option {
+	return func(o *clientOptions) {
+		o.deniedCIDRs = prefixes
+	}
+}
+
+// WithPlainHTTP reaches OCI registries over plain HTTP instead of HTTPS,
+// e.g. for a registry on the local network.
+func WithPlainHTTP(plain bool) Option {
+	return func(o *clientOptions) {
+		o.plainHTTP = plain
+	}
+}
+
+// WithRestrictChartHost restricts chart downloads, including redirects, to the
+// host of the repository that lists them.
+func WithRestrictChartHost(restrict bool) Option {
+	return func(o *clientOptions) {
+		o.restrictChartHost = restrict
+	}
+}
+
+// WithProvenanceMode sets how Helm provenance (.prov) signatures are treated
+// for charts downloaded from HTTP repositories.
+func WithProvenanceMode(m VerificationMode) Option {
+	return func(o *clientOptions) {
+		if m != "" {
+			o.provenance.mode = m
+		}
+	}
+}
+
+// WithProvenanceKeyring sets the PGP keyring used to verify provenance files.
+func WithProvenanceKeyring(path string) Option {
+	return func(o *clientOptions) {
+		o.keyring = path
+	}
+}
+
+// WithProvenanceHosts limits provenance verification to repositories on the given hosts.
+// An empty list applies verification to all repositories.
+func WithProvenanceHosts(hosts []string) Option {
+	return func(o *clientOptions) {
+		o.provenance.hosts = hosts
+	}
+}
+
+// WithProvenanceSkipHosts exempts repositories on the given hosts from provenance verification.
+func WithProvenanceSkipHosts(hosts []string) Option {
+	return func(o *clientOptions) {
+		o.provenance.skipHosts = hosts
+	}
+}
+
+// WithCosignMode sets how cosign signatures are treated for charts pulled
+// from OCI registries.
+func WithCosignMode(m VerificationMode) Option {
+	return func(o *clientOptions) {
+		if m != "" {
+			o.cosign.mode = m
+		}
+	}
+}
+
+// WithCosignKeys sets the PEM-encoded public key files trusted for cosign signatures.
+func WithCosignKeys(paths []string) Option {
+	return func(o *clientOptions) {
+		o.cosignKeys = paths
+	}
+}
+
+// WithCosignHosts limits cosign verification to registries on the given hosts.
+// An empty list applies verification to all registries.
+func WithCosignHosts(hosts []string) Option {
+	return func(o *clientOptions) {
+		o.cosign.hosts = hosts
+	}
+}
+
+// WithCosignSkipHosts exempts registries on the given hosts from cosign verification.
+func WithCosignSkipHosts(hosts []string) Option {
+	return func(o *clientOptions) {
+		o.cosign.skipHosts = hosts
+	}
+}
+
+// WithPolicy sets the chart policy evaluated before charts are downloaded.
+func WithPolicy(p *Policy) Option {
+	return func(o *clientOptions) {
+		o.policy = p
+	}
+}
+
+// defaultCacheDir returns the per-user cache directory for mcp-helm, or a
+// directory under the system temp directory if the user has none.
+func defaultCacheDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "mcp-helm")
+	}
+	return filepath.Join(os.TempDir(), "mcp-helm-cache")
+}
+
 // WithCacheDir sets the directory for Helm caches.
 func WithCacheDir(dir string) Option {
 	return func(o *clientOptions) {
@@ -124,6 +361,44 @@ func WithCacheDir(dir string) Option {
 	}
 }
 
+// WithMaxFetches limits the downloads in progress at once across all hosts.
+// Zero removes the limit. Default is 32.
+func WithMaxFetches(n int) Option {
+	return func(o *clientOptions) {
+		if n >= 0 {
+			o.maxFetches = n
+		}
+	}
+}
+
+// WithMaxHostFetches limits the downloads in progress at once from each
+// repository or registry host. Zero removes the limit. Default is 8.
+func WithMaxHostFetches(n int) Option {
+	return func(o *clientOptions) {
+		if n >= 0 {
+			o.maxHostFetches = n
+		}
+	}
+}
+
+// WithFetchQueueTimeout sets how long a download over the concurrency limits
+// waits for a free slot before failing with a FetchQueueTimeoutError. Zero
+// waits as long as the request allows. Default is 30s.
+func WithFetchQueueTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		if d >= 0 {
+			o.fetchQueueTimeout = d
+		}
+	}
+}
+
+// WithFetchObserver reports outbound requests to o, e.g. to export metrics.
+func WithFetchObserver(o FetchObserver) Option {
+	return func(opts *clientOptions) {
+		opts.fetchObserver = o
+	}
+}
+
 // WithLogger sets the logger for the client.
 func WithLogger(l *zap.Logger) Option {
 	return func(o *clientOptions) {
@@ -1,8 +1,7 @@
 package helm
 
 import (
-	"os"
-	"path/filepath"
+	"net/netip"
 	"testing"
 	"time"
 
@@ -18,7 +17,7 @@ func TestDefaultOptions(t *testing.T) {
 	assert.Equal(t, 100, opts.indexCacheSize)
 	assert.Equal(t, 50, opts.chartCacheSize)
 	assert.Equal(t, 2*1024*1024, opts.maxOutputBytes)
-	assert.Equal(t, filepath.Join(os.TempDir(), "mcp-helm-cache"), opts.cacheDir)
+	assert.Equal(t, defaultCacheDir(), opts.cacheDir)
 	assert.NotNil(t, opts.logger)
 	assert.False(t, opts.allowPrivateIPs)
 	assert.Nil(t, opts.allowedHosts)
@@ -64,6 +63,31 @@ func TestWithIndexTTL(t *testing.T) {
 	})
 }
 
+func TestWithStaleIndexOptions(t *testing.T) {
+	opts := defaultOptions()
+	assert.Zero(t, opts.indexMaxStale)
+	assert.False(t, opts.staleRevalidate)
+
+	WithIndexMaxStale(24 * time.Hour)(opts)
+	WithStaleWhileRevalidate(true)(opts)
+	assert.Equal(t, 24*time.Hour, opts.indexMaxStale)
+	assert.True(t, opts.staleRevalidate)
+
+	WithIndexMaxStale(-time.Second)(opts)
+	assert.Equal(t, 24*time.Hour, opts.indexMaxStale) // unchanged
+}
+
+func TestWithFailureTTL(t *testing.T) {
+	opts := defaultOptions()
+	assert.Zero(t, opts.failureTTL)
+
+	WithFailureTTL(30 * time.Second)(opts)
+	assert.Equal(t, 30*time.Second, opts.failureTTL)
+
+	WithFailureTTL(-time.Second)(opts)
+	assert.Equal(t, 30*time.Second, opts.failureTTL) // unchanged
+}
+
 func TestWithIndexCacheSize(t *testing.T) {
 	t.Run("positive size", func(t *testing.T) {
 		opts := defaultOptions()
@@ -126,6 +150,64 @@ func TestWithMaxOutputBytes(t *testing.T) {
 	})
 }
 
+func TestWithMaxIndexBytes(t *testing.T) {
+	opts := defaultOptions()
+	assert.Equal(t, int64(100*1024*1024), opts.maxIndexBytes)
+
+	WithMaxIndexBytes(1024)(opts)
+	assert.Equal(t, int64(1024), opts.maxIndexBytes)
+
+	WithMaxIndexBytes(0)(opts)
+	assert.Equal(t, int64(1024), opts.maxIndexBytes) // unchanged
+}
+
+func TestWithArchiveLimits(t *testing.T) {
+	opts := defaultOptions()
+	WithArchiveLimits(ArchiveLimits{MaxFiles: 10, MaxPathDepth: -1})(opts)
+
+	assert.Equal(t, 10, opts.archiveLimits.MaxFiles)
+	assert.Equal(t, defaultArchiveLimits().MaxTotalBytes, opts.archiveLimits.MaxTotalBytes) // default unchanged
+	assert.Equal(t, defaultArchiveLimits().MaxPathDepth, opts.archiveLimits.MaxPathDepth)   // default unchanged
+}
+
+func TestWithCacheBytes(t *testing.T) {
+	opts := defaultOptions()
+	WithIndexCacheBytes(64 * 1024 * 1024)(opts)
+	WithChartCacheBytes(0)(opts)
+	assert.Equal(t, int64(64*1024*1024), opts.indexCacheBytes)
+	assert.Equal(t, int64(0), opts.chartCacheBytes)
+
+	WithIndexCacheBytes(-1)(opts)
+	assert.Equal(t, int64(64*1024*1024), opts.indexCacheBytes) // unchanged
+}
+
+func TestWithDiskCacheSize(t *testing.T) {
+	opts := defaultOptions()
+	assert.Equal(t, int64(1024*1024*1024), opts.diskCacheBytes)
+
+	WithDiskCacheSize(0)(opts)
+	assert.Equal(t, int64(0), opts.diskCacheBytes)
+}
+
+func TestWithFetchLimits(t *testing.T) {
+	opts := defaultOptions()
+	assert.Equal(t, 32, opts.maxFetches)
+	assert.Equal(t, 8, opts.maxHostFetches)
+	assert.Equal(t, 30*time.Second, opts.fetchQueueTimeout)
+
+	WithMaxFetches(0)(opts)
+	WithMaxHostFetches(2)(opts)
+	WithFetchQueueTimeout(0)(opts)
+	assert.Equal(t, 0, opts.maxFetches)
+	assert.Equal(t, 2, opts.maxHostFetches)
+	assert.Equal(t, time.Duration(0), opts.fetchQueueTimeout)
+
+	WithMaxHostFetches(-1)(opts)
+	WithFetchQueueTimeout(-time.Second)(opts)
+	assert.Equal(t, 2, opts.maxHostFetches) // unchanged
+	assert.Equal(t, time.Duration(0), opts.fetchQueueTimeout)
+}
+
 func TestWithAllowPrivateIPs(t *testing.T) {
 	t.Run("enable private IPs", func(t *testing.T) {
 		opts := defaultOptions()
@@ -143,6 +225,18 @@ func TestWithAllowPrivateIPs(t *testing.T) {
 	})
 }
 
+func TestWithCIDRs(t *testing.T) {
+	allowed := []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}
+	denied := []netip.Prefix{netip.MustParsePrefix("169.254.169.254/32")}
+
+	opts := defaultOptions()
+	WithAllowedCIDRs(allowed)(opts)
+	WithDeniedCIDRs(denied)(opts)
+
+	assert.Equal(t, allowed, opts.allowedCIDRs)
+	assert.Equal(t, denied, opts.deniedCIDRs)
+}
+
 func TestWithAllowedHosts(t *testing.T) {
 	t.Run("set allowed hosts", func(t *testing.T) {
 		opts := defaultOptions()
@@ -185,6 +279,59 @@ func TestWithDeniedHosts(t *testing.T) {
 	})
 }
 
+func TestWithProvenance(t *testing.T) {
+	t.Run("defaults to off", func(t *testing.T) {
+		opts := defaultOptions()
+
+		assert.Equal(t, VerifyOff, opts.provenance.mode)
+		assert.Empty(t, opts.keyring)
+	})
+
+	t.Run("set mode, keyring and hosts", func(t *testing.T) {
+		opts := defaultOptions()
+		WithProvenanceMode(VerifyEnforce)(opts)
+		WithProvenanceKeyring("/keys/pubring.gpg")(opts)
+		WithProvenanceHosts([]string{"charts.example.com"})(opts)
+		WithProvenanceSkipHosts([]string{"internal.example.com"})(opts)
+
+		assert.Equal(t, VerifyEnforce, opts.provenance.mode)
+		assert.Equal(t, "/keys/pubring.gpg", opts.keyring)
+		assert.Equal(t, []string{"charts.example.com"}, opts.provenance.hosts)
+		assert.Equal(t, []string{"internal.example.com"}, opts.provenance.skipHosts)
+	})
+
+	t.Run("empty mode ignored", func(t *testing.T) {
+		opts := defaultOptions()
+		WithProvenanceMode(VerifyAnnotate)(opts)
+		WithProvenanceMode("")(opts)
+
+		assert.Equal(t, VerifyAnnotate, opts.provenance.mode)
+	})
+}
+
+func TestWithCosign(t *testing.T) {
+	t.Run("defaults to off", func(t *testing.T) {
+		opts := defaultOptions()
+
+		assert.Equal(t, VerifyOff, opts.cosign.mode)
+		assert.Empty(t, opts.cosignKeys)
+	})
+
+	t.Run("set mode, keys and hosts", func(t *testing.T) {
+		opts := defaultOptions()
+		WithCosignMode(VerifyEnforce)(opts)
+		WithCosignKeys([]string{"/keys/cosign.pub"})(opts)
+		WithCosignHosts([]string{"ghcr.io"})(opts)
+		WithCosignSkipHosts([]string{"registry.internal"})(opts)
+
+		assert.Equal(t, VerifyEnforce, opts.cosign.mode)
+		assert.Equal(t, []string{"/keys/cosign.pub"}, opts.cosignKeys)
+		assert.Equal(t, []string{"ghcr.io"}, opts.cosign.hosts)
+		assert.Equal(t, []string{"registry.internal"}, opts.cosign.skipHosts)
+		assert.Equal(t, VerifyOff, opts.provenance.mode, "cosign options must not affect provenance")
+	})
+}
+
 func TestWithCacheDir(t *testing.T) {
 	t.Run("set cache directory", func(t *testing.T) {
 		opts := defaultOptions()
@@ -197,7 +344,7 @@ func TestWithCacheDir(t *testing.T) {
 		opts := defaultOptions()
 		WithCacheDir("")(opts)
 
-		assert.Equal(t, filepath.Join(os.TempDir(), "mcp-helm-cache"), opts.cacheDir)
+		assert.Equal(t, defaultCacheDir(), opts.cacheDir)
 	})
 }
 
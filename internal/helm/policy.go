@@ -0,0 +1,248 @@
+package helm
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/goccy/go-yaml"
+)
+
+// PolicyEffect is the outcome of a matching policy rule.
+type PolicyEffect string
+
+// Policy effects.
+const (
+	PolicyAllow PolicyEffect = "allow"
+	PolicyDeny  PolicyEffect = "deny"
+)
+
+// Names reported for built-in policy checks.
+const (
+	policyRuleDefault        = "default"
+	policyRuleDenyDeprecated = "denyDeprecated"
+	policyRuleRequireSigned  = "requireSigned"
+)
+
+// Policy restricts which charts may be read, independently of host validation.
+//
+// Rules are evaluated in order and the first matching rule decides; if none
+// match, Default applies. A policy file looks like:
+//
+//	default: deny
+//	denyDeprecated: true
+//	rules:
+//	  - name: no-wordpress
+//	    effect: deny
+//	    repository: charts.bitnami.com/bitnami
+//	    chart: wordpress
+//	  - name: bitnami
+//	    effect: allow
+//	    repository: charts.bitnami.com/*
+//	    versions: ">= 10.0.0"
+type Policy struct {
+	Default        PolicyEffect `yaml:"default"`
+	DenyDeprecated bool         `yaml:"denyDeprecated"`
+	RequireSigned  bool         `yaml:"requireSigned"`
+	Rules          []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule matches charts by repository, name and version range.
+// Empty fields match everything.
+type PolicyRule struct {
+	Name   string       `yaml:"name"`
+	Effect PolicyEffect `yaml:"effect"`
+	// Repository is a glob matched against the repository URL. '*' matches
+	// any sequence of characters, including '/'. Patterns without a scheme
+	// match the URL with its scheme removed.
+	Repository string `yaml:"repository"`
+	// Chart is a glob matched against the chart name.
+	Chart string `yaml:"chart"`
+	// Versions is a semver constraint (e.g. ">= 1.2.0, < 2.0.0").
+	Versions string `yaml:"versions"`
+
+	repository  *regexp.Regexp
+	chart       *regexp.Regexp
+	constraints *semver.Constraints
+}
+
+// LoadPolicy reads and compiles a policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy file: %w", err)
+	}
+	p, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("policy file %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParsePolicy parses and compiles a YAML policy. Unknown fields are rejected
+// so that typos do not silently weaken the policy.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.UnmarshalWithOptions(data, &p, yaml.Strict()); err != nil {
+		return nil, err
+	}
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// compile validates the policy and prepares its matchers.
+func (p *Policy) compile() error {
+	switch p.Default {
+	case "":
+		p.Default = PolicyAllow
+	case PolicyAllow, PolicyDeny:
+	default:
+		return fmt.Errorf("invalid default effect %q: must be allow or deny", p.Default)
+	}
+
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rules[%d]", i)
+		}
+		if r.Effect != PolicyAllow && r.Effect != PolicyDeny {
+			return fmt.Errorf("rule %q: invalid effect %q: must be allow or deny", r.Name, r.Effect)
+		}
+		if r.Repository != "" {
+			r.repository = globRegexp(strings.TrimSuffix(r.Repository, "/"))
+		}
+		if r.Chart != "" {
+			r.chart = globRegexp(r.Chart)
+		}
+		if r.Versions != "" {
+			c, err := semver.NewConstraint(r.Versions)
+			if err != nil {
+				return fmt.Errorf("rule %q: invalid versions %q: %w", r.Name, r.Versions, err)
+			}
+			r.constraints = c
+		}
+	}
+	return nil
+}
+
+// globRegexp converts a glob with '*' and '?' wildcards to an anchored regexp.
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// policyTarget identifies the chart a policy decision is made for.
+// An empty version asks whether any version of the chart could be allowed.
+type policyTarget struct {
+	repository string
+	chart      string
+	version    string
+	deprecated bool
+}
+
+// evaluate returns a PolicyError if the target is denied, or nil if allowed.
+func (p *Policy) evaluate(t policyTarget) *PolicyError {
+	if p == nil {
+		return nil
+	}
+
+	deny := func(rule, reason string) *PolicyError {
+		return &PolicyError{Repository: t.repository, Chart: t.chart, Version: t.version, Rule: rule, Reason: reason}
+	}
+
+	if p.DenyDeprecated && t.deprecated {
+		return deny(policyRuleDenyDeprecated, "chart version is deprecated")
+	}
+
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if !r.matches(t) {
+			continue
+		}
+		if r.Effect == PolicyDeny {
+			return deny(r.Name, "matched deny rule")
+		}
+		return nil
+	}
+
+	if p.Default == PolicyDeny {
+		return deny(policyRuleDefault, "no allow rule matched")
+	}
+	return nil
+}
+
+// requireSignature returns a PolicyError if the policy requires signed charts
+// and the verification result (nil when no verification applies) is not verified.
+func (p *Policy) requireSignature(t policyTarget, v *Verification) *PolicyError {
+	if p == nil || !p.RequireSigned || v.Verified() {
+		return nil
+	}
+	reason := "signature verification is not configured for this repository"
+	if v != nil {
+		reason = fmt.Sprintf("chart signature is %s", v.Status)
+	}
+	return &PolicyError{Repository: t.repository, Chart: t.chart, Version: t.version, Rule: policyRuleRequireSigned, Reason: reason}
+}
+
+// matches reports whether the rule applies to the target. When the target has
+// no version, allow rules with a version range match (some version may be
+// allowed) while deny rules with a version range do not. A version that is
+// not semver, such as "latest", cannot be placed in a range, so it matches
+// ranged deny rules and no ranged allow rules.
+func (r *PolicyRule) matches(t policyTarget) bool {
+	if r.repository != nil && !matchRepository(r.repository, r.Repository, t.repository) {
+		return false
+	}
+	if r.chart != nil && !r.chart.MatchString(t.chart) {
+		return false
+	}
+	if r.constraints == nil {
+		return true
+	}
+	if t.version == "" {
+		return r.Effect == PolicyAllow
+	}
+	v, err := semver.NewVersion(t.version)
+	if err != nil {
+		return r.Effect == PolicyDeny
+	}
+	if r.constraints.Check(v) {
+		return true
+	}
+	// Constraints without a prerelease never match prereleases, which would
+	// let 1.5.0-rc.1 past a "< 2.0.0" deny rule. Deny rules match a
+	// prerelease when they match its release.
+	if r.Effect == PolicyDeny && v.Prerelease() != "" {
+		release, err := v.SetPrerelease("")
+		return err == nil && r.constraints.Check(&release)
+	}
+	return false
+}
+
+// matchRepository matches a repository URL against a compiled pattern,
+// ignoring the URL scheme when the pattern has none.
+func matchRepository(re *regexp.Regexp, pattern, repoURL string) bool {
+	repoURL = strings.TrimSuffix(repoURL, "/")
+	if !strings.Contains(pattern, "://") {
+		if _, rest, ok := strings.Cut(repoURL, "://"); ok {
+			repoURL = rest
+		}
+	}
+	return re.MatchString(repoURL)
+}
@@ -0,0 +1,241 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParsePolicy(t *testing.T) {
+	t.Run("valid policy", func(t *testing.T) {
+		p, err := ParsePolicy([]byte(`
+default: deny
+denyDeprecated: true
+rules:
+  - name: no-wordpress
+    effect: deny
+    chart: wordpress
+  - effect: allow
+    repository: charts.bitnami.com/*
+    versions: ">= 10.0.0"
+`))
+		require.NoError(t, err)
+		assert.Equal(t, PolicyDeny, p.Default)
+		assert.True(t, p.DenyDeprecated)
+		require.Len(t, p.Rules, 2)
+		assert.Equal(t, "no-wordpress", p.Rules[0].Name)
+		assert.Equal(t, "rules[1]", p.Rules[1].Name, "unnamed rules get a positional name")
+	})
+
+	t.Run("empty policy allows by default", func(t *testing.T) {
+		p, err := ParsePolicy([]byte("{}"))
+		require.NoError(t, err)
+		assert.Equal(t, PolicyAllow, p.Default)
+	})
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"invalid default", "default: maybe", "invalid default effect"},
+		{"invalid effect", "rules:\n  - name: r\n    effect: block", `rule "r": invalid effect`},
+		{"missing effect", "rules:\n  - name: r\n    chart: nginx", `rule "r": invalid effect`},
+		{"invalid version range", "rules:\n  - name: r\n    effect: deny\n    versions: \"not a range\"", `rule "r": invalid versions`},
+		{"unknown field", "rules:\n  - name: r\n    effect: deny\n    charts: nginx", "charts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePolicy([]byte(tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("default: deny\n"), 0o600))
+
+	p, err := LoadPolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, PolicyDeny, p.Default)
+
+	_, err = LoadPolicy(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	p, err := ParsePolicy([]byte(`
+default: deny
+denyDeprecated: true
+rules:
+  - name: no-wordpress
+    effect: deny
+    repository: charts.bitnami.com/bitnami
+    chart: wordpress
+  - name: no-old-postgres
+    effect: deny
+    chart: postgresql
+    versions: "< 12.0.0"
+  - name: bitnami
+    effect: allow
+    repository: charts.bitnami.com/*
+  - name: ghcr-new
+    effect: allow
+    repository: oci://ghcr.io/org/*
+    versions: ">= 2.0.0"
+`))
+	require.NoError(t, err)
+
+	const bitnami = "https://charts.bitnami.com/bitnami"
+	tests := []struct {
+		name     string
+		target   policyTarget
+		wantRule string // empty means allowed
+	}{
+		{"allowed by glob", policyTarget{repository: bitnami, chart: "nginx", version: "1.0.0"}, ""},
+		{"trailing slash ignored", policyTarget{repository: bitnami + "/", chart: "nginx", version: "1.0.0"}, ""},
+		{"deny rule wins when listed first", policyTarget{repository: bitnami, chart: "wordpress", version: "1.0.0"}, "no-wordpress"},
+		{"deny rule without version", policyTarget{repository: bitnami, chart: "wordpress"}, "no-wordpress"},
+		{"version range denies old version", policyTarget{repository: bitnami, chart: "postgresql", version: "11.9.0"}, "no-old-postgres"},
+		{"version range allows new version", policyTarget{repository: bitnami, chart: "postgresql", version: "12.1.0"}, ""},
+		{"version range denies old prerelease", policyTarget{repository: bitnami, chart: "postgresql", version: "11.9.0-rc.1"}, "no-old-postgres"},
+		{"version range allows new prerelease", policyTarget{repository: bitnami, chart: "postgresql", version: "12.1.0-rc.1"}, ""},
+		{"allow range excludes prerelease", policyTarget{repository: "oci://ghcr.io/org/charts", chart: "app", version: "3.0.0-rc.1"}, policyRuleDefault},
+		{"deny range ignored without version", policyTarget{repository: bitnami, chart: "postgresql"}, ""},
+		{"deprecated denied", policyTarget{repository: bitnami, chart: "nginx", version: "1.0.0", deprecated: true}, policyRuleDenyDeprecated},
+		{"no rule matches", policyTarget{repository: "https://example.com/charts", chart: "nginx", version: "1.0.0"}, policyRuleDefault},
+		{"scheme in pattern must match", policyTarget{repository: "https://ghcr.io/org/charts", chart: "app", version: "3.0.0"}, policyRuleDefault},
+		{"allow range matches", policyTarget{repository: "oci://ghcr.io/org/charts", chart: "app", version: "3.0.0"}, ""},
+		{"allow range excludes", policyTarget{repository: "oci://ghcr.io/org/charts", chart: "app", version: "1.0.0"}, policyRuleDefault},
+		{"allow range matches without version", policyTarget{repository: "oci://ghcr.io/org/charts", chart: "app"}, ""},
+		{"deny range matches non-semver version", policyTarget{repository: bitnami, chart: "postgresql", version: "v1.0-custom"}, "no-old-postgres"},
+		{"non-semver version skips ranged allow rules", policyTarget{repository: "oci://ghcr.io/org/charts", chart: "app", version: "latest"}, policyRuleDefault},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.evaluate(tt.target)
+			if tt.wantRule == "" {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Equal(t, tt.wantRule, err.Rule)
+		})
+	}
+
+	t.Run("nil policy allows everything", func(t *testing.T) {
+		var nilPolicy *Policy
+		assert.Nil(t, nilPolicy.evaluate(policyTarget{chart: "anything"}))
+	})
+}
+
+func TestClient_Policy(t *testing.T) {
+	ctx := context.Background()
+	archive := buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n")
+
+	newClient := func(t *testing.T, policy string, opts ...Option) *Client {
+		p, err := ParsePolicy([]byte(policy))
+		require.NoError(t, err)
+		return NewClient(append([]Option{
+			WithTimeout(5 * time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+			WithPolicy(p),
+		}, opts...)...)
+	}
+
+	t.Run("denied chart is refused before download", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		client := newClient(t, "rules:\n  - name: no-app\n    effect: deny\n    chart: app\n")
+
+		_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.Error(t, err)
+		require.True(t, IsPolicyError(err), "expected PolicyError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), `"no-app"`)
+		assert.Equal(t, int64(0), repo.downloads.Load())
+
+		_, err = client.ListVersions(ctx, repo.URL, "app")
+		assert.True(t, IsPolicyError(err), "expected PolicyError, got %T: %v", err, err)
+	})
+
+	t.Run("allowed chart is served", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		client := newClient(t, "default: deny\nrules:\n  - name: local\n    effect: allow\n    repository: 127.0.0.1*\n")
+
+		values, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, "replicaCount: 1\n", string(values))
+	})
+
+	t.Run("deprecated chart is refused before download", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		repo.deprecated = true
+		client := newClient(t, "denyDeprecated: true\n")
+
+		_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.Error(t, err)
+		require.True(t, IsPolicyError(err), "expected PolicyError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), policyRuleDenyDeprecated)
+		assert.Equal(t, int64(0), repo.downloads.Load())
+	})
+
+	t.Run("require signed without verification configured", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		client := newClient(t, "requireSigned: true\n")
+
+		_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.Error(t, err)
+		require.True(t, IsPolicyError(err), "expected PolicyError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), "signature verification is not configured")
+		assert.Equal(t, int64(0), repo.downloads.Load())
+	})
+
+	t.Run("require signed refuses unsigned chart in annotate mode", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", archive)
+		signer := newTestSigner(t, "Chart Publisher")
+		client := newClient(t, "requireSigned: true\n",
+			WithProvenanceMode(VerifyAnnotate), WithProvenanceKeyring(signer.keyring))
+
+		_, err := client.GetValues(ctx, repo.URL, "app", "1.0.0")
+		require.Error(t, err)
+		require.True(t, IsPolicyError(err), "expected PolicyError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), "chart signature is unsigned")
+	})
+
+	t.Run("latest version skips denied versions", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = fmt.Fprint(w, `apiVersion: v1
+entries:
+  app:
+    - {name: app, version: 3.0.0, apiVersion: v2, urls: [app-3.0.0.tgz]}
+    - {name: app, version: 2.0.0, apiVersion: v2, deprecated: true, urls: [app-2.0.0.tgz]}
+    - {name: app, version: 1.0.0, apiVersion: v2, urls: [app-1.0.0.tgz]}
+`)
+		}))
+		t.Cleanup(server.Close)
+
+		client := newClient(t, "denyDeprecated: true\nrules:\n  - name: no-3\n    effect: deny\n    versions: \">= 3.0.0\"\n")
+		latest, err := client.GetLatestVersion(ctx, server.URL, "app")
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.0", latest)
+
+		client = newClient(t, "rules:\n  - name: none\n    effect: deny\n    versions: \">= 0.0.0\"\n")
+		_, err = client.GetLatestVersion(ctx, server.URL, "app")
+		require.Error(t, err)
+		require.True(t, IsPolicyError(err), "expected PolicyError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), `version "3.0.0"`)
+	})
+}
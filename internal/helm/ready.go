@@ -0,0 +1,95 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/registry"
+)
+
+// Ensure Client implements ReadinessChecker.
+var _ ReadinessChecker = (*Client)(nil)
+
+// CheckCacheDir reports whether the cache directory is writable, by creating
+// and removing a file in it.
+func (c *Client) CheckCacheDir() error {
+	f, err := os.CreateTemp(c.opts.cacheDir, ".ready-*")
+	if err != nil {
+		return fmt.Errorf("cache directory is not writable: %w", err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("cache directory is not writable: %w", err)
+	}
+	if c.opts.diskCacheBytes > 0 && c.disk == nil {
+		return errors.New("disk cache could not be created")
+	}
+	return nil
+}
+
+// CheckRegistry reports whether the OCI registry client was created.
+func (c *Client) CheckRegistry() error {
+	if c.registryClient == nil {
+		if c.registryErr != nil {
+			return fmt.Errorf("OCI registry client is not available: %w", c.registryErr)
+		}
+		return errors.New("OCI registry client is not available")
+	}
+	return nil
+}
+
+// CheckRepository reports whether a repository answers requests, without
+// downloading its index: HTTP repositories are sent a HEAD request for
+// index.yaml, and OCI registries a request for the /v2/ API root, which
+// needs no credentials to answer, over the scheme the registry is pulled with.
+func (c *Client) CheckRepository(ctx context.Context, repoURL string) error {
+	var probeURL string
+	if registry.IsOCI(repoURL) {
+		validatedURL, err := ValidateOCIURL(ctx, repoURL, c.validationOpts())
+		if err != nil {
+			return err
+		}
+		u, err := url.Parse(validatedURL)
+		if err != nil {
+			return &URLValidationError{URL: repoURL, Reason: "invalid URL format"}
+		}
+		scheme := "https"
+		if c.opts.plainHTTP {
+			scheme = "http"
+		}
+		probeURL = scheme + "://" + u.Host + "/v2/"
+	} else {
+		validatedURL, err := ValidateRepoURL(ctx, repoURL, c.validationOpts())
+		if err != nil {
+			return err
+		}
+		probeURL = strings.TrimSuffix(validatedURL, "/") + "/index.yaml"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, probeURL, nil)
+	if err != nil {
+		return &RepositoryError{URL: repoURL, Op: "check", Message: "failed to reach repository", Err: err}
+	}
+	req.Header.Set("User-Agent", indexUserAgent)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &RepositoryError{URL: repoURL, Op: "check", Message: "failed to reach repository", Err: err}
+	}
+	_ = resp.Body.Close()
+
+	// Registries answer 401 without credentials, and some servers do not
+	// implement HEAD; anything else from the 4xx and 5xx ranges means the
+	// repository cannot serve charts.
+	switch {
+	case resp.StatusCode < 400, resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusMethodNotAllowed:
+		return nil
+	default:
+		return &RepositoryError{URL: repoURL, Op: "check", Message: "failed to reach repository", Err: fmt.Errorf("unexpected status %s", resp.Status)}
+	}
+}
@@ -0,0 +1,113 @@
+package helm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClient_CheckCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	client := NewClient(WithCacheDir(dir), WithLogger(zap.NewNop()))
+	assert.NoError(t, client.CheckCacheDir())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Name(), ".ready-", "probe file left behind")
+	}
+
+	require.NoError(t, os.RemoveAll(dir))
+	assert.Error(t, client.CheckCacheDir())
+}
+
+func TestClient_CheckRegistry(t *testing.T) {
+	client := NewClient(WithCacheDir(t.TempDir()), WithLogger(zap.NewNop()))
+	assert.NoError(t, client.CheckRegistry())
+
+	client.registryClient = nil
+	assert.Error(t, client.CheckRegistry())
+}
+
+func TestClient_CheckRepository(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(
+		WithAllowPrivateIPs(true),
+		WithCacheDir(t.TempDir()),
+		WithLogger(zap.NewNop()),
+	)
+
+	t.Run("reachable repository", func(t *testing.T) {
+		var method string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+			assert.Equal(t, "/charts/index.yaml", r.URL.Path)
+		}))
+		t.Cleanup(srv.Close)
+
+		require.NoError(t, client.CheckRepository(ctx, srv.URL+"/charts"))
+		assert.Equal(t, http.MethodHead, method)
+	})
+
+	t.Run("HEAD not supported", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}))
+		t.Cleanup(srv.Close)
+
+		assert.NoError(t, client.CheckRepository(ctx, srv.URL))
+	})
+
+	t.Run("missing index", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		t.Cleanup(srv.Close)
+
+		err := client.CheckRepository(ctx, srv.URL)
+		require.Error(t, err)
+		assert.True(t, IsRepositoryError(err))
+	})
+
+	t.Run("unreachable repository", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+
+		err := client.CheckRepository(ctx, srv.URL)
+		require.Error(t, err)
+		assert.True(t, IsRepositoryError(err))
+	})
+
+	t.Run("plain HTTP registry", func(t *testing.T) {
+		var path string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		t.Cleanup(srv.Close)
+		registryURL := "oci://" + strings.TrimPrefix(srv.URL, "http://") + "/charts"
+
+		err := client.CheckRepository(ctx, registryURL)
+		assert.True(t, IsRepositoryError(err), "expected HTTPS probe to fail, got %v", err)
+
+		plain := NewClient(
+			WithAllowPrivateIPs(true),
+			WithPlainHTTP(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+		)
+		require.NoError(t, plain.CheckRepository(ctx, registryURL))
+		assert.Equal(t, "/v2/", path)
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		err := client.CheckRepository(ctx, "ftp://example.com")
+		require.Error(t, err)
+		assert.True(t, IsURLValidationError(err))
+	})
+}
@@ -0,0 +1,81 @@
+package helm
+
+import (
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// Approximate fixed costs of in-memory objects, used when estimating how much
+// memory a cached chart or index holds. The estimates only need to be good
+// enough to keep the caches within their byte budgets.
+const (
+	objectOverhead    = 64 // Struct, slice, map or string header
+	stringHeaderBytes = 16
+	sliceHeaderBytes  = 24
+	mapEntryBytes     = 48
+	indexVersionBytes = 4*stringHeaderBytes + 2*sliceHeaderBytes + 32 // indexVersion fields
+)
+
+// estimateChartBytes estimates the memory held by a loaded chart and its
+// subcharts.
+func estimateChartBytes(c *chartv2.Chart) int64 {
+	if c == nil {
+		return 0
+	}
+	n := int64(objectOverhead + len(c.Schema))
+	for _, files := range [][]*common.File{c.Raw, c.Templates, c.Files} {
+		for _, f := range files {
+			if f == nil {
+				continue
+			}
+			n += int64(objectOverhead + len(f.Name) + len(f.Data))
+			// Parsed values are a tree of maps of about the size of the
+			// YAML they came from.
+			if f.Name == "values.yaml" {
+				n += int64(len(f.Data))
+			}
+		}
+	}
+	n += estimateMetadataBytes(c.Metadata)
+	for _, dep := range c.Dependencies() {
+		n += estimateChartBytes(dep)
+	}
+	return n
+}
+
+// estimateIndexBytes estimates the memory held by a compact repository index.
+func estimateIndexBytes(index *chartIndex) int64 {
+	if index == nil {
+		return 0
+	}
+	return objectOverhead + index.size
+}
+
+// estimateMetadataBytes estimates the variable-length part of chart metadata.
+func estimateMetadataBytes(m *chartv2.Metadata) int64 {
+	if m == nil {
+		return 0
+	}
+	n := len(m.Name) + len(m.Home) + len(m.Version) + len(m.Description) + len(m.Icon) +
+		len(m.APIVersion) + len(m.Condition) + len(m.Tags) + len(m.AppVersion) + len(m.KubeVersion) + len(m.Type)
+	for _, s := range m.Sources {
+		n += objectOverhead + len(s)
+	}
+	for _, s := range m.Keywords {
+		n += objectOverhead + len(s)
+	}
+	for _, mt := range m.Maintainers {
+		if mt != nil {
+			n += objectOverhead + len(mt.Name) + len(mt.Email) + len(mt.URL)
+		}
+	}
+	for k, v := range m.Annotations {
+		n += objectOverhead + len(k) + len(v)
+	}
+	for _, d := range m.Dependencies {
+		if d != nil {
+			n += objectOverhead + len(d.Name) + len(d.Version) + len(d.Repository) + len(d.Condition) + len(d.Alias)
+		}
+	}
+	return int64(n)
+}
@@ -0,0 +1,43 @@
+package helm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+	repo "helm.sh/helm/v4/pkg/repo/v1"
+)
+
+func TestEstimateBytes(t *testing.T) {
+	t.Run("chart includes files and subcharts", func(t *testing.T) {
+		chart := &chartv2.Chart{
+			Metadata: &chartv2.Metadata{Name: "app"},
+			Raw:      []*common.File{{Name: "values.yaml", Data: make([]byte, 1000)}},
+		}
+		base := estimateChartBytes(chart)
+		assert.Greater(t, base, int64(2000), "values are counted as raw and parsed")
+
+		chart.AddDependency(&chartv2.Chart{
+			Metadata:  &chartv2.Metadata{Name: "sub"},
+			Templates: []*common.File{{Name: "templates/x.yaml", Data: make([]byte, 5000)}},
+		})
+		assert.Greater(t, estimateChartBytes(chart), base+5000)
+		assert.Equal(t, int64(0), estimateChartBytes(nil))
+	})
+
+	t.Run("index grows with entries", func(t *testing.T) {
+		small := newChartIndex(&repo.IndexFile{Entries: map[string]repo.ChartVersions{
+			"a": {{Metadata: &chartv2.Metadata{Name: "a", Description: "short"}}},
+		}})
+		large := newChartIndex(&repo.IndexFile{Entries: map[string]repo.ChartVersions{
+			"a": {
+				{Metadata: &chartv2.Metadata{Name: "a", Description: "short"}},
+				{Metadata: &chartv2.Metadata{Name: "a", Description: strings.Repeat("long ", 100)}, URLs: []string{"a-2.0.0.tgz"}},
+			},
+		}})
+		assert.Greater(t, estimateIndexBytes(large), estimateIndexBytes(small)+indexVersionBytes+500)
+		assert.Equal(t, int64(0), estimateIndexBytes(nil))
+	})
+}
@@ -39,6 +39,72 @@ type ChartService interface {
 	GetDependencies(ctx context.Context, repoURL, chart, version string) ([]Dependency, error)
 }
 
+// ChartVerifier reports signature verification results for charts.
+// It is optional for ChartService implementations: consumers should
+// type-assert for it and only annotate output when it is available.
+type ChartVerifier interface {
+	// VerifyChart returns the verification result for a chart version,
+	// or nil if no verification policy applies to the repository.
+	VerifyChart(ctx context.Context, repoURL, chart, version string) (*Verification, error)
+
+	// VerifyVersions returns verification results for several versions of a
+	// chart, keyed by version. Versions whose result would require downloading
+	// the chart archive are omitted.
+	VerifyVersions(ctx context.Context, repoURL, chart string, versions []string) (map[string]*Verification, error)
+}
+
+// IndexStatusReporter reports when repository indexes are served stale.
+// Like ChartVerifier, it is optional for ChartService implementations.
+type IndexStatusReporter interface {
+	// IndexStatus returns the status of the index cached for the repository
+	// if it is past its TTL, or nil if it is fresh, not cached, or the
+	// repository has no index (OCI registries).
+	IndexStatus(ctx context.Context, repoURL string) (*IndexStatus, error)
+}
+
+// IndexStatus describes a repository index that is served past its TTL.
+type IndexStatus struct {
+	FetchedAt    time.Time     // When the index was downloaded or last revalidated
+	Age          time.Duration // How long ago that was
+	Revalidating bool          // Whether a background refresh is in progress
+	RefreshError string        // Why the last refresh failed, if it did
+}
+
+// ReadinessChecker checks that a service can serve requests.
+// Like ChartVerifier, it is optional for ChartService implementations.
+type ReadinessChecker interface {
+	// CheckCacheDir returns an error if the cache directory is not writable.
+	CheckCacheDir() error
+
+	// CheckRegistry returns an error if OCI registries cannot be accessed.
+	CheckRegistry() error
+
+	// CheckRepository returns an error if the repository does not respond.
+	CheckRepository(ctx context.Context, repoURL string) error
+}
+
+// CacheAdmin inspects and manages a service's caches.
+// Like ChartVerifier, it is optional for ChartService implementations.
+type CacheAdmin interface {
+	// CacheStatus returns statistics and contents of the caches.
+	CacheStatus() *CacheStatus
+
+	// RefreshRepository drops the cached failures of a repository and
+	// refetches its index, returning the refreshed index, or nil for OCI
+	// registries, which have no index.
+	RefreshRepository(ctx context.Context, repoURL string) (*CachedIndex, error)
+}
+
+// CacheStatus describes the contents of a service's caches.
+type CacheStatus struct {
+	Indexes  CacheStats `json:"indexes"`
+	Charts   CacheStats `json:"charts"`
+	Failures CacheStats `json:"failures"`
+
+	IndexEntries []CachedIndex `json:"index_entries"` // Least recently used first
+	ChartEntries []CachedChart `json:"chart_entries"` // Least recently used first
+}
+
 // ChartVersion represents metadata about a chart version.
 type ChartVersion struct {
 	Version    string
@@ -3,9 +3,14 @@ package helm
 import (
 	"context"
 	"net"
+	"net/netip"
 	"net/url"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/traceutil"
 )
 
 // MaxURLLength is the maximum allowed length for URLs to prevent resource exhaustion.
@@ -16,6 +21,12 @@ type ValidationOptions struct {
 	AllowPrivateIPs bool
 	AllowedHosts    []string
 	DeniedHosts     []string
+	// AllowedCIDRs are permitted even if they fall in a blocked range.
+	AllowedCIDRs []netip.Prefix
+	// DeniedCIDRs are refused even if AllowPrivateIPs is set.
+	DeniedCIDRs []netip.Prefix
+
+	resolver ipResolver // nil uses net.DefaultResolver
 }
 
 // urlValidationFlags controls optional behavior in the shared URL validation helper.
@@ -27,7 +38,36 @@ type urlValidationFlags struct {
 
 // validateHost performs host-level security checks shared by all URL validators.
 // It checks localhost blocking, deny/allow lists, DNS resolution, and SSRF protections.
-func validateHost(ctx context.Context, rawURL, host string, opts ValidationOptions) error {
+func validateHost(ctx context.Context, rawURL, host string, opts ValidationOptions) (err error) {
+	ctx, span := traceutil.StartSpan(ctx, tracerName, "helm.ValidateURL", attribute.String("server.address", host))
+	defer func() { traceutil.EndSpan(span, err) }()
+
+	if err := checkHostname(rawURL, host, opts); err != nil {
+		return err
+	}
+
+	// DNS resolution check
+	addrs, err := resolveHost(ctx, opts.resolver, host)
+	if err != nil {
+		return &URLValidationError{URL: rawURL, Reason: "failed to resolve host: " + err.Error()}
+	}
+	if len(addrs) == 0 {
+		return &URLValidationError{URL: rawURL, Reason: "host resolved to no addresses"}
+	}
+
+	// SSRF protection: check for private and otherwise blocked IPs
+	for _, addr := range addrs {
+		if !opts.allowIP(addr) {
+			return &URLValidationError{URL: rawURL, Reason: "host resolves to a blocked IP address"}
+		}
+	}
+
+	return nil
+}
+
+// checkHostname applies the name-based host checks (localhost blocking and
+// deny/allow lists) without resolving the host.
+func checkHostname(rawURL, host string, opts ValidationOptions) error {
 	// Localhost and .local are never allowed
 	hostLower := strings.ToLower(host)
 	if hostLower == "localhost" || strings.HasSuffix(hostLower, ".local") {
@@ -44,24 +84,30 @@ func validateHost(ctx context.Context, rawURL, host string, opts ValidationOptio
 		return &URLValidationError{URL: rawURL, Reason: "host is not in allowlist"}
 	}
 
-	// DNS resolution check
-	addrs, err := resolveHost(ctx, host)
-	if err != nil {
-		return &URLValidationError{URL: rawURL, Reason: "failed to resolve host: " + err.Error()}
+	return nil
+}
+
+// checkRequestURL validates the URL of an outbound request, including each
+// redirect hop. IP-level checks happen when the connection is dialed, so no
+// DNS lookup is made here. If pinnedHost is set, the request must target it.
+func checkRequestURL(u *url.URL, opts ValidationOptions, pinnedHost string) error {
+	rawURL := u.Redacted()
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &URLValidationError{URL: rawURL, Reason: "scheme must be http or https"}
 	}
-	if len(addrs) == 0 {
-		return &URLValidationError{URL: rawURL, Reason: "host resolved to no addresses"}
+	if u.User != nil {
+		return &URLValidationError{URL: rawURL, Reason: "URL must not contain userinfo"}
 	}
-
-	// SSRF protection: check for private IPs
-	if !opts.AllowPrivateIPs {
-		for _, addr := range addrs {
-			if isPrivateIP(addr) {
-				return &URLValidationError{URL: rawURL, Reason: "host resolves to a private IP address"}
-			}
-		}
+	host := u.Hostname()
+	if host == "" {
+		return &URLValidationError{URL: rawURL, Reason: "URL must include a host"}
+	}
+	if err := checkHostname(rawURL, host, opts); err != nil {
+		return err
+	}
+	if pinnedHost != "" && !strings.EqualFold(host, pinnedHost) {
+		return &URLValidationError{URL: rawURL, Reason: "chart downloads are restricted to repository host " + pinnedHost}
 	}
-
 	return nil
 }
 
@@ -223,11 +269,19 @@ const dnsTimeout = 5 * time.Second
 
 // resolveHost resolves a hostname to IP addresses with a dedicated DNS timeout.
 // The timeout ensures an unresponsive DNS server cannot hang the request indefinitely.
-func resolveHost(ctx context.Context, host string) ([]net.IP, error) {
+// A nil resolver uses net.DefaultResolver.
+func resolveHost(ctx context.Context, r ipResolver, host string) (_ []net.IP, err error) {
+	ctx, span := traceutil.StartSpan(ctx, tracerName, "dns.lookup", attribute.String("server.address", host))
+	defer func() { traceutil.EndSpan(span, err) }()
+
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
 	dnsCtx, cancel := context.WithTimeout(ctx, dnsTimeout)
 	defer cancel()
 
-	addrs, err := net.DefaultResolver.LookupIPAddr(dnsCtx, host)
+	addrs, err := r.LookupIPAddr(dnsCtx, host)
 	if err != nil {
 		return nil, err
 	}
@@ -237,26 +291,3 @@ func resolveHost(ctx context.Context, host string) ([]net.IP, error) {
 	}
 	return ips, nil
 }
-
-// isPrivateIP returns true if the IP is private, loopback, link-local, or otherwise non-public.
-func isPrivateIP(ip net.IP) bool {
-	if ip == nil {
-		return true
-	}
-	if ip.IsLoopback() {
-		return true
-	}
-	if ip.IsPrivate() {
-		return true
-	}
-	if ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
-		return true
-	}
-	if ip.IsUnspecified() {
-		return true
-	}
-	if ip.IsMulticast() {
-		return true
-	}
-	return false
-}
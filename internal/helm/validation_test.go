@@ -187,7 +187,7 @@ func TestResolveHost_RespectsTimeout(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // cancel immediately
 
-	_, err := resolveHost(ctx, "example.com")
+	_, err := resolveHost(ctx, nil, "example.com")
 	if err == nil {
 		t.Fatal("expected error from resolveHost with cancelled context, got nil")
 	}
@@ -364,7 +364,7 @@ func TestValidateOCIURL_PreservesPort(t *testing.T) {
 	}
 }
 
-func TestIsPrivateIP(t *testing.T) {
+func TestIsBlockedIP(t *testing.T) {
 	tests := []struct {
 		name string
 		ip   string
@@ -382,6 +382,17 @@ func TestIsPrivateIP(t *testing.T) {
 		{"multicast v6", "ff02::1", true},
 		{"unspecified v4", "0.0.0.0", true},
 		{"unspecified v6", "::", true},
+		{"this network", "0.1.2.3", true},
+		{"carrier-grade NAT", "100.64.0.1", true},
+		{"benchmarking", "198.18.0.1", true},
+		{"broadcast", "255.255.255.255", true},
+		{"IPv4-mapped private", "::ffff:10.0.0.1", true},
+		{"IPv4-mapped loopback", "::ffff:127.0.0.1", true},
+		{"NAT64", "64:ff9b::a9fe:a9fe", true},
+		{"6to4", "2002:a00:1::1", true},
+		{"unique local", "fd12:3456::1", true},
+		{"AWS metadata v6", "fd00:ec2::254", true},
+		{"IPv4-mapped public", "::ffff:8.8.8.8", false},
 		{"public v4", "8.8.8.8", false},
 		{"public v6", "2001:4860:4860::8888", false},
 		{"cloudflare dns", "1.1.1.1", false},
@@ -393,9 +404,9 @@ func TestIsPrivateIP(t *testing.T) {
 			if tt.ip != "" {
 				ip = net.ParseIP(tt.ip)
 			}
-			got := isPrivateIP(ip)
+			got := isBlockedIP(ip)
 			if got != tt.want {
-				t.Errorf("isPrivateIP(%q) = %v, want %v", tt.ip, got, tt.want)
+				t.Errorf("isBlockedIP(%q) = %v, want %v", tt.ip, got, tt.want)
 			}
 		})
 	}
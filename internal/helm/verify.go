@@ -0,0 +1,152 @@
+package helm
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/downloader"
+	"helm.sh/helm/v4/pkg/provenance"
+)
+
+// VerificationMode controls how chart signatures are treated.
+type VerificationMode string
+
+const (
+	// VerifyOff disables signature verification.
+	VerifyOff VerificationMode = "off"
+	// VerifyAnnotate verifies signatures when available and reports the result
+	// alongside tool output, but still serves unsigned or invalid charts.
+	VerifyAnnotate VerificationMode = "annotate"
+	// VerifyEnforce refuses to serve charts that are unsigned or fail verification.
+	VerifyEnforce VerificationMode = "enforce"
+)
+
+// ParseVerificationMode converts a string to a VerificationMode.
+// An empty string is treated as VerifyOff.
+func ParseVerificationMode(s string) (VerificationMode, error) {
+	switch m := VerificationMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return VerifyOff, nil
+	case VerifyOff, VerifyAnnotate, VerifyEnforce:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid verification mode %q: must be off, annotate, or enforce", s)
+	}
+}
+
+// VerificationStatus is the outcome of a chart signature check.
+type VerificationStatus string
+
+const (
+	// StatusVerified means the chart signature was valid and trusted.
+	StatusVerified VerificationStatus = "verified"
+	// StatusUnsigned means no signature was published for the chart.
+	StatusUnsigned VerificationStatus = "unsigned"
+	// StatusInvalid means a signature was found but could not be verified.
+	StatusInvalid VerificationStatus = "invalid"
+)
+
+// Verification methods reported in Verification.Method.
+const (
+	MethodProvenance = "provenance"
+)
+
+// Verification describes the signature verification result for a chart version.
+type Verification struct {
+	Method  string             // Verification method, e.g. "provenance"
+	Status  VerificationStatus // Outcome of the check
+	Signer  string             // Signer identity (empty unless verified)
+	Digest  string             // Verified archive digest, e.g. "sha256:..."
+	Message string             // Reason for an unsigned or invalid result
+}
+
+// Verified reports whether the chart signature was successfully verified.
+func (v *Verification) Verified() bool {
+	return v != nil && v.Status == StatusVerified
+}
+
+// verificationPolicy decides whether signature verification applies to a repository.
+type verificationPolicy struct {
+	mode      VerificationMode
+	hosts     []string // Hosts to verify; empty means all hosts
+	skipHosts []string // Hosts exempt from verification
+}
+
+// appliesTo reports whether the policy requires verification for the given repository URL.
+func (p verificationPolicy) appliesTo(repoURL string) bool {
+	if p.mode == "" || p.mode == VerifyOff {
+		return false
+	}
+	host := repoHost(repoURL)
+	if matchesHostList(host, p.skipHosts) {
+		return false
+	}
+	if len(p.hosts) > 0 && !matchesHostList(host, p.hosts) {
+		return false
+	}
+	return true
+}
+
+// repoHost extracts the lowercase hostname from an HTTP(S) or OCI repository URL.
+func repoHost(repoURL string) string {
+	if rest, ok := strings.CutPrefix(repoURL, "oci://"); ok {
+		repoURL = "https://" + rest
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// verifyProvenance checks a downloaded chart archive against its .prov file
+// using the given PGP keyring. A missing .prov file yields StatusUnsigned.
+func verifyProvenance(chartPath, keyring string) *Verification {
+	provPath := chartPath + ".prov"
+	if _, err := os.Stat(provPath); err != nil {
+		return &Verification{
+			Method:  MethodProvenance,
+			Status:  StatusUnsigned,
+			Message: "no provenance file published for chart",
+		}
+	}
+
+	ver, err := downloader.VerifyChart(chartPath, provPath, keyring)
+	if err != nil {
+		return &Verification{
+			Method:  MethodProvenance,
+			Status:  StatusInvalid,
+			Message: err.Error(),
+		}
+	}
+
+	return &Verification{
+		Method: MethodProvenance,
+		Status: StatusVerified,
+		Signer: signerIdentity(ver),
+		Digest: ver.FileHash,
+	}
+}
+
+// signerIdentity returns a stable, human-readable identity for the PGP entity
+// that signed a chart.
+func signerIdentity(ver *provenance.Verification) string {
+	if ver == nil || ver.SignedBy == nil {
+		return ""
+	}
+	names := make([]string, 0, len(ver.SignedBy.Identities))
+	for name := range ver.SignedBy.Identities {
+		names = append(names, name)
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		return names[0]
+	}
+	if ver.SignedBy.PrimaryKey != nil {
+		return ver.SignedBy.PrimaryKey.KeyIdString()
+	}
+	return ""
+}
@@ -0,0 +1,190 @@
+package helm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"helm.sh/helm/v4/pkg/provenance"
+)
+
+func TestParseVerificationMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    VerificationMode
+		wantErr bool
+	}{
+		{"", VerifyOff, false},
+		{"off", VerifyOff, false},
+		{"annotate", VerifyAnnotate, false},
+		{"ENFORCE", VerifyEnforce, false},
+		{" enforce ", VerifyEnforce, false},
+		{"strict", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseVerificationMode(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVerificationPolicy_AppliesTo(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy verificationPolicy
+		url    string
+		want   bool
+	}{
+		{"off", verificationPolicy{mode: VerifyOff}, "https://charts.example.com", false},
+		{"zero value", verificationPolicy{}, "https://charts.example.com", false},
+		{"all hosts", verificationPolicy{mode: VerifyEnforce}, "https://charts.example.com", true},
+		{"host included", verificationPolicy{mode: VerifyEnforce, hosts: []string{"example.com"}}, "https://charts.example.com", true},
+		{"host not included", verificationPolicy{mode: VerifyEnforce, hosts: []string{"example.org"}}, "https://charts.example.com", false},
+		{"host skipped", verificationPolicy{mode: VerifyAnnotate, skipHosts: []string{"charts.example.com"}}, "https://charts.example.com", false},
+		{"skip wins over include", verificationPolicy{mode: VerifyEnforce, hosts: []string{"*"}, skipHosts: []string{".example.com"}}, "https://charts.example.com", false},
+		{"OCI URL", verificationPolicy{mode: VerifyEnforce, hosts: []string{"ghcr.io"}}, "oci://ghcr.io/org/charts", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.appliesTo(tt.url))
+		})
+	}
+}
+
+// testSigner holds a generated PGP key and a public keyring file containing it.
+type testSigner struct {
+	entity  *openpgp.Entity
+	keyring string
+}
+
+func newTestSigner(t *testing.T, name string) *testSigner {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+	require.NoError(t, err)
+
+	keyring := filepath.Join(t.TempDir(), "pubring.gpg")
+	f, err := os.Create(keyring)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(f))
+	require.NoError(t, f.Close())
+
+	return &testSigner{entity: entity, keyring: keyring}
+}
+
+// sign produces a Helm provenance file for the given chart archive.
+func (s *testSigner) sign(t *testing.T, archive []byte, filename string) []byte {
+	t.Helper()
+
+	sig := &provenance.Signatory{Entity: s.entity}
+	prov, err := sig.ClearSign(archive, filename, []byte("name: signed\nversion: 1.0.0\n"))
+	require.NoError(t, err)
+	return []byte(prov)
+}
+
+func TestClient_Provenance(t *testing.T) {
+	ctx := context.Background()
+	archive := buildChartArchive(t, "signed", "1.0.0", "replicaCount: 1\n")
+	signer := newTestSigner(t, "Chart Publisher")
+
+	newClient := func(t *testing.T, mode VerificationMode, keyring string, opts ...Option) *Client {
+		return NewClient(append([]Option{
+			WithTimeout(5 * time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+			WithProvenanceMode(mode),
+			WithProvenanceKeyring(keyring),
+		}, opts...)...)
+	}
+
+	t.Run("enforce serves verified chart and reports signer", func(t *testing.T) {
+		repo := newChartRepo(t, "signed", "1.0.0", archive)
+		repo.prov = signer.sign(t, archive, repo.archiveName())
+		client := newClient(t, VerifyEnforce, signer.keyring)
+
+		values, err := client.GetValues(ctx, repo.URL, "signed", "1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, "replicaCount: 1\n", string(values))
+
+		v, err := client.VerifyChart(ctx, repo.URL, "signed", "1.0.0")
+		require.NoError(t, err)
+		require.NotNil(t, v)
+		assert.Equal(t, StatusVerified, v.Status)
+		assert.Equal(t, MethodProvenance, v.Method)
+		assert.Contains(t, v.Signer, "Chart Publisher")
+		assert.Contains(t, v.Digest, "sha256:")
+		assert.Equal(t, int64(1), repo.downloads.Load(), "VerifyChart should reuse the cached result")
+	})
+
+	t.Run("enforce refuses unsigned chart", func(t *testing.T) {
+		repo := newChartRepo(t, "signed", "1.0.0", archive)
+		client := newClient(t, VerifyEnforce, signer.keyring)
+
+		_, err := client.GetValues(ctx, repo.URL, "signed", "1.0.0")
+		require.Error(t, err)
+		require.True(t, IsVerificationError(err), "expected VerificationError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), string(StatusUnsigned))
+	})
+
+	t.Run("enforce refuses chart signed by untrusted key", func(t *testing.T) {
+		repo := newChartRepo(t, "signed", "1.0.0", archive)
+		repo.prov = newTestSigner(t, "Someone Else").sign(t, archive, repo.archiveName())
+		client := newClient(t, VerifyEnforce, signer.keyring)
+
+		_, err := client.GetValues(ctx, repo.URL, "signed", "1.0.0")
+		require.Error(t, err)
+		require.True(t, IsVerificationError(err), "expected VerificationError, got %T: %v", err, err)
+		assert.Contains(t, err.Error(), string(StatusInvalid))
+	})
+
+	t.Run("annotate serves unsigned chart with status", func(t *testing.T) {
+		repo := newChartRepo(t, "signed", "1.0.0", archive)
+		client := newClient(t, VerifyAnnotate, signer.keyring)
+
+		_, err := client.GetValues(ctx, repo.URL, "signed", "1.0.0")
+		require.NoError(t, err)
+
+		v, err := client.VerifyChart(ctx, repo.URL, "signed", "1.0.0")
+		require.NoError(t, err)
+		require.NotNil(t, v)
+		assert.Equal(t, StatusUnsigned, v.Status)
+		assert.Empty(t, v.Signer)
+	})
+
+	t.Run("skipped host is not verified", func(t *testing.T) {
+		repo := newChartRepo(t, "signed", "1.0.0", archive)
+		client := newClient(t, VerifyEnforce, signer.keyring, WithProvenanceSkipHosts([]string{"127.0.0.1"}))
+
+		_, err := client.GetValues(ctx, repo.URL, "signed", "1.0.0")
+		require.NoError(t, err)
+
+		v, err := client.VerifyChart(ctx, repo.URL, "signed", "1.0.0")
+		require.NoError(t, err)
+		assert.Nil(t, v)
+	})
+
+	t.Run("off does not verify", func(t *testing.T) {
+		repo := newChartRepo(t, "signed", "1.0.0", archive)
+		client := newClient(t, VerifyOff, "")
+
+		v, err := client.VerifyChart(ctx, repo.URL, "signed", "1.0.0")
+		require.NoError(t, err)
+		assert.Nil(t, v)
+		assert.Equal(t, int64(0), repo.downloads.Load())
+	})
+}
@@ -0,0 +1,259 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"go.uber.org/zap"
+	"helm.sh/helm/v4/pkg/registry"
+)
+
+// WarmList names the repositories and charts to load into the caches ahead
+// of use. A warm list file looks like:
+//
+//	repositories:
+//	  - url: https://charts.bitnami.com/bitnami
+//	    charts: [nginx, redis]
+//	  - url: oci://ghcr.io/traefik/helm
+//	    charts: [traefik]
+type WarmList struct {
+	Repositories []WarmRepository `yaml:"repositories"`
+}
+
+// WarmRepository is a repository whose index, and the latest versions of the
+// listed charts, are warmed. OCI registries have no index, so only their
+// charts are warmed.
+type WarmRepository struct {
+	URL    string   `yaml:"url"`
+	Charts []string `yaml:"charts"`
+}
+
+// LoadWarmList reads a warm list file.
+func LoadWarmList(path string) (*WarmList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading warm list: %w", err)
+	}
+	l, err := ParseWarmList(data)
+	if err != nil {
+		return nil, fmt.Errorf("warm list %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// ParseWarmList parses a YAML warm list. Unknown fields are rejected.
+func ParseWarmList(data []byte) (*WarmList, error) {
+	var l WarmList
+	if err := yaml.UnmarshalWithOptions(data, &l, yaml.Strict()); err != nil {
+		return nil, err
+	}
+	for i, r := range l.Repositories {
+		if r.URL == "" {
+			return nil, fmt.Errorf("repository %d: url is required", i+1)
+		}
+		if registry.IsOCI(r.URL) && len(r.Charts) == 0 {
+			return nil, fmt.Errorf("repository %s: OCI registries need charts to warm", r.URL)
+		}
+		for _, chart := range r.Charts {
+			if chart == "" {
+				return nil, fmt.Errorf("repository %s: empty chart name", r.URL)
+			}
+		}
+	}
+	return &l, nil
+}
+
+// WarmStatus reports the progress of cache warming.
+type WarmStatus struct {
+	Ready      bool      `json:"ready"`   // The first round has finished
+	Running    bool      `json:"running"` // A round is in progress
+	Rounds     int       `json:"rounds"`  // Rounds finished
+	Total      int       `json:"total"`   // Targets in the current or last round
+	Done       int       `json:"done"`    // Targets finished, including failures
+	Failed     int       `json:"failed"`  // Targets that failed
+	LastError  string    `json:"last_error,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+}
+
+// Warmer pre-loads repository indexes and the latest versions of charts into
+// a client's caches, at startup and then periodically.
+type Warmer struct {
+	client      *Client
+	list        *WarmList
+	versions    int
+	concurrency int
+	interval    time.Duration
+	logger      *zap.Logger
+
+	mu     sync.Mutex
+	status WarmStatus
+}
+
+// warmTarget is a unit of warming work: a repository index, or the latest
+// versions of a chart.
+type warmTarget struct {
+	repository string
+	chart      string // Empty to warm the index only
+}
+
+// NewWarmer creates a warmer that loads the latest versions of each listed
+// chart, running at most concurrency targets at once. A zero interval warms
+// once.
+func NewWarmer(client *Client, list *WarmList, versions, concurrency int, interval time.Duration) *Warmer {
+	return &Warmer{
+		client:      client,
+		list:        list,
+		versions:    max(versions, 1),
+		concurrency: max(concurrency, 1),
+		interval:    interval,
+		logger:      client.logger,
+	}
+}
+
+// Run warms the caches immediately and then every interval until ctx is done.
+func (w *Warmer) Run(ctx context.Context) {
+	w.warm(ctx)
+	if w.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.warm(ctx)
+		}
+	}
+}
+
+// WarmStatus returns the progress of warming.
+func (w *Warmer) WarmStatus() WarmStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// warm runs one round over all targets.
+func (w *Warmer) warm(ctx context.Context) {
+	targets := w.targets()
+	w.mu.Lock()
+	w.status.Running = true
+	w.status.Total = len(targets)
+	w.status.Done = 0
+	w.status.Failed = 0
+	w.status.LastError = ""
+	w.status.StartedAt = time.Now()
+	w.mu.Unlock()
+
+	w.logger.Info("warming caches", zap.Int("targets", len(targets)))
+
+	sem := make(chan struct{}, w.concurrency)
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			w.finish(t, w.warmTarget(ctx, t))
+		}()
+	}
+	wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.Running = false
+	w.status.FinishedAt = time.Now()
+	if ctx.Err() != nil {
+		return
+	}
+	w.status.Ready = true
+	w.status.Rounds++
+	w.logger.Info("warmed caches",
+		zap.Int("targets", w.status.Total),
+		zap.Int("failed", w.status.Failed),
+		zap.Duration("duration", w.status.FinishedAt.Sub(w.status.StartedAt)),
+	)
+}
+
+// targets lists the work of one round. Indexes of repositories with charts
+// are warmed along with the charts.
+func (w *Warmer) targets() []warmTarget {
+	var targets []warmTarget
+	for _, r := range w.list.Repositories {
+		if len(r.Charts) == 0 {
+			targets = append(targets, warmTarget{repository: r.URL})
+		}
+		for _, chart := range r.Charts {
+			targets = append(targets, warmTarget{repository: r.URL, chart: chart})
+		}
+	}
+	return targets
+}
+
+// warmTarget loads a repository index, or the latest versions of a chart.
+func (w *Warmer) warmTarget(ctx context.Context, t warmTarget) error {
+	if t.chart == "" {
+		_, err := w.client.getIndex(ctx, t.repository, false)
+		return err
+	}
+
+	validatedURL, err := w.client.validateRepository(ctx, t.repository)
+	if err != nil {
+		return err
+	}
+	versions, err := w.client.ListVersions(ctx, t.repository, t.chart)
+	if err != nil {
+		return err
+	}
+	// Versions are sorted newest first; those the policy refuses are skipped
+	var errs []error
+	warmed := 0
+	for _, v := range versions {
+		if warmed == w.versions {
+			break
+		}
+		target := policyTarget{repository: validatedURL, chart: t.chart, version: v.Version, deprecated: v.Deprecated}
+		if w.client.checkPolicy(target) != nil {
+			continue
+		}
+		warmed++
+		if _, err := w.client.loadHelmChart(ctx, t.repository, t.chart, v.Version); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// finish records the outcome of a target.
+func (w *Warmer) finish(t warmTarget, err error) {
+	if err != nil {
+		w.logger.Warn("failed to warm cache",
+			zap.String("repository", t.repository),
+			zap.String("chart", t.chart),
+			zap.Error(err),
+		)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.Done++
+	if err != nil {
+		w.status.Failed++
+		w.status.LastError = err.Error()
+	}
+}
@@ -0,0 +1,153 @@
+package helm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseWarmList(t *testing.T) {
+	t.Run("valid list", func(t *testing.T) {
+		l, err := ParseWarmList([]byte(`repositories:
+  - url: https://charts.example.com
+    charts: [nginx, redis]
+  - url: https://other.example.com
+  - url: oci://ghcr.io/org/charts
+    charts: [app]
+`))
+		require.NoError(t, err)
+		require.Len(t, l.Repositories, 3)
+		assert.Equal(t, []string{"nginx", "redis"}, l.Repositories[0].Charts)
+		assert.Empty(t, l.Repositories[1].Charts)
+	})
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"unknown field", "repositories:\n  - url: https://a.example.com\n    chart: nginx\n", "unknown field"},
+		{"missing url", "repositories:\n  - charts: [nginx]\n", "url is required"},
+		{"OCI without charts", "repositories:\n  - url: oci://ghcr.io/org\n", "OCI registries need charts"},
+		{"empty chart", "repositories:\n  - url: https://a.example.com\n    charts: ['']\n", "empty chart name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWarmList([]byte(tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestWarmer(t *testing.T) {
+	newClient := func(t *testing.T, opts ...Option) *Client {
+		return NewClient(append([]Option{
+			WithTimeout(5 * time.Second),
+			WithAllowPrivateIPs(true),
+			WithCacheDir(t.TempDir()),
+			WithLogger(zap.NewNop()),
+		}, opts...)...)
+	}
+
+	t.Run("warms indexes and charts", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n"))
+		indexOnly := newChartRepo(t, "other", "2.0.0", nil)
+		client := newClient(t)
+
+		w := NewWarmer(client, &WarmList{Repositories: []WarmRepository{
+			{URL: repo.URL, Charts: []string{"app"}},
+			{URL: indexOnly.URL},
+		}}, 1, 2, 0)
+		assert.False(t, w.WarmStatus().Ready)
+		w.Run(context.Background())
+
+		status := w.WarmStatus()
+		assert.True(t, status.Ready)
+		assert.False(t, status.Running)
+		assert.Equal(t, 1, status.Rounds)
+		assert.Equal(t, 2, status.Total)
+		assert.Equal(t, 2, status.Done)
+		assert.Zero(t, status.Failed)
+		assert.EqualValues(t, 1, repo.downloads.Load())
+		assert.EqualValues(t, 1, indexOnly.indexHits.Load())
+
+		// Tools are served from the warmed caches
+		_, err := client.GetValues(context.Background(), repo.URL, "app", "1.0.0")
+		require.NoError(t, err)
+		_, err = client.ListCharts(context.Background(), indexOnly.URL)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, repo.downloads.Load())
+		assert.EqualValues(t, 1, repo.indexHits.Load())
+		assert.EqualValues(t, 1, indexOnly.indexHits.Load())
+	})
+
+	t.Run("records failures and still becomes ready", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", nil)
+		repo.fail = true
+		client := newClient(t)
+
+		w := NewWarmer(client, &WarmList{Repositories: []WarmRepository{
+			{URL: repo.URL, Charts: []string{"app", "missing"}},
+		}}, 1, 1, 0)
+		w.Run(context.Background())
+
+		status := w.WarmStatus()
+		assert.True(t, status.Ready)
+		assert.Equal(t, 2, status.Done)
+		assert.Equal(t, 2, status.Failed)
+		assert.NotEmpty(t, status.LastError)
+	})
+
+	t.Run("skips versions refused by policy", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n"))
+		policy, err := ParsePolicy([]byte("rules:\n  - effect: deny\n    chart: app\n    versions: '>= 1.0.0'\n"))
+		require.NoError(t, err)
+		client := newClient(t, WithPolicy(policy))
+
+		w := NewWarmer(client, &WarmList{Repositories: []WarmRepository{
+			{URL: repo.URL, Charts: []string{"app"}},
+		}}, 1, 1, 0)
+		w.Run(context.Background())
+
+		assert.Zero(t, w.WarmStatus().Failed)
+		assert.Zero(t, repo.downloads.Load())
+	})
+
+	t.Run("matches policy against the normalized repository URL", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", buildChartArchive(t, "app", "1.0.0", "replicaCount: 1\n"))
+		policy, err := ParsePolicy([]byte("rules:\n  - effect: deny\n    repository: " + repo.URL + "\n    versions: '>= 1.0.0'\n"))
+		require.NoError(t, err)
+		client := newClient(t, WithPolicy(policy))
+
+		w := NewWarmer(client, &WarmList{Repositories: []WarmRepository{
+			{URL: " " + repo.URL + "/ ", Charts: []string{"app"}},
+		}}, 1, 1, 0)
+		w.Run(context.Background())
+
+		assert.Zero(t, w.WarmStatus().Failed)
+		assert.Zero(t, repo.downloads.Load())
+	})
+
+	t.Run("warms periodically until cancelled", func(t *testing.T) {
+		repo := newChartRepo(t, "app", "1.0.0", nil)
+		client := newClient(t, WithIndexTTL(time.Millisecond))
+
+		w := NewWarmer(client, &WarmList{Repositories: []WarmRepository{{URL: repo.URL}}}, 1, 1, 10*time.Millisecond)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			w.Run(ctx)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool { return w.WarmStatus().Rounds >= 3 }, 5*time.Second, 5*time.Millisecond)
+		cancel()
+		<-done
+		assert.GreaterOrEqual(t, repo.indexHits.Load(), int64(3))
+	})
+}
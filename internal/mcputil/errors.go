@@ -46,7 +46,50 @@ func OpError(op, repo, chart, version string, err error) *OperationError {
 	}
 }
 
-// HandleError converts a Helm error to an MCP error result.
+// ErrorClassKey is the _meta key under which HandleError records the class of
+// a failed tool call.
+const ErrorClassKey = "mcp-helm/errorClass"
+
+// Error classes recorded by HandleError, and reported by ResultErrorClass.
+const (
+	ClassChartNotFound  = "chart_not_found"
+	ClassRepository     = "repository"
+	ClassInvalidURL     = "invalid_url"
+	ClassChartTooLarge  = "chart_too_large"
+	ClassIndexTooLarge  = "index_too_large"
+	ClassOutputTooLarge = "output_too_large"
+	ClassVerification   = "verification"
+	ClassPolicy         = "policy"
+	ClassBusy           = "busy"         // Timed out queueing for a download slot
+	ClassRateLimited    = "rate_limited" // Rejected before reaching the tool; see RateLimitedError
+	ClassOther          = "other"        // A service error of no known type
+	ClassToolError      = "tool_error"   // An error result not built by HandleError, e.g. invalid input
+)
+
+// RetryAfterKey is the _meta key under which a rate limited result records
+// how many seconds to wait before retrying.
+const RetryAfterKey = "mcp-helm/retryAfterSeconds"
+
+// errorClasses maps Helm error types to their class and message prefix, in
+// order of precedence.
+var errorClasses = []struct {
+	is     func(error) bool
+	class  string
+	prefix string
+}{
+	{helm.IsChartNotFound, ClassChartNotFound, "chart not found"},
+	{helm.IsRepositoryError, ClassRepository, "repository error"},
+	{helm.IsURLValidationError, ClassInvalidURL, "invalid URL"},
+	{func(err error) bool { return helm.IsChartTooLarge(err) || helm.IsArchiveLimitError(err) }, ClassChartTooLarge, "chart too large"},
+	{helm.IsIndexTooLarge, ClassIndexTooLarge, "index too large"},
+	{helm.IsOutputTooLarge, ClassOutputTooLarge, "output too large"},
+	{helm.IsVerificationError, ClassVerification, "chart verification failed"},
+	{helm.IsPolicyError, ClassPolicy, "chart denied by policy"},
+	{helm.IsFetchQueueTimeout, ClassBusy, "server busy, retry later"},
+}
+
+// HandleError converts a Helm error to an MCP error result, recording its
+// class under ErrorClassKey in the result's _meta.
 // Returns nil if err is nil, indicating success.
 func HandleError(err error) *mcp.CallToolResult {
 	if err == nil {
@@ -54,18 +97,39 @@ func HandleError(err error) *mcp.CallToolResult {
 	}
 
 	// Map specific error types to user-friendly messages
-	switch {
-	case helm.IsChartNotFound(err):
-		return TextError(fmt.Sprintf("chart not found: %v", err))
-	case helm.IsRepositoryError(err):
-		return TextError(fmt.Sprintf("repository error: %v", err))
-	case helm.IsURLValidationError(err):
-		return TextError(fmt.Sprintf("invalid URL: %v", err))
-	case helm.IsOutputTooLarge(err):
-		return TextError(fmt.Sprintf("output too large: %v", err))
-	default:
-		return TextError(err.Error())
+	for _, c := range errorClasses {
+		if c.is(err) {
+			return classifiedError(c.class, fmt.Sprintf("%s: %v", c.prefix, err))
+		}
+	}
+	return classifiedError(ClassOther, err.Error())
+}
+
+// ResultErrorClass returns the class of a tool result: empty for a
+// successful call, the class recorded by HandleError, or ClassToolError.
+func ResultErrorClass(res *mcp.CallToolResult) string {
+	if res == nil || !res.IsError {
+		return ""
 	}
+	if class, ok := res.Meta[ErrorClassKey].(string); ok {
+		return class
+	}
+	return ClassToolError
+}
+
+// classifiedError creates an MCP error result recording its class.
+func classifiedError(class, msg string) *mcp.CallToolResult {
+	res := TextError(msg)
+	res.Meta = mcp.Meta{ErrorClassKey: class}
+	return res
+}
+
+// RateLimitedError creates an MCP error result for a call rejected by a rate
+// limit, recording the seconds to wait before retrying under RetryAfterKey.
+func RateLimitedError(retryAfterSeconds int, msg string) *mcp.CallToolResult {
+	res := classifiedError(ClassRateLimited, msg)
+	res.Meta[RetryAfterKey] = retryAfterSeconds
+	return res
 }
 
 // HandleOpError wraps an error with operation context and returns an MCP error result.
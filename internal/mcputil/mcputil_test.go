@@ -4,6 +4,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -81,6 +82,90 @@ func TestHandleError(t *testing.T) {
 		require.Len(t, result.Content, 1)
 	})
 
+	t.Run("CachedFailureError", func(t *testing.T) {
+		err := &helm.CachedFailureError{
+			Err: &helm.ChartNotFoundError{Repository: "https://repo.com", Chart: "nginx"},
+		}
+
+		result := HandleError(err)
+
+		require.NotNil(t, result)
+		assert.True(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		text := result.Content[0].(*mcp.TextContent).Text
+		assert.Contains(t, text, "chart not found")
+		assert.Contains(t, text, "cached failure")
+	})
+
+	t.Run("IndexTooLargeError", func(t *testing.T) {
+		err := &helm.IndexTooLargeError{
+			Repository: "https://repo.com",
+			Size:       200000000,
+			Limit:      100000000,
+		}
+
+		result := HandleError(err)
+
+		require.NotNil(t, result)
+		assert.True(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "index too large")
+	})
+
+	t.Run("VerificationError", func(t *testing.T) {
+		err := &helm.VerificationError{
+			Repository: "https://repo.com",
+			Chart:      "nginx",
+			Version:    "1.0.0",
+			Verification: &helm.Verification{
+				Method: helm.MethodProvenance,
+				Status: helm.StatusUnsigned,
+			},
+		}
+
+		result := HandleError(err)
+
+		require.NotNil(t, result)
+		assert.True(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		text, ok := result.Content[0].(*mcp.TextContent)
+		require.True(t, ok)
+		assert.Contains(t, text.Text, "chart verification failed")
+	})
+
+	t.Run("PolicyError", func(t *testing.T) {
+		err := &helm.PolicyError{
+			Repository: "https://repo.com",
+			Chart:      "wordpress",
+			Rule:       "no-wordpress",
+			Reason:     "matched deny rule",
+		}
+
+		result := HandleError(err)
+
+		require.NotNil(t, result)
+		assert.True(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		text, ok := result.Content[0].(*mcp.TextContent)
+		require.True(t, ok)
+		assert.Contains(t, text.Text, "chart denied by policy")
+		assert.Contains(t, text.Text, `"no-wordpress"`)
+	})
+
+	t.Run("ArchiveLimitError", func(t *testing.T) {
+		err := &helm.ArchiveLimitError{Limit: "total size", Value: 200, Max: 100}
+
+		result := HandleError(err)
+
+		require.NotNil(t, result)
+		assert.True(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		text, ok := result.Content[0].(*mcp.TextContent)
+		require.True(t, ok)
+		assert.Contains(t, text.Text, "chart too large")
+		assert.Contains(t, text.Text, "total size 200 exceeds limit 100")
+	})
+
 	t.Run("generic error", func(t *testing.T) {
 		err := errors.New("something went wrong")
 
@@ -92,6 +177,30 @@ func TestHandleError(t *testing.T) {
 	})
 }
 
+func TestResultErrorClass(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *mcp.CallToolResult
+		want   string
+	}{
+		{"success", nil, ""},
+		{"non-error result", &mcp.CallToolResult{}, ""},
+		{"chart not found", HandleError(&helm.ChartNotFoundError{Chart: "nginx"}), ClassChartNotFound},
+		{"wrapped repository error", HandleOpError("get_values", "https://repo.com", "", "", &helm.RepositoryError{URL: "https://repo.com"}), ClassRepository},
+		{"archive limit", HandleError(&helm.ArchiveLimitError{}), ClassChartTooLarge},
+		{"policy", HandleError(&helm.PolicyError{}), ClassPolicy},
+		{"fetch queue timeout", HandleError(&helm.FetchQueueTimeoutError{Host: "charts.example.com"}), ClassBusy},
+		{"unknown error", HandleError(errors.New("boom")), ClassOther},
+		{"text error", TextError("depth must be >= 0"), ClassToolError},
+		{"rate limited", RateLimitedError(3, "rate limit exceeded"), ClassRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ResultErrorClass(tt.result))
+		})
+	}
+}
+
 func TestOperationError(t *testing.T) {
 	baseErr := errors.New("network timeout")
 
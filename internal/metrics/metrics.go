@@ -0,0 +1,302 @@
+// Package metrics exports Prometheus metrics for mcp-helm.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/mcputil"
+)
+
+// namespace prefixes all metric names.
+const namespace = "mcp_helm"
+
+// Result label values for successful calls and fetches, and for tool calls
+// that failed before reaching a tool (e.g. an unknown tool name).
+const (
+	resultOK       = "ok"
+	resultError    = "error"
+	resultProtocol = "protocol_error"
+)
+
+// otherHost is the host label of fetches from hosts not reported by name.
+const otherHost = "other"
+
+// CacheStatsSource reports the statistics of a service's caches.
+type CacheStatsSource interface {
+	CacheStatus() *helm.CacheStatus
+}
+
+// FetchQueueSource reports the downloads in progress and queued per host.
+type FetchQueueSource interface {
+	FetchQueue() []helm.HostFetches
+}
+
+// Metrics records tool calls and outbound fetches, and collects cache and
+// session gauges when scraped. Safe for concurrent use.
+type Metrics struct {
+	registry *prometheus.Registry
+	hosts    map[string]bool // Hosts reported by name in host labels
+
+	toolCalls     *prometheus.CounterVec
+	toolDuration  *prometheus.HistogramVec
+	fetchDuration *prometheus.HistogramVec
+	fetchBytes    *prometheus.CounterVec
+}
+
+// Ensure Metrics implements helm.FetchObserver.
+var _ helm.FetchObserver = (*Metrics)(nil)
+
+// Option configures Metrics.
+type Option func(*Metrics)
+
+// WithHosts sets the repository hosts whose fetches are labeled with their
+// name, given as host names or repository URLs. Fetches from other hosts are
+// labeled "other", so that clients naming arbitrary repositories cannot
+// create unbounded label values. Patterns such as "*" name no host, and a
+// leading "." is ignored.
+func WithHosts(hosts []string) Option {
+	return func(m *Metrics) {
+		for _, h := range hosts {
+			if host := hostName(h); host != "" && host != "*" {
+				m.hosts[host] = true
+			}
+		}
+	}
+}
+
+// hostName returns the lowercase host name of a host name or repository URL.
+func hostName(s string) string {
+	s = strings.TrimSpace(s)
+	if rest, ok := strings.CutPrefix(s, "oci://"); ok {
+		s = "https://" + rest
+	}
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return ""
+		}
+		s = u.Hostname()
+	}
+	return strings.ToLower(strings.TrimPrefix(s, "."))
+}
+
+// New creates metrics in a new registry, along with the standard Go runtime
+// and process collectors.
+func New(opts ...Option) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		hosts:    make(map[string]bool),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_calls_total",
+			Help:      "MCP tool calls by tool and result: ok, an error class, or protocol_error.",
+		}, []string{"tool", "result"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tool_call_duration_seconds",
+			Help:      "MCP tool call latency by tool.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"tool"}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "fetch_duration_seconds",
+			Help:      "Outbound request latency by repository host, operation and result.",
+			Buckets:   []float64{.025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"host", "operation", "result"}),
+		fetchBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fetch_bytes_total",
+			Help:      "Bytes downloaded from repositories and registries by host and operation.",
+		}, []string{"host", "operation"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.toolCalls,
+		m.toolDuration,
+		m.fetchDuration,
+		m.fetchBytes,
+	)
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// hostLabel returns the host label of a fetch from host.
+func (m *Metrics) hostLabel(host string) string {
+	if m.hosts[host] {
+		return host
+	}
+	return otherHost
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveFetch records an outbound request.
+func (m *Metrics) ObserveFetch(host string, op helm.FetchOp, duration time.Duration, bytes int64, err error) {
+	result := resultOK
+	if err != nil {
+		result = resultError
+	}
+	host = m.hostLabel(host)
+	m.fetchDuration.WithLabelValues(host, string(op), result).Observe(duration.Seconds())
+	if bytes > 0 {
+		m.fetchBytes.WithLabelValues(host, string(op)).Add(float64(bytes))
+	}
+}
+
+// Middleware returns MCP server middleware that records tool calls. Calls
+// rejected before reaching a tool are counted with an empty tool label, so
+// that clients cannot create label values for tools that do not exist.
+func (m *Metrics) Middleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method != "tools/call" {
+				return next(ctx, method, req)
+			}
+
+			start := time.Now()
+			res, err := next(ctx, method, req)
+
+			tool, result := "", resultProtocol
+			if err == nil {
+				if params, ok := req.GetParams().(*mcp.CallToolParamsRaw); ok {
+					tool = params.Name
+				}
+				toolResult, _ := res.(*mcp.CallToolResult)
+				result = mcputil.ResultErrorClass(toolResult)
+				if result == "" {
+					result = resultOK
+				}
+			}
+			m.toolCalls.WithLabelValues(tool, result).Inc()
+			m.toolDuration.WithLabelValues(tool).Observe(time.Since(start).Seconds())
+			return res, err
+		}
+	}
+}
+
+// RegisterCaches collects the statistics of src's caches when scraped.
+func (m *Metrics) RegisterCaches(src CacheStatsSource) {
+	m.registry.MustRegister(&cacheCollector{src: src})
+}
+
+// RegisterFetchQueue reports the downloads in progress and queued for each
+// host of src when scraped.
+func (m *Metrics) RegisterFetchQueue(src FetchQueueSource) {
+	m.registry.MustRegister(&fetchQueueCollector{src: src, hostLabel: m.hostLabel})
+}
+
+// RegisterSessions reports the number of active MCP sessions of s.
+func (m *Metrics) RegisterSessions(s *mcp.Server) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "Active MCP sessions.",
+	}, func() float64 {
+		n := 0
+		for range s.Sessions() {
+			n++
+		}
+		return float64(n)
+	}))
+}
+
+// cacheCollector exports helm.CacheStats for each cache.
+type cacheCollector struct {
+	src CacheStatsSource
+}
+
+var (
+	cacheHitsDesc = prometheus.NewDesc(namespace+"_cache_hits_total",
+		"Cache hits by cache: index, chart or failure.", []string{"cache"}, nil)
+	cacheMissesDesc = prometheus.NewDesc(namespace+"_cache_misses_total",
+		"Cache misses by cache.", []string{"cache"}, nil)
+	cacheEntriesDesc = prometheus.NewDesc(namespace+"_cache_entries",
+		"Entries held by each cache.", []string{"cache"}, nil)
+	cacheBytesDesc = prometheus.NewDesc(namespace+"_cache_bytes",
+		"Estimated memory held by each cache.", []string{"cache"}, nil)
+	indexNotModifiedDesc = prometheus.NewDesc(namespace+"_index_not_modified_total",
+		"Index refreshes answered with 304 Not Modified.", nil, nil)
+	indexBytesSavedDesc = prometheus.NewDesc(namespace+"_index_bytes_saved_total",
+		"Index bytes not downloaded thanks to 304 Not Modified responses.", nil, nil)
+)
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEntriesDesc
+	ch <- cacheBytesDesc
+	ch <- indexNotModifiedDesc
+	ch <- indexBytesSavedDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	status := c.src.CacheStatus()
+	for _, cache := range []struct {
+		name  string
+		stats helm.CacheStats
+	}{
+		{"index", status.Indexes},
+		{"chart", status.Charts},
+		{"failure", status.Failures},
+	} {
+		ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(cache.stats.Hits), cache.name)
+		ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(cache.stats.Misses), cache.name)
+		ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(cache.stats.Size), cache.name)
+		ch <- prometheus.MustNewConstMetric(cacheBytesDesc, prometheus.GaugeValue, float64(cache.stats.Bytes), cache.name)
+	}
+	ch <- prometheus.MustNewConstMetric(indexNotModifiedDesc, prometheus.CounterValue, float64(status.Indexes.NotModified))
+	ch <- prometheus.MustNewConstMetric(indexBytesSavedDesc, prometheus.CounterValue, float64(status.Indexes.BytesSaved))
+}
+
+// fetchQueueCollector exports the downloads of each host with downloads in
+// progress or queued, summing the hosts that share a label.
+type fetchQueueCollector struct {
+	src       FetchQueueSource
+	hostLabel func(string) string
+}
+
+var (
+	fetchesInFlightDesc = prometheus.NewDesc(namespace+"_fetches_in_flight",
+		"Downloads in progress by repository host.", []string{"host"}, nil)
+	fetchQueueDepthDesc = prometheus.NewDesc(namespace+"_fetch_queue_depth",
+		"Downloads waiting for a slot under the concurrent download limits, by repository host.", []string{"host"}, nil)
+)
+
+func (c *fetchQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- fetchesInFlightDesc
+	ch <- fetchQueueDepthDesc
+}
+
+func (c *fetchQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	labels := make(map[string]*helm.HostFetches)
+	for _, h := range c.src.FetchQueue() {
+		label := c.hostLabel(h.Host)
+		sum, ok := labels[label]
+		if !ok {
+			sum = &helm.HostFetches{Host: label}
+			labels[label] = sum
+		}
+		sum.Active += h.Active
+		sum.Waiting += h.Waiting
+	}
+	for _, h := range labels {
+		ch <- prometheus.MustNewConstMetric(fetchesInFlightDesc, prometheus.GaugeValue, float64(h.Active), h.Host)
+		ch <- prometheus.MustNewConstMetric(fetchQueueDepthDesc, prometheus.GaugeValue, float64(h.Waiting), h.Host)
+	}
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm/mocks"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/mcputil"
+)
+
+type echoInput struct {
+	Fail bool `json:"fail,omitempty"`
+}
+
+type echoOutput struct {
+	OK bool `json:"ok"`
+}
+
+// connect runs a server with one tool and the metrics middleware, and
+// returns a connected client session.
+func connect(t *testing.T, m *Metrics) (*mcp.Server, *mcp.ClientSession) {
+	t.Helper()
+	ctx := context.Background()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddReceivingMiddleware(m.Middleware())
+	mcputil.RegisterTool(server, mcputil.ToolDef{Name: "echo"},
+		func(_ context.Context, _ *mcp.CallToolRequest, in echoInput) (*mcp.CallToolResult, echoOutput, error) {
+			if in.Fail {
+				return mcputil.HandleError(&helm.ChartNotFoundError{Chart: "nginx"}), echoOutput{}, nil
+			}
+			return nil, echoOutput{OK: true}, nil
+		})
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = serverSession.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = session.Close() })
+	return server, session
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	server, session := connect(t, m)
+	m.RegisterSessions(server)
+
+	_, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{"fail": true}})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "no-such-tool"})
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.toolCalls.WithLabelValues("echo", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.toolCalls.WithLabelValues("echo", mcputil.ClassChartNotFound)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.toolCalls.WithLabelValues("", "protocol_error")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.toolDuration))
+
+	// Unknown tool names do not become label values
+	assert.NotContains(t, scrape(t, m), "no-such-tool")
+	assert.Contains(t, scrape(t, m), "mcp_helm_active_sessions 1")
+}
+
+func TestObserveFetch(t *testing.T) {
+	m := New(WithHosts([]string{"charts.example.com", "oci://ghcr.io/org/charts"}))
+	m.ObserveFetch("charts.example.com", helm.FetchIndex, 100*time.Millisecond, 2048, nil)
+	m.ObserveFetch("charts.example.com", helm.FetchIndex, time.Second, 0, errors.New("timeout"))
+	m.ObserveFetch("ghcr.io", helm.FetchOCIPull, time.Second, 512, nil)
+
+	assert.Equal(t, 2048.0, testutil.ToFloat64(m.fetchBytes.WithLabelValues("charts.example.com", "index")))
+	assert.Equal(t, 512.0, testutil.ToFloat64(m.fetchBytes.WithLabelValues("ghcr.io", "oci_pull")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.fetchDuration))
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `mcp_helm_fetch_duration_seconds_count{host="charts.example.com",operation="index",result="error"} 1`)
+
+	// Hosts not configured share one label value
+	m.ObserveFetch("a.attacker.example", helm.FetchIndex, time.Second, 100, nil)
+	m.ObserveFetch("b.attacker.example", helm.FetchIndex, time.Second, 100, nil)
+	assert.Equal(t, 200.0, testutil.ToFloat64(m.fetchBytes.WithLabelValues("other", "index")))
+	assert.NotContains(t, scrape(t, m), "attacker")
+}
+
+func TestWithHosts(t *testing.T) {
+	m := New(WithHosts([]string{"*", ".example.com", "Charts.Example.org", "https://repo.example.net:8443/charts", "oci://GHCR.io/org"}))
+	assert.Equal(t, map[string]bool{
+		"example.com":        true,
+		"charts.example.org": true,
+		"repo.example.net":   true,
+		"ghcr.io":            true,
+	}, m.hosts)
+}
+
+func TestRegisterCaches(t *testing.T) {
+	admin := new(mocks.CacheAdmin)
+	admin.On("CacheStatus").Return(&helm.CacheStatus{
+		Indexes: helm.CacheStats{Hits: 3, Misses: 1, Size: 2, Bytes: 4096, NotModified: 5},
+		Charts:  helm.CacheStats{Hits: 7, Size: 1},
+	})
+
+	m := New()
+	m.RegisterCaches(admin)
+
+	expected := `
+# HELP mcp_helm_cache_hits_total Cache hits by cache: index, chart or failure.
+# TYPE mcp_helm_cache_hits_total counter
+mcp_helm_cache_hits_total{cache="chart"} 7
+mcp_helm_cache_hits_total{cache="failure"} 0
+mcp_helm_cache_hits_total{cache="index"} 3
+# HELP mcp_helm_index_not_modified_total Index refreshes answered with 304 Not Modified.
+# TYPE mcp_helm_index_not_modified_total counter
+mcp_helm_index_not_modified_total 5
+`
+	require.NoError(t, testutil.GatherAndCompare(m.registry, strings.NewReader(expected),
+		"mcp_helm_cache_hits_total", "mcp_helm_index_not_modified_total"))
+	assert.Contains(t, scrape(t, m), `mcp_helm_cache_bytes{cache="index"} 4096`)
+}
+
+// fetchQueue is a FetchQueueSource reporting fixed downloads.
+type fetchQueue []helm.HostFetches
+
+func (q fetchQueue) FetchQueue() []helm.HostFetches { return q }
+
+func TestRegisterFetchQueue(t *testing.T) {
+	m := New(WithHosts([]string{"charts.example.com"}))
+	m.RegisterFetchQueue(fetchQueue{
+		{Host: "charts.example.com", Active: 8, Waiting: 3},
+		{Host: "ghcr.io", Active: 1},
+		{Host: "quay.io", Active: 2, Waiting: 1},
+	})
+
+	expected := `
+# HELP mcp_helm_fetch_queue_depth Downloads waiting for a slot under the concurrent download limits, by repository host.
+# TYPE mcp_helm_fetch_queue_depth gauge
+mcp_helm_fetch_queue_depth{host="charts.example.com"} 3
+mcp_helm_fetch_queue_depth{host="other"} 1
+# HELP mcp_helm_fetches_in_flight Downloads in progress by repository host.
+# TYPE mcp_helm_fetches_in_flight gauge
+mcp_helm_fetches_in_flight{host="charts.example.com"} 8
+mcp_helm_fetches_in_flight{host="other"} 3
+`
+	require.NoError(t, testutil.GatherAndCompare(m.registry, strings.NewReader(expected),
+		"mcp_helm_fetch_queue_depth", "mcp_helm_fetches_in_flight"))
+}
+
+// scrape returns the metrics in the exposition format.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rr.Code)
+	return rr.Body.String()
+}
@@ -0,0 +1,184 @@
+// Package ratelimit limits how fast each client may call mcp-helm, with a
+// token bucket per client.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/time/rate"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/mcputil"
+)
+
+// sweepInterval is how often buckets that have refilled are forgotten. A
+// full bucket is the same as a new one, so forgetting it changes nothing.
+const sweepInterval = 10 * time.Minute
+
+// maxBuckets bounds the buckets held at once. Once that many clients are
+// still refilling, new clients share a single overflow bucket, so that a
+// flood of client keys cannot grow the limiter without bound.
+const maxBuckets = 100_000
+
+// overflowKey is the bucket shared by clients beyond maxBuckets. It cannot
+// collide with client keys, which all have a prefix.
+const overflowKey = "overflow"
+
+// Budget is a token bucket: PerMinute calls are allowed per minute, with
+// bursts of up to Burst calls.
+type Budget struct {
+	PerMinute float64
+	Burst     int
+}
+
+// Limiter holds a token bucket per client key. A nil Limiter allows
+// everything. Safe for concurrent use.
+type Limiter struct {
+	limit      rate.Limit
+	burst      int
+	maxBuckets int
+	now        func() time.Time // Replaced in tests
+
+	mu        sync.Mutex
+	buckets   map[string]*rate.Limiter
+	lastSweep time.Time
+}
+
+// New creates a Limiter for budget b, or returns nil if b.PerMinute is not
+// positive, disabling the limit.
+func New(b Budget) *Limiter {
+	if b.PerMinute <= 0 {
+		return nil
+	}
+	return &Limiter{
+		limit:      rate.Limit(b.PerMinute / 60),
+		burst:      max(b.Burst, 1),
+		maxBuckets: maxBuckets,
+		now:        time.Now,
+		buckets:    make(map[string]*rate.Limiter),
+	}
+}
+
+// Allow takes a token from key's bucket. If the bucket is empty it returns
+// false and how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.maxBuckets {
+			l.sweep(now)
+		}
+		if len(l.buckets) >= l.maxBuckets {
+			key = overflowKey
+			b = l.buckets[key]
+		}
+		if b == nil {
+			b = rate.NewLimiter(l.limit, l.burst)
+			l.buckets[key] = b
+		}
+	}
+
+	r := b.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep forgets the buckets that are full again. Buckets still refilling
+// are kept, however long ago they were used. The caller must hold mu.
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.TokensAt(now) >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// RetryAfterSeconds rounds a retry delay up to whole seconds, as reported
+// in Retry-After headers and rate limited tool results.
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ToolLimits limits MCP tool calls per client, drawing calls to expensive
+// tools, which download charts, from a separate budget to the rest.
+type ToolLimits struct {
+	calls     *Limiter
+	downloads *Limiter
+	expensive map[string]bool
+}
+
+// NewToolLimits creates ToolLimits drawing calls to the expensive tools from
+// the downloads budget and all other tool calls from the calls budget. A
+// budget with no rate is unlimited.
+func NewToolLimits(calls, downloads Budget, expensive []string) *ToolLimits {
+	t := &ToolLimits{
+		calls:     New(calls),
+		downloads: New(downloads),
+		expensive: make(map[string]bool, len(expensive)),
+	}
+	for _, name := range expensive {
+		t.expensive[name] = true
+	}
+	return t
+}
+
+// Middleware returns MCP server middleware that answers tool calls over
+// budget with a rate_limited tool error instead of calling the tool.
+// Clients are keyed by the authenticated user if there is one, and by MCP
+// session otherwise.
+func (t *ToolLimits) Middleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method != "tools/call" {
+				return next(ctx, method, req)
+			}
+			params, ok := req.GetParams().(*mcp.CallToolParamsRaw)
+			if !ok {
+				return next(ctx, method, req)
+			}
+
+			limiter, budget := t.calls, "tool call"
+			if t.expensive[params.Name] {
+				limiter, budget = t.downloads, "chart download"
+			}
+			if ok, retryAfter := limiter.Allow(clientKey(req)); !ok {
+				seconds := RetryAfterSeconds(retryAfter)
+				return mcputil.RateLimitedError(seconds,
+					fmt.Sprintf("rate limit exceeded: %s budget exhausted, retry after %ds", budget, seconds)), nil
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// clientKey identifies the client of an MCP request: the authenticated
+// user, or else the session. Sessions over transports without session IDs,
+// such as stdio, are told apart by address.
+func clientKey(req mcp.Request) string {
+	if extra := req.GetExtra(); extra != nil && extra.TokenInfo != nil && extra.TokenInfo.UserID != "" {
+		return "user:" + extra.TokenInfo.UserID
+	}
+	session := req.GetSession()
+	if id := session.ID(); id != "" {
+		return "session:" + id
+	}
+	return fmt.Sprintf("session:%p", session)
+}
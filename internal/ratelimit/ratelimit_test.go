@@ -0,0 +1,191 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/mcputil"
+)
+
+// fakeClock returns a Limiter clock that only moves when advanced.
+func fakeClock(l *Limiter) func(time.Duration) {
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("allows a burst then refills", func(t *testing.T) {
+		l := New(Budget{PerMinute: 6, Burst: 2})
+		advance := fakeClock(l)
+
+		for i := 0; i < 2; i++ {
+			ok, _ := l.Allow("a")
+			require.True(t, ok, "call %d", i)
+		}
+		ok, retryAfter := l.Allow("a")
+		assert.False(t, ok)
+		assert.Equal(t, 10*time.Second, retryAfter)
+
+		// Rejected calls take no token
+		advance(10 * time.Second)
+		ok, _ = l.Allow("a")
+		assert.True(t, ok)
+	})
+
+	t.Run("keys have separate buckets", func(t *testing.T) {
+		l := New(Budget{PerMinute: 1, Burst: 1})
+		fakeClock(l)
+
+		ok, _ := l.Allow("a")
+		assert.True(t, ok)
+		ok, _ = l.Allow("a")
+		assert.False(t, ok)
+		ok, _ = l.Allow("b")
+		assert.True(t, ok)
+	})
+
+	t.Run("forgets refilled clients", func(t *testing.T) {
+		l := New(Budget{PerMinute: 60, Burst: 1})
+		advance := fakeClock(l)
+
+		l.Allow("a")
+		advance(sweepInterval / 2)
+		l.Allow("b")
+		advance(sweepInterval / 2)
+		l.Allow("b")
+		assert.NotContains(t, l.buckets, "a")
+		assert.Contains(t, l.buckets, "b")
+	})
+
+	t.Run("keeps clients still refilling", func(t *testing.T) {
+		// One call per 30 minutes refills slower than the sweep interval
+		l := New(Budget{PerMinute: 1.0 / 30, Burst: 1})
+		advance := fakeClock(l)
+
+		ok, _ := l.Allow("a")
+		require.True(t, ok)
+		advance(sweepInterval)
+		l.Allow("b")
+		assert.Contains(t, l.buckets, "a")
+
+		ok, retryAfter := l.Allow("a")
+		assert.False(t, ok)
+		assert.Equal(t, 30*time.Minute-sweepInterval, retryAfter)
+	})
+
+	t.Run("zero rate disables the limit", func(t *testing.T) {
+		l := New(Budget{Burst: 1})
+		assert.Nil(t, l)
+		for i := 0; i < 100; i++ {
+			ok, _ := l.Allow("a")
+			require.True(t, ok)
+		}
+	})
+
+	t.Run("new clients share a bucket once full", func(t *testing.T) {
+		l := New(Budget{PerMinute: 1, Burst: 1})
+		l.maxBuckets = 2
+		advance := fakeClock(l)
+
+		for _, key := range []string{"a", "b", "c"} {
+			ok, _ := l.Allow(key)
+			assert.True(t, ok, key)
+		}
+		ok, _ := l.Allow("d")
+		assert.False(t, ok, "d shares the overflow bucket with c")
+		assert.Len(t, l.buckets, 3)
+
+		// Refilled buckets make room again
+		advance(time.Minute)
+		ok, _ = l.Allow("d")
+		assert.True(t, ok)
+		assert.Contains(t, l.buckets, "d")
+	})
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 0, RetryAfterSeconds(0))
+	assert.Equal(t, 1, RetryAfterSeconds(time.Millisecond))
+	assert.Equal(t, 2, RetryAfterSeconds(1500*time.Millisecond))
+	assert.Equal(t, 60, RetryAfterSeconds(time.Minute))
+}
+
+// connect runs a server with a cheap and an expensive tool behind the
+// limits, and returns a connected client session.
+func connect(t *testing.T, limits *ToolLimits) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddReceivingMiddleware(limits.Middleware())
+	for _, name := range []string{"search_charts", "get_values"} {
+		mcputil.RegisterTool(server, mcputil.ToolDef{Name: name},
+			func(context.Context, *mcp.CallToolRequest, struct{}) (*mcp.CallToolResult, struct{}, error) {
+				return nil, struct{}{}, nil
+			})
+	}
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = serverSession.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+func TestToolLimits_Middleware(t *testing.T) {
+	ctx := context.Background()
+	call := func(t *testing.T, session *mcp.ClientSession, tool string) *mcp.CallToolResult {
+		t.Helper()
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: tool, Arguments: map[string]any{}})
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("expensive tools have their own budget", func(t *testing.T) {
+		limits := NewToolLimits(Budget{PerMinute: 60, Burst: 3}, Budget{PerMinute: 1, Burst: 1}, []string{"get_values"})
+		session := connect(t, limits)
+
+		assert.False(t, call(t, session, "get_values").IsError)
+		res := call(t, session, "get_values")
+		require.True(t, res.IsError)
+		assert.Equal(t, mcputil.ClassRateLimited, mcputil.ResultErrorClass(res))
+		assert.EqualValues(t, 60, res.Meta[mcputil.RetryAfterKey])
+		assert.Contains(t, res.Content[0].(*mcp.TextContent).Text, "chart download budget exhausted")
+
+		// Cheap tools still have budget
+		for i := 0; i < 3; i++ {
+			assert.False(t, call(t, session, "search_charts").IsError, "call %d", i)
+		}
+		res = call(t, session, "search_charts")
+		assert.Equal(t, mcputil.ClassRateLimited, mcputil.ResultErrorClass(res))
+	})
+
+	t.Run("sessions have separate budgets", func(t *testing.T) {
+		limits := NewToolLimits(Budget{PerMinute: 1, Burst: 1}, Budget{}, nil)
+		first, second := connect(t, limits), connect(t, limits)
+
+		assert.False(t, call(t, first, "search_charts").IsError)
+		assert.True(t, call(t, first, "search_charts").IsError)
+		assert.False(t, call(t, second, "search_charts").IsError)
+	})
+
+	t.Run("unlimited budget", func(t *testing.T) {
+		limits := NewToolLimits(Budget{PerMinute: 1, Burst: 1}, Budget{}, []string{"get_values"})
+		session := connect(t, limits)
+
+		for i := 0; i < 5; i++ {
+			assert.False(t, call(t, session, "get_values").IsError, "call %d", i)
+		}
+	})
+}
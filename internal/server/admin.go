@@ -0,0 +1,134 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+)
+
+// cacheStatsJSON is helm.CacheStats with its hit ratio.
+type cacheStatsJSON struct {
+	helm.CacheStats
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// cachedIndexJSON is helm.CachedIndex with its age.
+type cachedIndexJSON struct {
+	helm.CachedIndex
+	AgeSeconds int64 `json:"age_seconds"`
+}
+
+// adminHandler serves the cache administration endpoints, which require the
+// configured bearer token:
+//
+//	GET  /admin/cache                         cache statistics and contents
+//	POST /admin/cache/refresh?repository=URL  refetch a repository's index
+func (s *Server) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/cache", s.handleCacheStatus)
+	mux.HandleFunc("/admin/cache/refresh", s.handleCacheRefresh)
+	return s.requireAdminToken(&jsonNotFoundMux{mux: mux})
+}
+
+// requireAdminToken rejects requests without the admin bearer token.
+func (s *Server) requireAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-helm admin"`)
+			writeJSONError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid admin token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCacheStatus reports cache statistics and the cached indexes and
+// charts, most recently used first.
+func (s *Server) handleCacheStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+
+	status := s.cacheAdmin.CacheStatus()
+	now := time.Now()
+	indexes := make([]cachedIndexJSON, 0, len(status.IndexEntries))
+	for i := len(status.IndexEntries) - 1; i >= 0; i-- {
+		e := status.IndexEntries[i]
+		indexes = append(indexes, cachedIndexJSON{e, int64(now.Sub(e.FetchedAt) / time.Second)})
+	}
+	charts := make([]helm.CachedChart, 0, len(status.ChartEntries))
+	for i := len(status.ChartEntries) - 1; i >= 0; i-- {
+		charts = append(charts, status.ChartEntries[i])
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Indexes      cacheStatsJSON     `json:"indexes"`
+		Charts       cacheStatsJSON     `json:"charts"`
+		Failures     cacheStatsJSON     `json:"failures"`
+		IndexEntries []cachedIndexJSON  `json:"index_entries"`
+		ChartEntries []helm.CachedChart `json:"chart_entries"`
+	}{
+		Indexes:      cacheStatsJSON{status.Indexes, status.Indexes.HitRatio()},
+		Charts:       cacheStatsJSON{status.Charts, status.Charts.HitRatio()},
+		Failures:     cacheStatsJSON{status.Failures, status.Failures.HitRatio()},
+		IndexEntries: indexes,
+		ChartEntries: charts,
+	})
+}
+
+// handleCacheRefresh refetches the index of the repository named by the
+// repository query parameter and drops its cached failures.
+func (s *Server) handleCacheRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	repo := strings.TrimSpace(r.URL.Query().Get("repository"))
+	if repo == "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "repository query parameter is required")
+		return
+	}
+
+	index, err := s.cacheAdmin.RefreshRepository(r.Context(), repo)
+	if err != nil {
+		s.logger.Warn("admin repository refresh failed", zap.String("repository", repo), zap.Error(err))
+		code := http.StatusBadGateway
+		if helm.IsURLValidationError(err) {
+			code = http.StatusBadRequest
+		}
+		writeJSONError(w, code, "refresh_failed", err.Error())
+		return
+	}
+
+	s.logger.Info("admin refreshed repository", zap.String("repository", repo))
+	writeJSON(w, http.StatusOK, struct {
+		Status     string            `json:"status"`
+		Repository string            `json:"repository"`
+		Index      *helm.CachedIndex `json:"index,omitempty"`
+	}{"refreshed", repo, index})
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes an error response in the same shape as unmatched routes.
+func writeJSONError(w http.ResponseWriter, code int, errCode, description string) {
+	writeJSON(w, code, struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}{errCode, description})
+}
@@ -2,11 +2,16 @@ package server
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"time"
 
+	sdkauth "github.com/modelcontextprotocol/go-sdk/auth"
 	"go.uber.org/zap"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/ratelimit"
 )
 
 // LoggingMiddleware logs HTTP requests.
@@ -66,6 +71,39 @@ func RecoveryMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+// RateLimitMiddleware rejects requests over the client's budget with 429
+// and a Retry-After header. Clients are keyed by the user authenticated by
+// an enclosing auth middleware if there is one, and by IP address otherwise.
+func RateLimitMiddleware(l *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retryAfter := l.Allow(requestClient(r)); !ok {
+				seconds := ratelimit.RetryAfterSeconds(retryAfter)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				writeJSONError(w, http.StatusTooManyRequests, "rate_limited",
+					"too many requests, retry after "+strconv.Itoa(seconds)+"s")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestClient identifies the client of an HTTP request: the authenticated
+// user, or else the remote IP address. The Mcp-Session-Id header is not
+// used, since it has not been checked yet and a client could send a new one
+// with every request to get a fresh budget.
+func requestClient(r *http.Request) string {
+	if info := sdkauth.TokenInfoFromContext(r.Context()); info != nil && info.UserID != "" {
+		return "user:" + info.UserID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
 // responseWriter wraps http.ResponseWriter to capture the status code.
 type responseWriter struct {
 	http.ResponseWriter
@@ -1,12 +1,17 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	sdkauth "github.com/modelcontextprotocol/go-sdk/auth"
 	"go.uber.org/zap"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/ratelimit"
 )
 
 func TestLoggingMiddleware(t *testing.T) {
@@ -79,6 +84,88 @@ func TestRecoveryMiddleware(t *testing.T) {
 	})
 }
 
+func TestRateLimitMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	request := func(h http.Handler, remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/mcp", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("limits each IP address", func(t *testing.T) {
+		h := RateLimitMiddleware(ratelimit.New(ratelimit.Budget{PerMinute: 1, Burst: 2}))(ok)
+
+		for i := 0; i < 2; i++ {
+			if rr := request(h, "192.0.2.1:1234", ""); rr.Code != http.StatusOK {
+				t.Fatalf("request %d: got status %d, want %d", i, rr.Code, http.StatusOK)
+			}
+		}
+		rr := request(h, "192.0.2.1:5678", "")
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("got status %d, want %d", rr.Code, http.StatusTooManyRequests)
+		}
+		if got := rr.Header().Get("Retry-After"); got != "60" {
+			t.Errorf("got Retry-After %q, want %q", got, "60")
+		}
+		var body map[string]string
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+		if body["error"] != "rate_limited" {
+			t.Errorf("got error %q, want %q", body["error"], "rate_limited")
+		}
+
+		if rr := request(h, "192.0.2.2:1234", ""); rr.Code != http.StatusOK {
+			t.Errorf("other IP: got status %d, want %d", rr.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("limits authenticated users across IP addresses", func(t *testing.T) {
+		verify := func(_ context.Context, token string, _ *http.Request) (*sdkauth.TokenInfo, error) {
+			return &sdkauth.TokenInfo{UserID: token, Expiration: time.Now().Add(time.Hour)}, nil
+		}
+		limited := RateLimitMiddleware(ratelimit.New(ratelimit.Budget{PerMinute: 1, Burst: 1}))(ok)
+		h := sdkauth.RequireBearerToken(verify, nil)(limited)
+
+		if rr := request(h, "192.0.2.1:1234", "alice"); rr.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+		}
+		if rr := request(h, "192.0.2.2:1234", "alice"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("same user: got status %d, want %d", rr.Code, http.StatusTooManyRequests)
+		}
+		if rr := request(h, "192.0.2.1:1234", "bob"); rr.Code != http.StatusOK {
+			t.Errorf("other user: got status %d, want %d", rr.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("ignores session IDs from unauthenticated clients", func(t *testing.T) {
+		h := RateLimitMiddleware(ratelimit.New(ratelimit.Budget{PerMinute: 1, Burst: 1}))(ok)
+		inSession := func(session string) int {
+			req := httptest.NewRequest("POST", "/mcp", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("Mcp-Session-Id", session)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			return rr.Code
+		}
+
+		if code := inSession("s1"); code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+		if code := inSession("s2"); code != http.StatusTooManyRequests {
+			t.Errorf("new session ID: got status %d, want %d", code, http.StatusTooManyRequests)
+		}
+	})
+
+}
+
 func TestResponseWriter(t *testing.T) {
 	t.Run("captures status code", func(t *testing.T) {
 		underlying := httptest.NewRecorder()
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// CORS headers for web clients of /mcp: the request headers the MCP
+// Streamable HTTP transport uses, and the response headers clients read.
+const (
+	corsAllowMethods  = "GET, POST, DELETE, OPTIONS"
+	corsAllowHeaders  = "Authorization, Content-Type, Accept, Last-Event-ID, Mcp-Session-Id, Mcp-Protocol-Version"
+	corsExposeHeaders = "Mcp-Session-Id, Mcp-Protocol-Version, WWW-Authenticate, Retry-After"
+	corsMaxAge        = "600"
+)
+
+// originPolicy validates the Origin and Host headers of requests, so that
+// web pages cannot reach the server through a user's browser, including by
+// rebinding their own host name to a local address.
+type originPolicy struct {
+	origins   map[string]bool // Allowed origins, normalized
+	anyOrigin bool
+	hosts     []string // Allowed Host headers; empty allows loopback names on loopback connections, and any name otherwise
+}
+
+// newOriginPolicy creates an originPolicy allowing browser requests from
+// origins ("*" allows any) and requests with the given Host headers.
+func newOriginPolicy(origins, hosts []string) *originPolicy {
+	p := &originPolicy{origins: make(map[string]bool, len(origins)), hosts: hosts}
+	for _, o := range origins {
+		if o == "*" {
+			p.anyOrigin = true
+			continue
+		}
+		p.origins[normalizeOrigin(o)] = true
+	}
+	return p
+}
+
+// Middleware rejects requests with a Host header that is not allowed, and
+// requests from browsers on origins that are not allowed, with 403. Requests
+// without an Origin header come from other clients and are not restricted.
+// Allowed origins get CORS headers, and their preflight requests are
+// answered here, before authentication.
+func (p *originPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.hostAllowed(r) {
+			writeJSONError(w, http.StatusForbidden, "forbidden", "invalid Host header "+r.Host)
+			return
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !p.anyOrigin && !p.origins[normalizeOrigin(origin)] {
+			writeJSONError(w, http.StatusForbidden, "forbidden", "origin "+origin+" is not allowed")
+			return
+		}
+
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			h.Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostAllowed reports whether the request's Host header is allowed: one of
+// the configured hosts, or else a loopback name if the request arrived on a
+// loopback address. Configured hosts without a port match any port.
+func (p *originPolicy) hostAllowed(r *http.Request) bool {
+	hostname := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		hostname = h
+	}
+	hostname = strings.Trim(hostname, "[]")
+
+	if len(p.hosts) > 0 {
+		for _, allowed := range p.hosts {
+			if strings.EqualFold(allowed, r.Host) || strings.EqualFold(strings.Trim(allowed, "[]"), hostname) {
+				return true
+			}
+		}
+		return false
+	}
+	if local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && isLoopback(local.String()) {
+		return isLoopback(hostname)
+	}
+	return true
+}
+
+// isLoopback reports whether addr, a host name or IP address with an
+// optional port, is localhost or a loopback address.
+func isLoopback(addr string) bool {
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+// normalizeOrigin lowercases an origin and drops a trailing slash, as found
+// in configured origins.
+func normalizeOrigin(origin string) string {
+	u, err := url.Parse(strings.TrimSuffix(origin, "/"))
+	if err != nil || u.Host == "" {
+		return strings.ToLower(origin)
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginPolicy(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	loopback := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8012}
+	public := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 5), Port: 8012}
+
+	tests := []struct {
+		name      string
+		origins   []string
+		hosts     []string
+		localAddr net.Addr
+		host      string
+		origin    string
+		wantCode  int
+		wantCORS  bool
+	}{
+		{"no origin on loopback", nil, nil, loopback, "localhost:8012", "", http.StatusOK, false},
+		{"IPv6 loopback host", nil, nil, loopback, "[::1]:8012", "", http.StatusOK, false},
+		{"rebound host on loopback", nil, nil, loopback, "attacker.example:8012", "", http.StatusForbidden, false},
+		{"any host on other addresses", nil, nil, public, "mcp.example.com", "", http.StatusOK, false},
+		{"allowed host", nil, []string{"mcp.example.com"}, public, "mcp.example.com:443", "", http.StatusOK, false},
+		{"allowed host with port", nil, []string{"mcp.example.com:8443"}, public, "mcp.example.com:8443", "", http.StatusOK, false},
+		{"host not allowed", nil, []string{"mcp.example.com"}, public, "other.example.com", "", http.StatusForbidden, false},
+		{"browser origin not allowed", nil, nil, loopback, "localhost:8012", "https://attacker.example", http.StatusForbidden, false},
+		{"allowed origin", []string{"https://app.example.com/"}, nil, public, "mcp.example.com", "https://App.example.com", http.StatusOK, true},
+		{"other origin", []string{"https://app.example.com"}, nil, public, "mcp.example.com", "https://app.example.com:8443", http.StatusForbidden, false},
+		{"any origin", []string{"*"}, nil, public, "mcp.example.com", "https://app.example.com", http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newOriginPolicy(tt.origins, tt.hosts).Middleware(ok)
+
+			req := httptest.NewRequest("POST", "/mcp", nil)
+			req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, tt.localAddr))
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantCode)
+			}
+			gotCORS := rr.Header().Get("Access-Control-Allow-Origin")
+			if tt.wantCORS && gotCORS != tt.origin {
+				t.Errorf("got Access-Control-Allow-Origin %q, want %q", gotCORS, tt.origin)
+			}
+			if !tt.wantCORS && gotCORS != "" {
+				t.Errorf("got Access-Control-Allow-Origin %q, want none", gotCORS)
+			}
+		})
+	}
+}
+
+func TestOriginPolicy_Preflight(t *testing.T) {
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	h := newOriginPolicy([]string{"https://app.example.com"}, nil).Middleware(next)
+
+	req := httptest.NewRequest("OPTIONS", "/mcp", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "authorization, content-type, mcp-session-id")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusNoContent)
+	}
+	if reached {
+		t.Error("preflight reached the wrapped handler")
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": corsAllowMethods,
+		"Access-Control-Allow-Headers": corsAllowHeaders,
+		"Vary":                         "Origin",
+	} {
+		if got := rr.Header().Get(header); got != want {
+			t.Errorf("got %s %q, want %q", header, got, want)
+		}
+	}
+
+	// Preflights from other origins are rejected
+	req.Header.Set("Origin", "https://attacker.example")
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+)
+
+const (
+	// repoCheckTimeout bounds a repository reachability check.
+	repoCheckTimeout = 5 * time.Second
+	// repoCheckTTL is how long a repository check result is reused, so that
+	// frequent probes do not turn into a request to every repository.
+	repoCheckTTL = 30 * time.Second
+)
+
+// Errors reported by the shutdown and warmup checks.
+var (
+	errShuttingDown = errors.New("server is shutting down")
+	errWarming      = errors.New("first cache warming round has not finished")
+)
+
+// Names of the checks reported by /readyz.
+const (
+	checkShutdown   = "shutdown"
+	checkCacheDir   = "cache_dir"
+	checkRegistry   = "registry"
+	checkWarmup     = "warmup"
+	checkRepository = "repository"
+)
+
+// readyCheck is the outcome of one readiness check.
+type readyCheck struct {
+	Name   string `json:"name"`
+	Target string `json:"target,omitempty"` // Repository URL for repository checks
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// newReadyCheck returns the outcome of a check that failed with err, or
+// passed if err is nil.
+func newReadyCheck(name, target string, err error) readyCheck {
+	c := readyCheck{Name: name, Target: target, OK: err == nil}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	return c
+}
+
+// readyResponse is the body of /readyz.
+type readyResponse struct {
+	Status    string           `json:"status"` // "ready" or "not_ready"
+	Timestamp string           `json:"timestamp"`
+	Checks    []readyCheck     `json:"checks"`
+	Warmup    *helm.WarmStatus `json:"warmup,omitempty"`
+}
+
+// repoCheckCache remembers recent repository check results.
+type repoCheckCache struct {
+	checks singleflight.Group // Concurrent probes of a repository share one check
+
+	mu      sync.Mutex
+	results map[string]repoCheckResult
+}
+
+type repoCheckResult struct {
+	err       error
+	checkedAt time.Time
+}
+
+// check returns the result of checking repo, reusing a result younger than
+// repoCheckTTL.
+func (c *repoCheckCache) check(ctx context.Context, checker helm.ReadinessChecker, repo string) error {
+	c.mu.Lock()
+	res, ok := c.results[repo]
+	c.mu.Unlock()
+	if ok && time.Since(res.checkedAt) < repoCheckTTL {
+		return res.err
+	}
+
+	// The result is shared with concurrent and later probes, so it must not
+	// depend on whether this probe's client went away
+	_, err, _ := c.checks.Do(repo, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), repoCheckTimeout)
+		defer cancel()
+		err := checker.CheckRepository(ctx, repo)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.results == nil {
+			c.results = make(map[string]repoCheckResult)
+		}
+		c.results[repo] = repoCheckResult{err: err, checkedAt: time.Now()}
+		return nil, err
+	})
+	return err
+}
+
+// handleReadyz handles readiness probe requests. It responds 503 if any
+// check fails, with the outcome of every check.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	resp := readyResponse{
+		Status:    "ready",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Checks:    s.readyChecks(r.Context()),
+	}
+	if s.warmup != nil {
+		warmup := s.warmup.WarmStatus()
+		resp.Warmup = &warmup
+	}
+
+	code := http.StatusOK
+	for _, c := range resp.Checks {
+		if !c.OK {
+			resp.Status, code = "not_ready", http.StatusServiceUnavailable
+			break
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// readyChecks runs the readiness checks, checking repositories concurrently.
+func (s *Server) readyChecks(ctx context.Context) []readyCheck {
+	var checks []readyCheck
+
+	var shutdownErr error
+	if s.shuttingDown.Load() {
+		shutdownErr = errShuttingDown
+	}
+	checks = append(checks, newReadyCheck(checkShutdown, "", shutdownErr))
+
+	if s.warmup != nil {
+		var warmupErr error
+		if !s.warmup.WarmStatus().Ready {
+			warmupErr = errWarming
+		}
+		checks = append(checks, newReadyCheck(checkWarmup, "", warmupErr))
+	}
+
+	if s.readiness == nil {
+		return checks
+	}
+	checks = append(checks,
+		newReadyCheck(checkCacheDir, "", s.readiness.CheckCacheDir()),
+		newReadyCheck(checkRegistry, "", s.readiness.CheckRegistry()),
+	)
+
+	repos := make([]readyCheck, len(s.cfg.ReadyRepositories))
+	var wg sync.WaitGroup
+	for i, repo := range s.cfg.ReadyRepositories {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repos[i] = newReadyCheck(checkRepository, repo, s.repoChecks.check(ctx, s.readiness, repo))
+		}()
+	}
+	wg.Wait()
+	return append(checks, repos...)
+}
@@ -3,32 +3,103 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
 
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/auth"
 	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/config"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/ratelimit"
 )
 
 // Server wraps the MCP server with HTTP transport and lifecycle management.
 type Server struct {
-	cfg       *config.Config
-	logger    *zap.Logger
-	mcpServer *mcp.Server
+	cfg        *config.Config
+	logger     *zap.Logger
+	mcpServer  *mcp.Server
+	warmup     WarmupReporter        // nil when caches are not warmed
+	readiness  helm.ReadinessChecker // nil when only warming and shutdown are checked
+	cacheAdmin helm.CacheAdmin       // nil when the /admin endpoints are disabled
+	metrics    http.Handler          // nil when metrics are not served
+	auth       *auth.Authenticator   // nil when /mcp is not authenticated
+	rateLimit  *ratelimit.Limiter    // nil when /mcp requests are not rate limited
+
+	shuttingDown atomic.Bool
+	repoChecks   repoCheckCache
+}
+
+// WarmupReporter reports the progress of cache warming.
+type WarmupReporter interface {
+	WarmStatus() helm.WarmStatus
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithWarmup reports cache warming progress in /readyz, which is not ready
+// until the first warming round has finished.
+func WithWarmup(r WarmupReporter) Option {
+	return func(s *Server) {
+		s.warmup = r
+	}
+}
+
+// WithReadiness checks the cache directory, the OCI registry client and the
+// repositories listed in the ready-repositories setting in /readyz.
+func WithReadiness(c helm.ReadinessChecker) Option {
+	return func(s *Server) {
+		s.readiness = c
+	}
+}
+
+// WithCacheAdmin serves the /admin/cache endpoints over HTTP. They are only
+// registered when an admin token is configured.
+func WithCacheAdmin(a helm.CacheAdmin) Option {
+	return func(s *Server) {
+		s.cacheAdmin = a
+	}
+}
+
+// WithAuth requires a valid bearer token on /mcp, and serves the OAuth
+// protected resource metadata if a accepts JWTs.
+func WithAuth(a *auth.Authenticator) Option {
+	return func(s *Server) {
+		s.auth = a
+	}
+}
+
+// WithRateLimit limits the requests each client may make to /mcp, keyed by
+// authenticated user or IP address.
+func WithRateLimit(l *ratelimit.Limiter) Option {
+	return func(s *Server) {
+		s.rateLimit = l
+	}
+}
+
+// WithMetrics serves h on /metrics.
+func WithMetrics(h http.Handler) Option {
+	return func(s *Server) {
+		s.metrics = h
+	}
 }
 
 // New creates a new Server.
-func New(cfg *config.Config, logger *zap.Logger, mcpServer *mcp.Server) *Server {
-	return &Server{
+func New(cfg *config.Config, logger *zap.Logger, mcpServer *mcp.Server, opts ...Option) *Server {
+	s := &Server{
 		cfg:       cfg,
 		logger:    logger,
 		mcpServer: mcpServer,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Run starts the server and blocks until the context is cancelled.
@@ -62,12 +133,34 @@ func (s *Server) runHTTP(ctx context.Context) error {
 		&mcp.StreamableHTTPOptions{},
 	)
 
+	// Rate limit inside authentication, so that clients are keyed by user
+	var mcpEndpoint http.Handler = mcpHandler
+	if s.rateLimit != nil {
+		mcpEndpoint = RateLimitMiddleware(s.rateLimit)(mcpEndpoint)
+	}
+	if s.auth != nil {
+		mcpEndpoint = s.auth.Middleware()(mcpEndpoint)
+	}
+	// Check Origin and Host first, so that CORS preflights need no token
+	mcpEndpoint = newOriginPolicy(s.cfg.AllowedOrigins, s.cfg.AllowedHostHeaders).Middleware(mcpEndpoint)
+
 	// Build router
 	mux := http.NewServeMux()
-	mux.Handle("/mcp", mcpHandler)
-	mux.Handle("/mcp/", mcpHandler)
+	mux.Handle("/mcp", mcpEndpoint)
+	mux.Handle("/mcp/", mcpEndpoint)
+	if s.auth != nil && s.auth.OAuth() {
+		for _, path := range s.auth.MetadataPaths() {
+			mux.Handle(path, s.auth.MetadataHandler())
+		}
+	}
 	mux.HandleFunc("/healthz", s.handleHealthz)
 	mux.HandleFunc("/readyz", s.handleReadyz)
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics)
+	}
+	if s.cacheAdmin != nil && s.cfg.AdminToken != "" {
+		mux.Handle("/admin/", s.adminHandler())
+	}
 
 	// Apply middleware
 	var handler http.Handler = &jsonNotFoundMux{mux: mux}
@@ -84,6 +177,12 @@ func (s *Server) runHTTP(ctx context.Context) error {
 		IdleTimeout:       120 * time.Second,
 	}
 
+	if s.auth == nil && !isLoopback(s.cfg.Listen) {
+		s.logger.Warn("serving /mcp without authentication on a non-loopback address; any client that can reach it can use it",
+			zap.String("addr", s.cfg.Listen),
+		)
+	}
+
 	// Start server in goroutine
 	errCh := make(chan error, 1)
 	go func() {
@@ -104,6 +203,12 @@ func (s *Server) runHTTP(ctx context.Context) error {
 		return fmt.Errorf("server error: %w", err)
 	case <-ctx.Done():
 		s.logger.Info("shutting down server")
+		s.shuttingDown.Store(true)
+		if s.cfg.ShutdownDelay > 0 {
+			// Keep serving while load balancers notice /readyz failing
+			s.logger.Info("waiting before shutdown", zap.Duration("delay", s.cfg.ShutdownDelay))
+			time.Sleep(s.cfg.ShutdownDelay)
+		}
 
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -129,10 +234,7 @@ func (m *jsonNotFoundMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Check if the mux has a handler for this path.
 	_, pattern := m.mux.Handler(r)
 	if pattern == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		pathJSON, _ := json.Marshal(r.URL.Path)
-		_, _ = fmt.Fprintf(w, `{"error":"not_found","error_description":"no route for %s"}`, string(pathJSON))
+		writeJSONError(w, http.StatusNotFound, "not_found", "no route for "+r.URL.Path)
 		return
 	}
 	m.mux.ServeHTTP(w, r)
@@ -144,10 +246,3 @@ func (s *Server) handleHealthz(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = fmt.Fprintf(w, `{"status":"ok","timestamp":"%s"}`, time.Now().UTC().Format(time.RFC3339))
 }
-
-// handleReadyz handles readiness probe requests.
-func (s *Server) handleReadyz(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, _ = fmt.Fprintf(w, `{"status":"ready","timestamp":"%s"}`, time.Now().UTC().Format(time.RFC3339))
-}
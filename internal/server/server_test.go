@@ -0,0 +1,288 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/config"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm/mocks"
+)
+
+type fakeWarmup helm.WarmStatus
+
+func (f fakeWarmup) WarmStatus() helm.WarmStatus { return helm.WarmStatus(f) }
+
+func TestHandleReadyz(t *testing.T) {
+	readyz := func(t *testing.T, s *Server) (int, map[string]any) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.handleReadyz(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+		var body map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid JSON response: %v", err)
+		}
+		return rr.Code, body
+	}
+	// check returns the named check from a /readyz body.
+	check := func(t *testing.T, body map[string]any, name, target string) map[string]any {
+		t.Helper()
+		checks, _ := body["checks"].([]any)
+		for _, c := range checks {
+			c, _ := c.(map[string]any)
+			if c["name"] == name && (target == "" || c["target"] == target) {
+				return c
+			}
+		}
+		t.Fatalf("no %s check in %v", name, body["checks"])
+		return nil
+	}
+	newServer := func(cfg *config.Config, opts ...Option) *Server {
+		return New(cfg, zap.NewNop(), nil, opts...)
+	}
+
+	t.Run("ready without warming", func(t *testing.T) {
+		code, body := readyz(t, newServer(&config.Config{}))
+		if code != http.StatusOK {
+			t.Errorf("got status %d, want %d", code, http.StatusOK)
+		}
+		if body["status"] != "ready" {
+			t.Errorf("got status field %v, want ready", body["status"])
+		}
+		if c := check(t, body, "shutdown", ""); c["ok"] != true {
+			t.Errorf("got shutdown check %v, want ok", c)
+		}
+	})
+
+	t.Run("not ready while warming", func(t *testing.T) {
+		code, body := readyz(t, newServer(&config.Config{}, WithWarmup(fakeWarmup{Running: true, Total: 4, Done: 1})))
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("got status %d, want %d", code, http.StatusServiceUnavailable)
+		}
+		if body["status"] != "not_ready" {
+			t.Errorf("got status field %v, want not_ready", body["status"])
+		}
+		if c := check(t, body, "warmup", ""); c["ok"] != false {
+			t.Errorf("got warmup check %v, want failed", c)
+		}
+		warmup, _ := body["warmup"].(map[string]any)
+		if warmup["total"] != 4.0 || warmup["done"] != 1.0 {
+			t.Errorf("got warmup %v, want total 4 and done 1", warmup)
+		}
+	})
+
+	t.Run("ready after the first round", func(t *testing.T) {
+		code, body := readyz(t, newServer(&config.Config{}, WithWarmup(fakeWarmup{Ready: true, Running: true, Rounds: 1})))
+		if code != http.StatusOK {
+			t.Errorf("got status %d, want %d", code, http.StatusOK)
+		}
+		if body["status"] != "ready" {
+			t.Errorf("got status field %v, want ready", body["status"])
+		}
+	})
+
+	t.Run("not ready while shutting down", func(t *testing.T) {
+		s := newServer(&config.Config{})
+		s.shuttingDown.Store(true)
+		code, body := readyz(t, s)
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("got status %d, want %d", code, http.StatusServiceUnavailable)
+		}
+		if c := check(t, body, "shutdown", ""); c["ok"] != false {
+			t.Errorf("got shutdown check %v, want failed", c)
+		}
+	})
+
+	t.Run("reports service checks", func(t *testing.T) {
+		checker := new(mocks.ReadinessChecker)
+		checker.On("CheckCacheDir").Return(nil)
+		checker.On("CheckRegistry").Return(errors.New("OCI registry client is not available"))
+		checker.On("CheckRepository", mock.Anything, "https://up.example.com").Return(nil)
+		checker.On("CheckRepository", mock.Anything, "https://down.example.com").Return(errors.New("connection refused"))
+		s := newServer(&config.Config{ReadyRepositories: []string{"https://up.example.com", "https://down.example.com"}}, WithReadiness(checker))
+
+		code, body := readyz(t, s)
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("got status %d, want %d", code, http.StatusServiceUnavailable)
+		}
+		if body["status"] != "not_ready" {
+			t.Errorf("got status field %v, want not_ready", body["status"])
+		}
+		if c := check(t, body, "cache_dir", ""); c["ok"] != true {
+			t.Errorf("got cache_dir check %v, want ok", c)
+		}
+		if c := check(t, body, "registry", ""); c["ok"] != false || c["error"] != "OCI registry client is not available" {
+			t.Errorf("got registry check %v, want failed with its error", c)
+		}
+		if c := check(t, body, "repository", "https://up.example.com"); c["ok"] != true {
+			t.Errorf("got repository check %v, want ok", c)
+		}
+		if c := check(t, body, "repository", "https://down.example.com"); c["ok"] != false || c["error"] != "connection refused" {
+			t.Errorf("got repository check %v, want failed with its error", c)
+		}
+
+		// Repository results are reused by later probes
+		_, _ = readyz(t, s)
+		checker.AssertNumberOfCalls(t, "CheckRepository", 2)
+		checker.AssertNumberOfCalls(t, "CheckCacheDir", 2)
+	})
+
+	t.Run("concurrent probes share a repository check", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		var once sync.Once
+		checker := new(mocks.ReadinessChecker)
+		checker.On("CheckCacheDir").Return(nil)
+		checker.On("CheckRegistry").Return(nil)
+		checker.On("CheckRepository", mock.Anything, "https://slow.example.com").
+			Run(func(mock.Arguments) {
+				once.Do(func() { close(started) })
+				<-release
+			}).
+			Return(nil)
+		s := newServer(&config.Config{ReadyRepositories: []string{"https://slow.example.com"}}, WithReadiness(checker))
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rr := httptest.NewRecorder()
+				s.handleReadyz(rr, httptest.NewRequest("GET", "/readyz", nil))
+				if rr.Code != http.StatusOK {
+					t.Errorf("got status %d, want %d", rr.Code, http.StatusOK)
+				}
+			}()
+		}
+		// Give the other probes time to reach the check before it finishes
+		<-started
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		checker.AssertNumberOfCalls(t, "CheckRepository", 1)
+	})
+}
+
+func TestAdminHandler(t *testing.T) {
+	fetchedAt := time.Now().Add(-time.Minute)
+	admin := new(mocks.CacheAdmin)
+	admin.On("CacheStatus").Return(&helm.CacheStatus{
+		Indexes: helm.CacheStats{Hits: 1, Misses: 1, Size: 1},
+		IndexEntries: []helm.CachedIndex{
+			{Repository: "https://repo.com", FetchedAt: fetchedAt, Charts: 2},
+		},
+	})
+	admin.On("RefreshRepository", mock.Anything, "https://repo.com").
+		Return(&helm.CachedIndex{Repository: "https://repo.com", FetchedAt: fetchedAt, Charts: 2}, nil)
+	admin.On("RefreshRepository", mock.Anything, "https://down.com").
+		Return(nil, &helm.RepositoryError{URL: "https://down.com", Op: "fetch", Err: errors.New("connection refused")})
+
+	s := New(&config.Config{AdminToken: "s3cret"}, zap.NewNop(), nil, WithCacheAdmin(admin))
+	h := s.adminHandler()
+
+	serve := func(t *testing.T, method, target, token string) (int, map[string]any) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		var body map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid JSON response: %v", err)
+		}
+		return rr.Code, body
+	}
+
+	t.Run("rejects missing token", func(t *testing.T) {
+		code, body := serve(t, "GET", "/admin/cache", "")
+		if code != http.StatusUnauthorized {
+			t.Errorf("got status %d, want %d", code, http.StatusUnauthorized)
+		}
+		if body["error"] != "unauthorized" {
+			t.Errorf("got error %v, want unauthorized", body["error"])
+		}
+	})
+
+	t.Run("rejects wrong token", func(t *testing.T) {
+		code, _ := serve(t, "GET", "/admin/cache", "wrong")
+		if code != http.StatusUnauthorized {
+			t.Errorf("got status %d, want %d", code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("reports cache status", func(t *testing.T) {
+		code, body := serve(t, "GET", "/admin/cache", "s3cret")
+		if code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+		indexes, _ := body["indexes"].(map[string]any)
+		if indexes["hit_ratio"] != 0.5 {
+			t.Errorf("got hit_ratio %v, want 0.5", indexes["hit_ratio"])
+		}
+		entries, _ := body["index_entries"].([]any)
+		if len(entries) != 1 {
+			t.Fatalf("got %d index entries, want 1", len(entries))
+		}
+		entry, _ := entries[0].(map[string]any)
+		if entry["repository"] != "https://repo.com" {
+			t.Errorf("got repository %v, want https://repo.com", entry["repository"])
+		}
+		if age, _ := entry["age_seconds"].(float64); age < 59 || age > 61 {
+			t.Errorf("got age_seconds %v, want about 60", entry["age_seconds"])
+		}
+	})
+
+	t.Run("refresh requires POST", func(t *testing.T) {
+		code, _ := serve(t, "GET", "/admin/cache/refresh?repository=https://repo.com", "s3cret")
+		if code != http.StatusMethodNotAllowed {
+			t.Errorf("got status %d, want %d", code, http.StatusMethodNotAllowed)
+		}
+	})
+
+	t.Run("refresh requires repository", func(t *testing.T) {
+		code, _ := serve(t, "POST", "/admin/cache/refresh", "s3cret")
+		if code != http.StatusBadRequest {
+			t.Errorf("got status %d, want %d", code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("refreshes repository", func(t *testing.T) {
+		code, body := serve(t, "POST", "/admin/cache/refresh?repository=https://repo.com", "s3cret")
+		if code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+		index, _ := body["index"].(map[string]any)
+		if index["charts"] != 2.0 {
+			t.Errorf("got index %v, want 2 charts", index)
+		}
+	})
+
+	t.Run("reports refresh failure", func(t *testing.T) {
+		code, body := serve(t, "POST", "/admin/cache/refresh?repository=https://down.com", "s3cret")
+		if code != http.StatusBadGateway {
+			t.Errorf("got status %d, want %d", code, http.StatusBadGateway)
+		}
+		if body["error"] != "refresh_failed" {
+			t.Errorf("got error %v, want refresh_failed", body["error"])
+		}
+	})
+
+	t.Run("unknown admin route", func(t *testing.T) {
+		code, _ := serve(t, "GET", "/admin/other", "s3cret")
+		if code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", code, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+// Package traceutil provides helpers for tracing work in spans. It depends on
+// nothing else in mcp-helm, so that every package can use it.
+package traceutil
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartSpan starts a span from the named tracer of the global tracer
+// provider, which records nothing unless tracing is configured.
+func StartSpan(ctx context.Context, tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracer).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err, if any, on span and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
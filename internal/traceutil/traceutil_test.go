@@ -0,0 +1,42 @@
+package traceutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpans(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	_, span := StartSpan(context.Background(), "test", "ok", attribute.String("key", "value"))
+	EndSpan(span, nil)
+	_, span = StartSpan(context.Background(), "test", "failed")
+	EndSpan(span, errors.New("boom"))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "ok", spans[0].Name)
+	assert.Equal(t, "test", spans[0].InstrumentationScope.Name)
+	assert.Contains(t, spans[0].Attributes, attribute.String("key", "value"))
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Empty(t, spans[0].Events)
+
+	assert.Equal(t, "failed", spans[1].Name)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Equal(t, "boom", spans[1].Status.Description)
+	require.Len(t, spans[1].Events, 1)
+	assert.Equal(t, "exception", spans[1].Events[0].Name)
+}
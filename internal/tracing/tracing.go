@@ -0,0 +1,118 @@
+// Package tracing exports OpenTelemetry traces of MCP tool calls and the
+// work they cause.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/mcputil"
+)
+
+// serviceName identifies mcp-helm in exported traces.
+const serviceName = "mcp-helm"
+
+// tracerName names the tracer of the tool call spans.
+const tracerName = "github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/tracing"
+
+// Options configures tracing.
+type Options struct {
+	// Endpoint is the URL of the OTLP/HTTP collector, e.g.
+	// http://localhost:4318. Traces are sent to its /v1/traces path.
+	Endpoint string
+	// SampleRatio is the fraction of new traces that are recorded. Traces
+	// started by a caller follow the caller's sampling decision.
+	SampleRatio float64
+	// ServiceVersion is reported as the service.version resource attribute.
+	ServiceVersion string
+}
+
+// Setup exports traces to the OTLP collector at opts.Endpoint, installing
+// the tracer provider and the W3C trace context propagator globally. The
+// returned function flushes pending spans and stops exporting.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opts.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	tp := Install(sdktrace.WithBatcher(exporter), opts)
+	return tp.Shutdown, nil
+}
+
+// Install creates a tracer provider that passes spans to processor, and
+// installs it and the W3C trace context propagator globally. Tests use it
+// with an in-memory exporter.
+func Install(processor sdktrace.TracerProviderOption, opts Options) *sdktrace.TracerProvider {
+	tp := sdktrace.NewTracerProvider(
+		processor,
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", opts.ServiceVersion),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return tp
+}
+
+// Middleware returns MCP server middleware that traces each tool call in a
+// span, continuing the trace of the HTTP request that carried it if the
+// request has W3C trace context headers. Spans of the tool's own work are
+// children of the call's span.
+func Middleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method != "tools/call" {
+				return next(ctx, method, req)
+			}
+
+			if extra := req.GetExtra(); extra != nil && extra.Header != nil {
+				ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(extra.Header))
+			}
+
+			var tool string
+			if params, ok := req.GetParams().(*mcp.CallToolParamsRaw); ok {
+				tool = params.Name
+			}
+			attrs := []attribute.KeyValue{
+				attribute.String("mcp.method.name", method),
+				attribute.String("gen_ai.tool.name", tool),
+			}
+			if session := req.GetSession(); session != nil {
+				attrs = append(attrs, attribute.String("mcp.session.id", session.ID()))
+			}
+			ctx, span := otel.Tracer(tracerName).Start(ctx, method+" "+tool,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			res, err := next(ctx, method, req)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return res, err
+			}
+			toolResult, _ := res.(*mcp.CallToolResult)
+			if class := mcputil.ResultErrorClass(toolResult); class != "" {
+				span.SetAttributes(attribute.String("error.type", class))
+				span.SetStatus(codes.Error, class)
+			}
+			return res, err
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/helm"
+	"github.com/Kubedoll-Heavy-Industries/mcp-helm/internal/mcputil"
+)
+
+type echoInput struct {
+	Fail bool `json:"fail,omitempty"`
+}
+
+type echoOutput struct {
+	OK bool `json:"ok"`
+}
+
+// install records spans in an in-memory exporter for the duration of the
+// test, restoring the previous global provider and propagator afterwards.
+func install(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	exporter := tracetest.NewInMemoryExporter()
+	Install(sdktrace.WithSyncer(exporter), Options{SampleRatio: 1})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+// newServer returns a server with the tracing middleware and an echo tool
+// that starts a child span of its own.
+func newServer() *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddReceivingMiddleware(Middleware())
+	mcputil.RegisterTool(server, mcputil.ToolDef{Name: "echo"},
+		func(ctx context.Context, _ *mcp.CallToolRequest, in echoInput) (*mcp.CallToolResult, echoOutput, error) {
+			_, span := otel.Tracer("test").Start(ctx, "work")
+			span.End()
+			if in.Fail {
+				return mcputil.HandleError(&helm.ChartNotFoundError{Chart: "nginx"}), echoOutput{}, nil
+			}
+			return nil, echoOutput{OK: true}, nil
+		})
+	return server
+}
+
+// connect returns a client session connected to server in memory.
+func connect(t *testing.T, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = serverSession.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+// spansNamed returns the recorded spans with the given name.
+func spansNamed(exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStubs {
+	var spans tracetest.SpanStubs
+	for _, s := range exporter.GetSpans() {
+		if s.Name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	exporter := install(t)
+	session := connect(t, newServer())
+
+	_, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{}})
+	require.NoError(t, err)
+
+	calls := spansNamed(exporter, "tools/call echo")
+	require.Len(t, calls, 1)
+	call := calls[0]
+	assert.Equal(t, trace.SpanKindServer, call.SpanKind)
+	assert.Contains(t, call.Attributes, attribute.String("gen_ai.tool.name", "echo"))
+	assert.Equal(t, codes.Unset, call.Status.Code)
+	assert.False(t, call.Parent.IsValid())
+
+	// The tool's own spans are children of the call
+	work := spansNamed(exporter, "work")
+	require.Len(t, work, 1)
+	assert.Equal(t, call.SpanContext.SpanID(), work[0].Parent.SpanID())
+
+	t.Run("tool errors set the span status", func(t *testing.T) {
+		exporter.Reset()
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{"fail": true}})
+		require.NoError(t, err)
+		assert.True(t, res.IsError)
+
+		calls := spansNamed(exporter, "tools/call echo")
+		require.Len(t, calls, 1)
+		assert.Equal(t, codes.Error, calls[0].Status.Code)
+		assert.Contains(t, calls[0].Attributes, attribute.String("error.type", mcputil.ClassChartNotFound))
+	})
+
+	t.Run("protocol errors are recorded", func(t *testing.T) {
+		exporter.Reset()
+		_, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "no-such-tool"})
+		require.Error(t, err)
+
+		calls := spansNamed(exporter, "tools/call no-such-tool")
+		require.Len(t, calls, 1)
+		assert.Equal(t, codes.Error, calls[0].Status.Code)
+	})
+
+	t.Run("other methods are not traced", func(t *testing.T) {
+		exporter.Reset()
+		_, err := session.ListTools(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, exporter.GetSpans())
+	})
+}
+
+// traceHeaderTransport injects the trace context of its requests' contexts
+// into their headers.
+type traceHeaderTransport struct {
+	ctx context.Context
+}
+
+func (t traceHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	otel.GetTextMapPropagator().Inject(t.ctx, propagation.HeaderCarrier(req.Header))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestMiddleware_PropagatesTraceContext(t *testing.T) {
+	exporter := install(t)
+	server := newServer()
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(httpServer.Close)
+
+	// The caller's trace is carried in a traceparent header
+	ctx, parent := otel.Tracer("caller").Start(context.Background(), "caller")
+	defer parent.End()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil)
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+		Endpoint:   httpServer.URL,
+		HTTPClient: &http.Client{Transport: traceHeaderTransport{ctx: ctx}},
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = session.Close() })
+
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{}})
+	require.NoError(t, err)
+
+	calls := spansNamed(exporter, "tools/call echo")
+	require.Len(t, calls, 1)
+	assert.Equal(t, parent.SpanContext().TraceID(), calls[0].SpanContext.TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), calls[0].Parent.SpanID())
+	assert.True(t, calls[0].Parent.IsRemote())
+}
+
+func TestInstall_SampleRatio(t *testing.T) {
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	exporter := tracetest.NewInMemoryExporter()
+	Install(sdktrace.WithSyncer(exporter), Options{SampleRatio: 0})
+
+	_, span := otel.Tracer("test").Start(context.Background(), "dropped")
+	span.End()
+	assert.Empty(t, exporter.GetSpans())
+}